@@ -220,6 +220,10 @@ func initSystemConfig(tx *db.Session) (err error) {
 			Name:        models.SysCfgNamePeriodOfLogSave,
 			Value:       "Permanent",
 			Description: "日志保存周期",
+		}, {
+			Name:        models.SysCfgNamePeriodOfAuditLogSave,
+			Value:       models.SysCfgValuePermanent,
+			Description: "审计日志保存天数",
 		},
 	}
 
@@ -171,7 +171,7 @@ func (p *InitDemo) Execute(args []string) error {
 			Sensitive:   v.Sensitive,
 		})
 	}
-	if err := services.OperationVariables(tx, org.Id, "", "", "", variables, nil); err != nil {
+	if err := services.OperationVariables(tx, consts.SysUserId, org.Id, "", "", "", variables, nil); err != nil {
 		panic(fmt.Errorf("create variable failed, err %s", err))
 	}
 
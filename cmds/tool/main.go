@@ -16,6 +16,7 @@ type Option struct {
 	ChangePassword ChangePassword        `command:"password" description:"update user password"`
 	Version        common.VersionCommand `command:"version" description:"show version"`
 	InitDemo       InitDemo              `command:"init-demo" description:"init demo data with config file"`
+	ScrubResources ScrubResources        `command:"scrub-resources" description:"mask sensitive attributes of saved resources"`
 }
 
 var (
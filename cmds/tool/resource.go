@@ -0,0 +1,108 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package main
+
+import (
+	"cloudiac/configs"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"cloudiac/portal/services"
+	"cloudiac/portal/services/logstorage"
+	"fmt"
+)
+
+// ./iac-tool scrub-resources [--dry-run]
+
+// ScrubResources 隐藏已保存的资源属性中的敏感值，敏感属性优先使用任务 state 中的 sensitive_values 判断，
+// 同时加密任务结果中以明文保存的敏感输出
+type ScrubResources struct {
+	DryRun bool `long:"dry-run" description:"only print the number of resources and outputs to be scrubbed"`
+}
+
+func (p *ScrubResources) Execute(args []string) error {
+	configs.Init(opt.Config)
+	db.Init(configs.Get().Mysql)
+	models.Init(false)
+
+	taskIds := make([]models.Id, 0)
+	if err := db.Get().Model(models.Resource{}).Group("task_id").Pluck("task_id", &taskIds); err != nil {
+		return err
+	}
+	logger.Infof("%d tasks have resources", len(taskIds))
+
+	total := 0
+	for _, taskId := range taskIds {
+		task, err := services.GetTaskById(db.Get(), taskId)
+		if err != nil {
+			logger.Warnf("get task %s: %v", taskId, err)
+			continue
+		}
+
+		var state *services.TfState
+		if bs, err := logstorage.Get().Read(task.StateJsonPath()); err != nil {
+			logger.Warnf("read state json of task %s: %v, scrub by attribute names", taskId, err)
+		} else if state, err = services.UnmarshalStateJson(bs); err != nil {
+			logger.Warnf("unmarshal state json of task %s: %v, scrub by attribute names", taskId, err)
+			state = nil
+		}
+
+		// 每个任务的资源在单独的事务中更新，dry-run 时回滚
+		tx := db.Get().Begin()
+		count, er := services.ScrubTaskResources(tx, taskId, state)
+		if er != nil || p.DryRun {
+			_ = tx.Rollback()
+		} else if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if er != nil {
+			return fmt.Errorf("scrub resources of task %s: %v", taskId, er)
+		}
+		if count > 0 {
+			logger.Infof("task %s: %d resources scrubbed", taskId, count)
+		}
+		total += count
+	}
+
+	if p.DryRun {
+		logger.Infof("dry run, %d resources need to be scrubbed", total)
+	} else {
+		logger.Infof("%d resources scrubbed", total)
+	}
+	return p.encryptOutputs()
+}
+
+// encryptOutputs 加密 SaveTaskOutputs 加密敏感输出之前保存的任务输出
+func (p *ScrubResources) encryptOutputs() error {
+	tasks := make([]models.Task, 0)
+	if err := db.Get().Model(models.Task{}).Where("result LIKE ?", `%"sensitive"%`).Find(&tasks); err != nil {
+		return err
+	}
+
+	total := 0
+	for i := range tasks {
+		task := &tasks[i]
+		tx := db.Get().Begin()
+		count, er := services.EncryptTaskOutputs(tx, task)
+		if er != nil || p.DryRun {
+			_ = tx.Rollback()
+		} else if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if er != nil {
+			return fmt.Errorf("encrypt outputs of task %s: %v", task.Id, er)
+		}
+		if count > 0 {
+			logger.Infof("task %s: %d outputs encrypted", task.Id, count)
+		}
+		total += count
+	}
+
+	if p.DryRun {
+		logger.Infof("dry run, %d outputs need to be encrypted", total)
+	} else {
+		logger.Infof("%d outputs encrypted", total)
+	}
+	return nil
+}
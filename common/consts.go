@@ -14,6 +14,10 @@ const (
 	TaskTypePlan    = "plan"    // 计划执行，不会修改资源或做服务配置
 	TaskTypeApply   = "apply"   // 执行 terraform apply 和 playbook
 	TaskTypeDestroy = "destroy" // 销毁，删除所有资源
+	TaskTypeImport  = "import"  // 导入已有资源到环境的 state
+	TaskTypeState   = "state"   // 资源状态操作，如 replace、taint、state rm/mv
+	TaskTypeRefresh = "refresh" // 同步 state 与实际资源(terraform apply -refresh-only)
+	TaskTypeOutput  = "output"  // 读取 outputs，不会修改资源
 
 	TaskPending   = "pending"
 	TaskRunning   = "running"
@@ -25,6 +29,9 @@ const (
 	TaskStepPlan    = "plan"
 	TaskStepApply   = "apply"
 	TaskStepDestroy = "destroy"
+	TaskStepImport  = "import"  // terraform import
+	TaskStepState   = "state"   // terraform taint、state rm/mv
+	TaskStepOutput  = "output"  // terraform output
 	TaskStepPlay    = "play"    // play playbook
 	TaskStepCommand = "command" // run command
 	TaskStepCollect = "collect" // 任务结束后的信息采集
@@ -42,6 +49,10 @@ const (
 	TaskTypePlanName    = "plan"
 	TaskTypeApplyName   = "apply"
 	TaskTypeDestroyName = "destroy"
+	TaskTypeImportName  = "import"
+	TaskTypeStateName   = "state"
+	TaskTypeRefreshName = "refresh"
+	TaskTypeOutputName  = "output"
 
 	TaskStepTimeoutDuration = 600
 )
@@ -50,6 +50,14 @@ type PortalConfig struct {
 	Address       string `yaml:"address"` // portal 对外提供服务的 url
 	SSHPrivateKey string `yaml:"ssh_private_key"`
 	SSHPublicKey  string `yaml:"ssh_public_key"`
+
+	StepRetry map[string]StepRetryConfig `yaml:"step_retry"` // 按步骤类型配置的自动重试策略
+}
+
+type StepRetryConfig struct {
+	MaxRetries int      `yaml:"max_retries"` // 最大重试次数，0 表示不重试
+	Backoff    int      `yaml:"backoff"`     // 首次重试的等待时间(秒)，之后每次翻倍
+	Patterns   []string `yaml:"patterns"`    // 临时性错误的日志匹配规则(正则)，追加到默认规则中
 }
 
 func (c *RunnerConfig) mustAbs(path string) string {
@@ -96,15 +96,33 @@ var polices = []Policy{
 	{"admin", "orgs", "listuser/adduser/removeuser/updaterole"},
 	{"member", "orgs", "read"},
 
+	// 审计日志
+	{"admin", "audit-logs", "read"},
+
 	{"admin", "projects", "*"},
 	{"member", "projects", "read"},
 
+	// 团队
+	{"admin", "teams", "*"},
+	{"member", "teams", "read"},
+
+	// 冻结窗口
+	{"admin", "freeze-windows", "*"},
+	{"member", "freeze-windows", "read"},
+	{"manager", "freeze-windows", "*"},
+	{"approver", "freeze-windows", "read"},
+	{"operator", "freeze-windows", "read"},
+	{"guest", "freeze-windows", "read"},
+
 	{"admin", "templates", "*"},
 	{"member", "templates", "read"},
 
 	{"admin", "variables", "*"},
 	{"member", "variables", "read"},
 
+	{"admin", "variable-sets", "*"},
+	{"member", "variable-sets", "read"},
+
 	// 项目
 	{"manager", "projects", "*"},
 	{"approver", "projects", "read"},
@@ -120,6 +138,35 @@ var polices = []Policy{
 	{"guest", "envs", "read"},
 	{"guest", "tasks", "read"},
 
+	{"manager", "approval-policies", "*"},
+	{"approver", "approval-policies", "read"},
+	{"operator", "approval-policies", "read"},
+	{"guest", "approval-policies", "read"},
+
+	{"manager", "env-schedules", "*"},
+	{"approver", "env-schedules", "*"},
+	{"operator", "env-schedules", "read/update"},
+	{"guest", "env-schedules", "read"},
+
+	{"manager", "bulk-operations", "*"},
+	{"approver", "bulk-operations", "*"},
+	{"operator", "bulk-operations", "read/create"},
+	{"guest", "bulk-operations", "read"},
+
+	// 资源账号是组织级的凭证，只允许组织管理员绑定到项目
+	{"admin", "resource-account-bindings", "*"},
+	{"manager", "resource-account-bindings", "read"},
+	{"approver", "resource-account-bindings", "read"},
+	{"operator", "resource-account-bindings", "read"},
+	{"guest", "resource-account-bindings", "read"},
+
+	// 变量集可能包含组织级的敏感变量，只允许组织管理员关联到项目
+	{"admin", "variable-set-attachments", "*"},
+	{"manager", "variable-set-attachments", "read"},
+	{"approver", "variable-set-attachments", "read"},
+	{"operator", "variable-set-attachments", "read"},
+	{"guest", "variable-set-attachments", "read"},
+
 	{"manager", "templates", "*"},
 	{"approver", "templates", "*"},
 	{"operator", "templates", "read"},
@@ -171,6 +218,14 @@ var polices = []Policy{
 	{"demo", "users", "read"},
 	{"demo", "self", "read"},
 	{"demo", "projects", "read"},
+	{"demo", "teams", "read"},
+	{"demo", "freeze-windows", "read"},
+	{"demo", "approval-policies", "read"},
+	{"demo", "env-schedules", "read"},
+	{"demo", "bulk-operations", "read"},
+	{"demo", "resource-account-bindings", "read"},
+	{"demo", "variable-sets", "read"},
+	{"demo", "variable-set-attachments", "read"},
 	{"demo", "tokens", "read"},
 	{"demo", "notifications", "read"},
 	{"demo", "vcs", "read"},
@@ -7,7 +7,7 @@ require (
 	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751
 	github.com/casbin/casbin/v2 v2.31.9
 	github.com/casbin/gorm-adapter/v2 v2.1.0
-	github.com/casbin/gorm-adapter/v3 v3.3.2 // indirect
+	github.com/casbin/gorm-adapter/v3 v3.3.2
 	github.com/containerd/containerd v1.4.4 // indirect
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/docker/distribution v2.7.1+incompatible // indirect
@@ -46,13 +46,14 @@ require (
 	github.com/swaggo/gin-swagger v1.3.0
 	github.com/swaggo/swag v1.7.0
 	github.com/xanzy/go-gitlab v0.47.0
+	github.com/zclconf/go-cty v1.8.0
 	golang.org/x/crypto v0.0.0-20210421170649-83a5a9bb288b
 	google.golang.org/grpc v1.36.0 // indirect
 	gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
 	gopkg.in/yaml.v2 v2.4.0
-	gorm.io/driver/mysql v1.1.1 // indirect
-	gorm.io/gorm v1.21.12 // indirect
-	gorm.io/plugin/soft_delete v1.0.2 // indirect
+	gorm.io/driver/mysql v1.1.1
+	gorm.io/gorm v1.21.12
+	gorm.io/plugin/soft_delete v1.0.2
 	gotest.tools/v3 v3.0.3 // indirect
 )
@@ -0,0 +1,168 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/page"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"fmt"
+	"net/http"
+)
+
+// checkApprovalPolicyParams 检查审批策略参数
+func checkApprovalPolicyParams(c *ctx.ServiceContext, minApprovers int, teamId models.Id, expireMinutes int) e.Error {
+	if minApprovers < 1 {
+		return e.New(e.BadParam, fmt.Errorf("'minApprovers' must be greater than 0"), http.StatusBadRequest)
+	}
+	if expireMinutes < 0 {
+		return e.New(e.BadParam, fmt.Errorf("'expireMinutes' can not be negative"), http.StatusBadRequest)
+	}
+	if teamId != "" {
+		if _, err := services.GetTeamById(services.QueryWithOrgId(c.DB(), c.OrgId), teamId); err != nil {
+			if err.Code() == e.TeamNotExists {
+				return e.New(err.Code(), err, http.StatusBadRequest)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func getApprovalPolicy(c *ctx.ServiceContext, id models.Id) (*models.ApprovalPolicy, e.Error) {
+	query := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	policy, err := services.GetApprovalPolicyById(query, id)
+	if err != nil {
+		if err.Code() == e.ApprovalPolicyNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		c.Logger().Errorf("error get approval policy, err %s", err)
+		return nil, err
+	}
+	if err := checkTokenEnvScope(c, policy.EnvId); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// CreateApprovalPolicy 创建项目或环境的审批策略
+func CreateApprovalPolicy(c *ctx.ServiceContext, form *forms.CreateApprovalPolicyForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("create approval policy for project %s env '%s'", c.ProjectId, form.EnvId))
+
+	minApprovers := form.MinApprovers
+	if !form.HasKey("minApprovers") {
+		minApprovers = 1
+	}
+	if err := checkApprovalPolicyParams(c, minApprovers, form.RequiredTeamId, form.ExpireMinutes); err != nil {
+		return nil, err
+	}
+	if form.EnvId != "" {
+		if err := checkTokenEnvScope(c, form.EnvId); err != nil {
+			return nil, err
+		}
+		envQuery := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+		if _, err := services.GetEnvById(envQuery, form.EnvId); err != nil {
+			if err.Code() == e.EnvNotExists {
+				return nil, e.New(err.Code(), err, http.StatusBadRequest)
+			}
+			return nil, err
+		}
+	}
+
+	policy, err := services.CreateApprovalPolicy(c.DB(), models.ApprovalPolicy{
+		OrgId:            c.OrgId,
+		ProjectId:        c.ProjectId,
+		EnvId:            form.EnvId,
+		MinApprovers:     minApprovers,
+		RequiredTeamId:   form.RequiredTeamId,
+		DenySelfApproval: form.DenySelfApproval,
+		ExpireMinutes:    form.ExpireMinutes,
+		CreatorId:        c.UserId,
+	})
+	if err != nil && err.Code() == e.ApprovalPolicyAlreadyExists {
+		return nil, e.New(err.Code(), err, http.StatusBadRequest)
+	} else if err != nil {
+		c.Logger().Errorf("error create approval policy, err %s", err)
+		return nil, err
+	}
+	return policy, nil
+}
+
+// SearchApprovalPolicy 查询项目下的审批策略
+func SearchApprovalPolicy(c *ctx.ServiceContext, form *forms.SearchApprovalPolicyForm) (interface{}, e.Error) {
+	if err := checkTokenEnvScope(c, form.EnvId); err != nil {
+		return nil, err
+	}
+	query := services.QueryApprovalPolicy(services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId))
+	if form.HasKey("envId") {
+		query = query.Where("env_id = ?", form.EnvId)
+	}
+	if form.SortField() == "" {
+		query = query.Order("env_id, created_at DESC")
+	}
+
+	p := page.New(form.CurrentPage(), form.PageSize(), form.Order(query))
+	policies := make([]*models.ApprovalPolicy, 0)
+	if err := p.Scan(&policies); err != nil {
+		c.Logger().Errorf("error search approval policy, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     policies,
+	}, nil
+}
+
+// ApprovalPolicyDetail 审批策略详情
+func ApprovalPolicyDetail(c *ctx.ServiceContext, form *forms.DetailApprovalPolicyForm) (interface{}, e.Error) {
+	return getApprovalPolicy(c, form.Id)
+}
+
+// UpdateApprovalPolicy 修改审批策略
+func UpdateApprovalPolicy(c *ctx.ServiceContext, form *forms.UpdateApprovalPolicyForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("update approval policy %s", form.Id))
+	policy, err := getApprovalPolicy(c, form.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := models.Attrs{}
+	if form.HasKey("minApprovers") {
+		policy.MinApprovers = form.MinApprovers
+		attrs["min_approvers"] = form.MinApprovers
+	}
+	if form.HasKey("requiredTeamId") {
+		policy.RequiredTeamId = form.RequiredTeamId
+		attrs["required_team_id"] = form.RequiredTeamId
+	}
+	if form.HasKey("denySelfApproval") {
+		attrs["deny_self_approval"] = form.DenySelfApproval
+	}
+	if form.HasKey("expireMinutes") {
+		policy.ExpireMinutes = form.ExpireMinutes
+		attrs["expire_minutes"] = form.ExpireMinutes
+	}
+	if err := checkApprovalPolicyParams(c, policy.MinApprovers, policy.RequiredTeamId, policy.ExpireMinutes); err != nil {
+		return nil, err
+	}
+
+	policy, err = services.UpdateApprovalPolicy(c.DB(), form.Id, attrs)
+	if err != nil {
+		c.Logger().Errorf("error update approval policy, err %s", err)
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeleteApprovalPolicy 删除审批策略
+func DeleteApprovalPolicy(c *ctx.ServiceContext, form *forms.DeleteApprovalPolicyForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("delete approval policy %s", form.Id))
+	if _, err := getApprovalPolicy(c, form.Id); err != nil {
+		return nil, err
+	}
+	return nil, services.DeleteApprovalPolicy(c.DB(), form.Id)
+}
@@ -0,0 +1,206 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/libs/page"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// recordAuditLog 记录业务操作的审计日志，before/after 为变更前后的数据
+func recordAuditLog(c *ctx.ServiceContext, resourceType string, resourceId models.Id,
+	operationType string, info string, before, after interface{}) {
+	err := services.CreateOperationLog(c.DB(), models.OperationLog{
+		OrgId:         c.OrgId,
+		ProjectId:     c.ProjectId,
+		UserID:        c.UserId,
+		Username:      c.Username,
+		UserAddr:      c.UserIpAddr,
+		OperationAt:   models.Time(time.Now()),
+		OperationType: operationType,
+		ResourceType:  resourceType,
+		ResourceId:    resourceId,
+		OperationInfo: info,
+		Desc:          models.NewOperationDesc(before, after),
+	})
+	if err != nil {
+		c.Logger().Errorf("error create operation log, err %s", err)
+		return
+	}
+	c.AuditLogged = true
+}
+
+// taskOperationType 任务类型对应的审计操作类型
+func taskOperationType(taskType string) string {
+	switch taskType {
+	case models.TaskTypeApply:
+		return models.OperationDeploy
+	case models.TaskTypeDestroy:
+		return models.OperationDestroy
+	default:
+		return models.OperationCreate
+	}
+}
+
+func taskAuditInfo(task *models.Task) map[string]interface{} {
+	return map[string]interface{}{
+		"taskId":   task.Id,
+		"taskType": task.Type,
+		"revision": task.Revision,
+		"commitId": task.CommitId,
+	}
+}
+
+func queryAuditLog(c *ctx.ServiceContext, form *forms.SearchAuditLogForm) (*db.Session, e.Error) {
+	query := services.QueryOperationLog(c.DB())
+	if c.IsSuperAdmin && form.OrgId != "" {
+		query = query.Where("org_id = ?", form.OrgId)
+	} else if !c.IsSuperAdmin {
+		if c.OrgId == "" {
+			return nil, e.New(e.InvalidOrganizationId, http.StatusForbidden)
+		}
+		query = query.Where("org_id = ?", c.OrgId)
+	} else if c.OrgId != "" {
+		query = query.Where("org_id = ?", c.OrgId)
+	}
+
+	if form.ProjectId != "" {
+		query = query.Where("project_id = ?", form.ProjectId)
+	}
+	if form.UserId != "" {
+		query = query.Where("user_id = ?", form.UserId)
+	}
+	if form.ResourceType != "" {
+		query = query.Where("resource_type = ?", form.ResourceType)
+	}
+	if form.ResourceId != "" {
+		query = query.Where("resource_id = ?", form.ResourceId)
+	}
+	if form.OperationType != "" {
+		query = query.Where("operation_type = ?", form.OperationType)
+	}
+	if form.StartAt != "" {
+		startAt, err := time.Parse(time.RFC3339, form.StartAt)
+		if err != nil {
+			return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+		}
+		query = query.Where("operation_at >= ?", startAt)
+	}
+	if form.EndAt != "" {
+		endAt, err := time.Parse(time.RFC3339, form.EndAt)
+		if err != nil {
+			return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+		}
+		query = query.Where("operation_at <= ?", endAt)
+	}
+
+	if form.SortField() == "" {
+		query = query.Order("operation_at DESC")
+	}
+	return form.Order(query), nil
+}
+
+// SearchAuditLog 查询审计日志
+func SearchAuditLog(c *ctx.ServiceContext, form *forms.SearchAuditLogForm) (interface{}, e.Error) {
+	query, err := queryAuditLog(c, form)
+	if err != nil {
+		return nil, err
+	}
+
+	p := page.New(form.CurrentPage(), form.PageSize(), query)
+	logs := make([]*models.OperationLog, 0)
+	if err := p.Scan(&logs); err != nil {
+		c.Logger().Errorf("error search audit log, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     logs,
+	}, nil
+}
+
+// ExportAuditLog 导出符合条件的全部审计日志，支持 csv 和 jsonl 格式，分批查询后流式输出
+func ExportAuditLog(c *ctx.GinRequest, form *forms.SearchAuditLogForm) e.Error {
+	query, err := queryAuditLog(c.Service(), form)
+	if err != nil {
+		return err
+	}
+	// 分批查询需要稳定的排序
+	query = query.Order("id")
+
+	p := page.New(1, consts.MaxPageSize, query)
+	total, er := p.Total()
+	if er != nil {
+		c.Logger().Errorf("error count audit log, err %s", er)
+		return e.New(e.DBError, er)
+	}
+	if total > consts.MaxAuditLogExportSize {
+		return e.New(e.BadParam, fmt.Errorf("too many audit logs to export (%d), the maximum is %d, please narrow the filters",
+			total, consts.MaxAuditLogExportSize), http.StatusBadRequest)
+	}
+
+	var (
+		writeRow func(l *models.OperationLog) error
+		flush    = func() error { return nil }
+	)
+	filename := fmt.Sprintf("audit-logs-%s", time.Now().Format("20060102150405"))
+	switch form.Format {
+	case "jsonl":
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.jsonl", filename))
+		encoder := json.NewEncoder(c.Writer)
+		writeRow = func(l *models.OperationLog) error {
+			return encoder.Encode(l)
+		}
+	case "", "csv":
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write([]string{"id", "operationAt", "orgId", "projectId", "userId", "username", "userAddr",
+			"operationType", "resourceType", "resourceId", "operationInfo", "desc"})
+		writeRow = func(l *models.OperationLog) error {
+			return w.Write([]string{string(l.Id), time.Time(l.OperationAt).Format(time.RFC3339),
+				string(l.OrgId), string(l.ProjectId), string(l.UserID), l.Username, l.UserAddr,
+				l.OperationType, l.ResourceType, string(l.ResourceId), l.OperationInfo, string(l.Desc)})
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	default:
+		return e.New(e.BadParam, fmt.Errorf("unsupported format '%s'", form.Format), http.StatusBadRequest)
+	}
+
+	for exported := int64(0); exported < total; p = p.Next() {
+		logs := make([]*models.OperationLog, 0)
+		if err := p.Scan(&logs); err != nil {
+			c.Logger().Errorf("error export audit log, err %s", err)
+			return e.New(e.DBError, err)
+		}
+		for _, l := range logs {
+			if err := writeRow(l); err != nil {
+				return e.New(e.IOError, err)
+			}
+		}
+		if err := flush(); err != nil {
+			return e.New(e.IOError, err)
+		}
+		exported += int64(len(logs))
+		if len(logs) < p.Size {
+			break
+		}
+	}
+	return nil
+}
@@ -5,12 +5,16 @@ package apps
 import (
 	"cloudiac/portal/libs/ctx"
 	"fmt"
+	"net/http"
 	"reflect"
 
+	"cloudiac/portal/consts"
 	"cloudiac/portal/consts/e"
 	"cloudiac/portal/libs/db"
 	"cloudiac/portal/libs/page"
+	"cloudiac/portal/models"
 	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
 )
 
 type TableIface interface {
@@ -51,7 +55,35 @@ func getEmptyListResult(form forms.PageFormer) (interface{}, e.Error) {
 	}, nil
 }
 
+// checkTokenEnvScope 检查环境是否在 token 授权范围内。
+// 路由中的环境(:id)由 Auth 中间件检查，从请求参数中获取环境 id 的接口需要调用该方法
+func checkTokenEnvScope(c *ctx.ServiceContext, envIds ...models.Id) e.Error {
+	if c.ApiToken == nil {
+		return nil
+	}
+	for _, id := range envIds {
+		if id != "" && !c.ApiToken.Scopes.AllowEnv(id) {
+			return e.New(e.InvalidTokenScope, fmt.Errorf("env %s not in token scopes", id), http.StatusForbidden)
+		}
+	}
+	return nil
+}
+
+// queryWithTokenEnvScope token 限制了环境范围时只查询授权环境的数据，column 为环境 id 字段名
+func queryWithTokenEnvScope(c *ctx.ServiceContext, query *db.Session, column string) *db.Session {
+	if c.ApiToken == nil || len(c.ApiToken.Scopes.Envs) == 0 {
+		return query
+	}
+	return query.Where(fmt.Sprintf("%s IN (?)", column), c.ApiToken.Scopes.Envs)
+}
+
 func BaseHandler(c *ctx.ServiceContext, form *forms.BaseForm) (interface{}, e.Error) {
 	c.AddLogField("action", fmt.Sprintf("base"))
 	return nil, nil
 }
+
+// canRevealSensitive 判断用户是否可以查看敏感信息的明文，需要是平台管理员、组织管理员或项目管理者
+func canRevealSensitive(c *ctx.ServiceContext, projectId models.Id) bool {
+	return c.IsSuperAdmin || services.UserHasOrgRole(c.UserId, c.OrgId, consts.OrgRoleAdmin) ||
+		(projectId != "" && services.UserHasProjectRole(c.UserId, c.OrgId, projectId, consts.ProjectRoleManager))
+}
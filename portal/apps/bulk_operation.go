@@ -0,0 +1,276 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/page"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"cloudiac/utils"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultBulkParallelism = 5
+
+type bulkOperationDetail struct {
+	models.BulkOperation
+	Items []*models.BulkOperationItemResp `json:"items"` // 每个环境的执行记录及任务
+}
+
+// selectBulkEnvs 按环境ID或筛选条件选择当前项目下未归档的环境
+func selectBulkEnvs(c *ctx.ServiceContext, form *forms.CreateBulkOperationForm) ([]models.Id, e.Error) {
+	if err := checkTokenEnvScope(c, form.EnvIds...); err != nil {
+		return nil, err
+	}
+	// 按筛选条件选择时只选择 token 授权范围内的环境
+	query := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId).
+		Model(&models.Env{}).Where("archived = ?", false)
+	query = queryWithTokenEnvScope(c, query, "id")
+
+	if len(form.EnvIds) > 0 {
+		query = query.Where("id IN (?)", form.EnvIds)
+	} else {
+		if form.TplId == "" && form.Q == "" && form.Status == "" {
+			return nil, e.New(e.BadParam, fmt.Errorf("'envIds' or filter is required"), http.StatusBadRequest)
+		}
+		if form.TplId != "" {
+			query = query.Where("tpl_id = ?", form.TplId)
+		}
+		if form.Q != "" {
+			query = query.WhereLike("name", form.Q)
+		}
+		if form.Status != "" {
+			if !utils.InArrayStr(models.EnvStatus, form.Status) {
+				return nil, e.New(e.BadParam, fmt.Errorf("invalid status '%s'", form.Status), http.StatusBadRequest)
+			}
+			query = query.Where("status = ?", form.Status)
+		}
+	}
+
+	envIds := make([]models.Id, 0)
+	if err := query.Order("created_at").Pluck("id", &envIds); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	for _, id := range form.EnvIds {
+		if !id.InArray(envIds...) {
+			return nil, e.New(e.EnvNotExists, fmt.Errorf("env '%s' not found", id), http.StatusBadRequest)
+		}
+	}
+	if len(envIds) == 0 {
+		return nil, e.New(e.BulkOperationNoEnv, http.StatusBadRequest)
+	}
+	return envIds, nil
+}
+
+// CreateBulkOperation 创建批量操作。plan、部署、销毁由任务管理器按并发数限制逐个创建任务，
+// 更新变量直接在请求中依次执行
+func CreateBulkOperation(c *ctx.ServiceContext, form *forms.CreateBulkOperationForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("create bulk %s operation", form.Action))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	switch form.Action {
+	case models.BulkActionPlan, models.BulkActionApply, models.BulkActionDestroy:
+	case models.BulkActionVariables:
+		if len(form.Variables) == 0 {
+			return nil, e.New(e.BadParam, fmt.Errorf("'variables' is required"), http.StatusBadRequest)
+		}
+		for _, v := range form.Variables {
+			if v.Name == "" || !utils.StrInArray(v.Type, consts.VarTypeEnv, consts.VarTypeTerraform, consts.VarTypeAnsible, consts.VarTypeFile) {
+				return nil, e.New(e.BadParam, fmt.Errorf("invalid variable '%s'", v.Name), http.StatusBadRequest)
+			}
+		}
+	default:
+		return nil, e.New(e.BadParam, fmt.Errorf("invalid action '%s'", form.Action), http.StatusBadRequest)
+	}
+
+	envIds, err := selectBulkEnvs(c, form)
+	if err != nil {
+		return nil, err
+	}
+
+	parallelism := form.Parallelism
+	if parallelism == 0 {
+		parallelism = defaultBulkParallelism
+	}
+	op, err := services.CreateBulkOperation(c.DB(), models.BulkOperation{
+		OrgId:       c.OrgId,
+		ProjectId:   c.ProjectId,
+		CreatorId:   c.UserId,
+		Action:      form.Action,
+		Parallelism: parallelism,
+		MaxFailures: form.MaxFailures,
+		Status:      models.BulkOperationRunning,
+	}, envIds)
+	if err != nil {
+		c.Logger().Errorf("error create bulk operation, err %s", err)
+		return nil, err
+	}
+
+	info := map[string]interface{}{"action": op.Action, "envIds": envIds}
+	if form.Action == models.BulkActionVariables {
+		if err := runBulkUpdateVariables(c, op, form.Variables); err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(form.Variables))
+		for _, v := range form.Variables {
+			names = append(names, v.Name)
+		}
+		info["variables"] = names
+	}
+	recordAuditLog(c, "bulk-operations", op.Id, models.OperationCreate,
+		fmt.Sprintf("bulk %s %d envs", op.Action, op.Total), nil, info)
+
+	return getBulkOperationDetail(c, op.Id)
+}
+
+// runBulkUpdateVariables 依次更新每个环境的变量，每个环境单独提交，失败数量达到上限后跳过剩余环境
+func runBulkUpdateVariables(c *ctx.ServiceContext, op *models.BulkOperation, variables []forms.Variables) e.Error {
+	items, err := services.GetBulkOperationItems(c.DB(), op.Id)
+	if err != nil {
+		return err
+	}
+
+	succeeded, failed, skipped := 0, 0, 0
+	for _, item := range items {
+		status, message := models.BulkItemComplete, ""
+		if op.MaxFailures > 0 && failed >= op.MaxFailures {
+			status, message = models.BulkItemSkipped, fmt.Sprintf("stopped after %d failures", failed)
+		} else if err := updateBulkEnvVariables(c, item.EnvId, variables); err != nil {
+			c.Logger().Errorf("error update env %s variables, err %s", item.EnvId, err)
+			status, message = models.BulkItemFailed, err.Error()
+		}
+
+		switch status {
+		case models.BulkItemComplete:
+			succeeded += 1
+		case models.BulkItemFailed:
+			failed += 1
+		default:
+			skipped += 1
+		}
+		if err := services.UpdateBulkOperationItem(c.DB(), item, models.Attrs{"status": status, "message": message}); err != nil {
+			return err
+		}
+	}
+
+	status := models.BulkOperationComplete
+	if skipped > 0 {
+		status = models.BulkOperationAborted
+	} else if failed > 0 {
+		status = models.BulkOperationFailed
+	}
+	return services.UpdateBulkOperation(c.DB(), op.Id, models.Attrs{
+		"status":    status,
+		"succeeded": succeeded,
+		"failed":    failed,
+		"skipped":   skipped,
+		"end_at":    models.Time(time.Now()),
+	})
+}
+
+// updateBulkEnvVariables 按名称和类型新增或覆盖环境变量，并更新环境的固化变量
+func updateBulkEnvVariables(c *ctx.ServiceContext, envId models.Id, variables []forms.Variables) e.Error {
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	env, err := services.GetEnvById(tx, envId)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	vars := make([]forms.Variables, 0, len(variables))
+	for _, v := range variables {
+		ids := make([]models.Id, 0)
+		if err := tx.Model(&models.Variable{}).Where("env_id = ? AND name = ? AND type = ?", env.Id, v.Name, v.Type).
+			Pluck("id", &ids); err != nil {
+			_ = tx.Rollback()
+			return e.New(e.DBError, err)
+		}
+		v.Id = ""
+		if len(ids) > 0 {
+			v.Id = ids[0]
+		}
+		v.Scope = consts.ScopeEnv
+		vars = append(vars, v)
+	}
+	if err := services.OperationVariables(tx, c.UserId, env.OrgId, env.ProjectId, env.TplId, env.Id, vars, nil); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	validVars, err, _ := services.GetValidVariables(tx, consts.ScopeEnv, env.OrgId, env.ProjectId, env.TplId, env.Id, true)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.Model(&models.Env{}).Where("id = ?", env.Id).
+		UpdateColumn("variables", getVariables(validVars)); err != nil {
+		_ = tx.Rollback()
+		return e.New(e.DBError, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return e.New(e.DBError, err)
+	}
+	return nil
+}
+
+func getBulkOperationDetail(c *ctx.ServiceContext, id models.Id) (*bulkOperationDetail, e.Error) {
+	query := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	op, err := services.GetBulkOperationById(query, id)
+	if err != nil {
+		if err.Code() == e.BulkOperationNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+
+	detail := &bulkOperationDetail{BulkOperation: *op, Items: make([]*models.BulkOperationItemResp, 0)}
+	if err := services.QueryBulkOperationItems(c.DB(), op.Id).Scan(&detail.Items); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return detail, nil
+}
+
+// SearchBulkOperation 查询批量操作
+func SearchBulkOperation(c *ctx.ServiceContext, form *forms.SearchBulkOperationForm) (interface{}, e.Error) {
+	query := services.QueryWithProjectId(services.QueryWithOrgId(services.QueryBulkOperation(c.DB()), c.OrgId), c.ProjectId)
+	if form.Action != "" {
+		query = query.Where("action = ?", form.Action)
+	}
+	if form.Status != "" {
+		query = query.Where("status = ?", form.Status)
+	}
+	query = query.Order("created_at DESC")
+
+	p := page.New(form.CurrentPage(), form.PageSize(), query)
+	ops := make([]*models.BulkOperation, 0)
+	if err := p.Scan(&ops); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     ops,
+	}, nil
+}
+
+// BulkOperationDetail 批量操作详情，包含每个环境的执行状态和任务
+func BulkOperationDetail(c *ctx.ServiceContext, form *forms.DetailBulkOperationForm) (interface{}, e.Error) {
+	return getBulkOperationDetail(c, form.Id)
+}
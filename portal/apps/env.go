@@ -51,6 +51,12 @@ func CreateEnv(c *ctx.ServiceContext, form *forms.CreateEnvForm) (*models.EnvDet
 	if form.Timeout == 0 {
 		form.Timeout = common.TaskStepTimeoutDuration
 	}
+	if err := checkDependencyTrigger(form.DependencyTrigger); err != nil {
+		return nil, err
+	}
+	if err := form.Labels.Validate(); err != nil {
+		return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+	}
 
 	var (
 		destroyAt models.Time
@@ -84,6 +90,7 @@ func CreateEnv(c *ctx.ServiceContext, form *forms.CreateEnvForm) (*models.EnvDet
 		TplId:     form.TplId,
 
 		Name:     form.Name,
+		Labels:   form.Labels,
 		RunnerId: form.RunnerId,
 		Status:   models.EnvStatusInactive,
 		OneTime:  form.OneTime,
@@ -100,7 +107,8 @@ func CreateEnv(c *ctx.ServiceContext, form *forms.CreateEnvForm) (*models.EnvDet
 		AutoDestroyAt: &destroyAt,
 		AutoApproval:  form.AutoApproval,
 
-		Triggers: form.Triggers,
+		Triggers:          form.Triggers,
+		DependencyTrigger: form.DependencyTrigger,
 	})
 	if err != nil && err.Code() == e.EnvAlreadyExists {
 		_ = tx.Rollback()
@@ -112,7 +120,7 @@ func CreateEnv(c *ctx.ServiceContext, form *forms.CreateEnvForm) (*models.EnvDet
 	}
 
 	// 创建新导入的变量
-	if err = services.OperationVariables(tx, c.OrgId, c.ProjectId, env.TplId, env.Id, form.Variables, nil); err != nil {
+	if err = services.OperationVariables(tx, c.UserId, c.OrgId, c.ProjectId, env.TplId, env.Id, form.Variables, nil); err != nil {
 		return nil, e.New(err.Code(), err, http.StatusInternalServerError)
 	}
 	// 获取计算后的变量列表
@@ -145,7 +153,13 @@ func CreateEnv(c *ctx.ServiceContext, form *forms.CreateEnvForm) (*models.EnvDet
 	if err != nil {
 		_ = tx.Rollback()
 		c.Logger().Errorf("error creating task, err %s", err)
-		return nil, e.New(err.Code(), err, http.StatusInternalServerError)
+		return nil, e.New(err.Code(), err, createTaskErrStatus(err))
+	}
+
+	varWarnings, err := checkEnvTfVariables(c, tx, tpl, task)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
 	}
 
 	// 首次部署，直接更新 last_task_id
@@ -166,15 +180,55 @@ func CreateEnv(c *ctx.ServiceContext, form *forms.CreateEnvForm) (*models.EnvDet
 	// 屏蔽敏感字段输出
 	env.HideSensitiveVariable()
 	envDetail := models.EnvDetail{
-		Env:        *env,
-		TaskId:     task.Id,
-		Operator:   c.Username,
-		OperatorId: c.UserId,
+		Env:              *env,
+		TaskId:           task.Id,
+		Operator:         c.Username,
+		OperatorId:       c.UserId,
+		VariableWarnings: varWarnings,
 	}
 
 	return &envDetail, nil
 }
 
+// createTaskErrStatus 创建任务失败时返回的 http 状态码
+func createTaskErrStatus(err e.Error) int {
+	switch err.Code() {
+	case e.EnvFrozen:
+		return http.StatusForbidden
+	case e.EnvHasDependents, e.EnvOutputRefInvalid, e.EnvDependencyCycle:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// checkEnvTfVariables 使用模板中声明的 terraform 变量检查任务变量，返回模板中未声明变量的警告信息。
+// 无法读取模板文件(如 vcs 访问失败)时跳过检查，由 terraform 执行时报错
+func checkEnvTfVariables(c *ctx.ServiceContext, tx *db.Session, tpl *models.Template, task *models.Task) ([]string, e.Error) {
+	decls, fileVars, err := services.GetTemplateTfVariableDecls(tx, tpl, task.CommitId, task.Workdir, task.TfVarsFile)
+	if err != nil {
+		c.Logger().Warnf("get template variable declarations error, skip check: %v", err)
+		return nil, nil
+	}
+	values, err := services.GetTfVariableValues(task.Variables)
+	if err != nil {
+		return nil, e.New(err.Code(), err, http.StatusInternalServerError)
+	}
+	errs, warnings := services.CheckTfVariables(decls, values, fileVars)
+	if len(errs) > 0 {
+		return nil, e.New(e.VariableInvalid, fmt.Errorf("%s", strings.Join(errs, "; ")), http.StatusBadRequest)
+	}
+	return warnings, nil
+}
+
+// checkDependencyTrigger 检查依赖触发的任务类型
+func checkDependencyTrigger(trigger string) e.Error {
+	if trigger != "" && trigger != models.TaskTypePlan && trigger != models.TaskTypeApply {
+		return e.New(e.BadParam, fmt.Errorf("invalid dependencyTrigger '%s'", trigger), http.StatusBadRequest)
+	}
+	return nil
+}
+
 func getVariables(vars map[string]models.Variable) models.EnvVariables {
 	var vb []models.Variable
 	for _, v := range vars {
@@ -190,6 +244,7 @@ func SearchEnv(c *ctx.ServiceContext, form *forms.SearchEnvForm) (interface{}, e
 	}
 	query := c.DB().Where("iac_env.org_id = ? AND iac_env.project_id = ?", c.OrgId, c.ProjectId)
 	query = services.QueryEnvDetail(query)
+	query = queryWithTokenEnvScope(c, query, "iac_env.id")
 
 	if form.Status != "" {
 		if utils.InArrayStr(models.EnvStatus, form.Status) {
@@ -222,6 +277,30 @@ func SearchEnv(c *ctx.ServiceContext, form *forms.SearchEnvForm) (interface{}, e
 		query = query.WhereLike("iac_env.name", form.Q)
 	}
 
+	if form.Labels != "" {
+		selector, err := models.ParseLabelSelector(form.Labels)
+		if err != nil {
+			return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+		}
+		query = services.WhereLabelSelector(query, "iac_env.labels", selector)
+	}
+	if form.TplId != "" {
+		query = query.Where("iac_env.tpl_id = ?", form.TplId)
+	}
+	if form.CreatorId != "" {
+		query = query.Where("iac_env.creator_id = ?", form.CreatorId)
+	}
+	if form.LastTaskStatus != "" {
+		query = query.Where("iac_env.last_task_id IN (SELECT id FROM iac_task WHERE status = ?)", form.LastTaskStatus)
+	}
+	if form.TtlBefore != "" {
+		ttlBefore, err := models.Time{}.Parse(form.TtlBefore)
+		if err != nil {
+			return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+		}
+		query = query.Where("iac_env.auto_destroy_at IS NOT NULL AND iac_env.auto_destroy_at <= ?", ttlBefore)
+	}
+
 	// 默认按创建时间逆序排序
 	if form.SortField() == "" {
 		query = query.Order("iac_env.created_at DESC")
@@ -317,6 +396,13 @@ func UpdateEnv(c *ctx.ServiceContext, form *forms.UpdateEnvForm) (*models.EnvDet
 		attrs["auto_approval"] = form.AutoApproval
 	}
 
+	if form.HasKey("dependencyTrigger") {
+		if err := checkDependencyTrigger(form.DependencyTrigger); err != nil {
+			return nil, err
+		}
+		attrs["dependency_trigger"] = form.DependencyTrigger
+	}
+
 	if form.HasKey("destroyAt") {
 		destroyAt, err := models.Time{}.Parse(form.DestroyAt)
 		if err != nil {
@@ -345,6 +431,13 @@ func UpdateEnv(c *ctx.ServiceContext, form *forms.UpdateEnvForm) (*models.EnvDet
 		attrs["triggers"] = form.Triggers
 	}
 
+	if form.HasKey("labels") {
+		if err := form.Labels.Validate(); err != nil {
+			return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+		}
+		attrs["labels"] = form.Labels
+	}
+
 	if form.HasKey("archived") {
 		if env.Status != models.EnvStatusInactive {
 			return nil, e.New(e.EnvCannotArchiveActive,
@@ -378,6 +471,9 @@ func EnvDetail(c *ctx.ServiceContext, form forms.DetailEnvForm) (*models.EnvDeta
 	}
 	query := c.DB().Where("iac_env.org_id = ? AND iac_env.project_id = ?", c.OrgId, c.ProjectId)
 	query = services.QueryEnvDetail(query)
+	if c.ApiToken != nil && len(c.ApiToken.Scopes.Envs) > 0 {
+		query = query.Where("iac_env.id IN (?)", c.ApiToken.Scopes.Envs)
+	}
 
 	envDetail, err := services.GetEnvDetailById(query, form.Id)
 	if err != nil && err.Code() == e.EnvNotExists {
@@ -491,9 +587,11 @@ func EnvDeploy(c *ctx.ServiceContext, form *forms.DeployEnvForm) (*models.EnvDet
 	if form.HasKey("timeout") {
 		env.Timeout = form.Timeout
 	}
+	var varsBefore, varsAfter []auditVariable
 	if form.HasKey("variables") || form.HasKey("deleteVariablesId") {
+		varsBefore, varsAfter = variableAuditChanges(tx, form.Variables, form.DeleteVariablesId)
 		// 变量列表增删
-		if err = services.OperationVariables(tx, c.OrgId, c.ProjectId, env.TplId, env.Id, form.Variables, form.DeleteVariablesId); err != nil {
+		if err = services.OperationVariables(tx, c.UserId, c.OrgId, c.ProjectId, env.TplId, env.Id, form.Variables, form.DeleteVariablesId); err != nil {
 			return nil, e.New(err.Code(), err, http.StatusInternalServerError)
 		}
 		// 计算变量列表
@@ -520,6 +618,23 @@ func EnvDeploy(c *ctx.ServiceContext, form *forms.DeployEnvForm) (*models.EnvDet
 		return nil, e.New(e.BadParam, http.StatusBadRequest)
 	}
 
+	// 紧急变更：平台管理员可以忽略冻结窗口执行部署或销毁
+	var frozenBy *models.FreezeWindow
+	if form.FreezeOverride {
+		if !c.IsSuperAdmin {
+			_ = tx.Rollback()
+			return nil, e.New(e.PermissionDeny, fmt.Errorf("freeze override requires platform admin"), http.StatusForbidden)
+		}
+		if strings.TrimSpace(form.FreezeOverrideReason) == "" {
+			_ = tx.Rollback()
+			return nil, e.New(e.BadParam, fmt.Errorf("'freezeOverrideReason' is required"), http.StatusBadRequest)
+		}
+		if frozenBy, err = services.GetActiveFreezeWindow(tx, env, time.Now()); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+
 	targets := make([]string, 0)
 	if len(strings.TrimSpace(form.Targets)) > 0 {
 		targets = strings.Split(strings.TrimSpace(form.Targets), ",")
@@ -538,12 +653,19 @@ func EnvDeploy(c *ctx.ServiceContext, form *forms.DeployEnvForm) (*models.EnvDet
 		StepTimeout: form.Timeout,
 		AutoApprove: env.AutoApproval,
 		Revision:    env.Revision,
+		Extra:       models.TaskExtra{FreezeOverride: frozenBy != nil},
 	})
 
 	if err != nil {
 		_ = tx.Rollback()
 		c.Logger().Errorf("error creating task, err %s", err)
-		return nil, e.New(err.Code(), err, http.StatusInternalServerError)
+		return nil, e.New(err.Code(), err, createTaskErrStatus(err))
+	}
+
+	varWarnings, err := checkEnvTfVariables(c, tx, tpl, task)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
 	}
 
 	// Save() 调用会全量将结构体中的字段进行保存，即使字段为 zero value
@@ -559,12 +681,26 @@ func EnvDeploy(c *ctx.ServiceContext, form *forms.DeployEnvForm) (*models.EnvDet
 		return nil, e.New(e.DBError, err)
 	}
 
+	if varsBefore != nil || varsAfter != nil {
+		recordAuditLog(c, "variables", env.Id, models.OperationUpdate, "update env variables", varsBefore, varsAfter)
+	}
+	recordAuditLog(c, "envs", env.Id, taskOperationType(task.Type),
+		fmt.Sprintf("%s env %s", task.Type, env.Name), nil, taskAuditInfo(task))
+	if frozenBy != nil {
+		c.Logger().Warnf("freeze window %s overridden by %s, env %s, task %s: %s",
+			frozenBy.Id, c.Username, env.Id, task.Id, form.FreezeOverrideReason)
+		recordAuditLog(c, "freeze-windows", frozenBy.Id, models.OperationOverride,
+			fmt.Sprintf("override freeze window %s to %s env %s", frozenBy.Name, task.Type, env.Name),
+			frozenBy, freezeOverrideAuditInfo(task, form.FreezeOverrideReason))
+	}
+
 	// 屏蔽敏感字段输出
 	env.HideSensitiveVariable()
 	env.MergeTaskStatus()
 	envDetail := &models.EnvDetail{
-		Env:    *env,
-		TaskId: task.Id,
+		Env:              *env,
+		TaskId:           task.Id,
+		VariableWarnings: varWarnings,
 	}
 	envDetail = PopulateLastTask(c.DB(), envDetail)
 
@@ -591,3 +727,74 @@ func SearchEnvResources(c *ctx.ServiceContext, form *forms.SearchEnvResourceForm
 		Q:        form.Q,
 	})
 }
+
+type envDependenciesResp struct {
+	DependsOn  []*models.EnvDependencyResp `json:"dependsOn"`  // 环境依赖的上游环境
+	Dependents []*models.EnvDependencyResp `json:"dependents"` // 依赖该环境的下游环境
+}
+
+// EnvDependencies 查询环境的上下游依赖关系
+func EnvDependencies(c *ctx.ServiceContext, form *forms.DetailEnvForm) (interface{}, e.Error) {
+	query := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	if _, err := services.GetEnvById(query, form.Id); err != nil {
+		if err.Code() == e.EnvNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+
+	resp := envDependenciesResp{
+		DependsOn:  make([]*models.EnvDependencyResp, 0),
+		Dependents: make([]*models.EnvDependencyResp, 0),
+	}
+	if err := services.QueryEnvDependencies(c.DB(), form.Id, true).Scan(&resp.DependsOn); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	if err := services.QueryEnvDependencies(c.DB(), form.Id, false).Scan(&resp.Dependents); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return resp, nil
+}
+
+type variableChangesResp struct {
+	BaseTaskId models.Id               `json:"baseTaskId"` // 对比的部署任务ID(环境最后一次成功执行的部署任务)，环境未成功部署过时为空
+	Changes    []services.VariableDiff `json:"changes"`    // 变化的变量列表
+}
+
+// variableChangesSinceLastApply 比较变量与环境在 before 之前最后一次成功部署使用的变量
+func variableChangesSinceLastApply(query *db.Session, envId models.Id, before *models.Time,
+	vars []models.VariableBody) (*variableChangesResp, e.Error) {
+	resp := &variableChangesResp{}
+	olds := make([]models.VariableBody, 0)
+	lastTask, err := services.GetLastSucceededApplyTask(query, envId, before)
+	if err != nil {
+		return nil, err
+	}
+	if lastTask != nil {
+		resp.BaseTaskId = lastTask.Id
+		olds = lastTask.Variables
+	}
+	if resp.Changes, err = services.DiffVariables(olds, vars); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// EnvVariableDiff 查询环境下次部署将使用的变量与最后一次成功部署时使用的变量的差异
+func EnvVariableDiff(c *ctx.ServiceContext, form *forms.DetailEnvForm) (interface{}, e.Error) {
+	query := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	env, err := services.GetEnvById(query, form.Id)
+	if err != nil {
+		if err.Code() == e.EnvNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+
+	// 与创建任务时一致，引用的其他环境输出解析为实际的值后再比较
+	vars, _, err := services.ResolveEnvOutputRefs(c.DB(), env, services.GetVariableBody(env.Variables), false)
+	if err != nil {
+		return nil, e.New(err.Code(), err, http.StatusBadRequest)
+	}
+	return variableChangesSinceLastApply(c.DB(), env.Id, nil, vars)
+}
@@ -0,0 +1,364 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/common"
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"fmt"
+	"net/http"
+)
+
+// allowProjectAction 按 rbac 策略判断当前用户能否在指定项目(可以不是当前请求的项目)下执行操作
+func allowProjectAction(c *ctx.ServiceContext, projectId models.Id, object, action string) bool {
+	if c.ApiToken != nil && (!c.ApiToken.Scopes.AllowProject(projectId) || !c.ApiToken.Scopes.AllowAction(object, action)) {
+		return false
+	}
+	enforcer := c.Enforcer()
+	if err := enforcer.LoadPolicy(); err != nil {
+		c.Logger().Errorf("error load rbac policy, err %s", err)
+		return false
+	}
+	role := services.AccessOrgRole(c.UserId, c.OrgId, c.IsSuperAdmin, c.ApiToken)
+	proj := services.AccessProjectRole(c.UserId, c.OrgId, projectId, c.IsSuperAdmin, c.ApiToken)
+	if !c.IsSuperAdmin && c.OrgId == models.Id(common.DemoOrgId) {
+		role, proj = consts.RoleDemo, consts.RoleDemo
+	}
+	allow, err := enforcer.Enforce(role, proj, object, action)
+	if err != nil {
+		c.Logger().Errorf("error enforce %s,%s %s:%s, err %s", role, proj, object, action, err)
+		return false
+	}
+	return allow
+}
+
+// getProjectEnv 获取当前项目下的环境
+func getProjectEnv(c *ctx.ServiceContext, query *db.Session, id models.Id) (*models.Env, e.Error) {
+	if err := checkTokenEnvScope(c, id); err != nil {
+		return nil, err
+	}
+	envQuery := services.QueryWithProjectId(services.QueryWithOrgId(query, c.OrgId), c.ProjectId)
+	env, err := services.GetEnvById(envQuery, id)
+	if err != nil && err.Code() == e.EnvNotExists {
+		return nil, e.New(err.Code(), err, http.StatusNotFound)
+	} else if err != nil {
+		c.Logger().Errorf("error get env, err %s", err)
+		return nil, e.New(e.DBError, err, http.StatusInternalServerError)
+	}
+	return env, nil
+}
+
+// getEnvTemplate 获取环境使用的模板，模板须为启用状态
+func getEnvTemplate(c *ctx.ServiceContext, query *db.Session, env *models.Env) (*models.Template, e.Error) {
+	tpl, err := services.GetTemplateById(services.QueryWithOrgId(query, c.OrgId), env.TplId)
+	if err != nil && err.Code() == e.TemplateNotExists {
+		return nil, e.New(err.Code(), err, http.StatusBadRequest)
+	} else if err != nil {
+		c.Logger().Errorf("error get template, err %s", err)
+		return nil, e.New(e.DBError, err, http.StatusInternalServerError)
+	}
+	if tpl.Status == models.Disable {
+		return nil, e.New(e.TemplateDisabled, http.StatusBadRequest)
+	}
+	return tpl, nil
+}
+
+// checkCloneTargetProject 检查克隆的目标项目，跨项目克隆时需要在目标项目下有创建环境的权限，且模板已关联到目标项目
+func checkCloneTargetProject(c *ctx.ServiceContext, projectId models.Id, tplId models.Id) e.Error {
+	if projectId == c.ProjectId {
+		return nil
+	}
+
+	project, err := services.DetailProject(c.DB(), projectId)
+	if err != nil {
+		if e.IsRecordNotFound(err.Err()) {
+			return e.New(e.ProjectNotExists, http.StatusBadRequest)
+		}
+		return err
+	}
+	if project.OrgId != c.OrgId {
+		return e.New(e.ProjectNotExists, http.StatusBadRequest)
+	}
+	if project.Status == models.Disable {
+		return e.New(e.BadParam, fmt.Errorf("project '%s' is disabled", project.Name), http.StatusBadRequest)
+	}
+	if !allowProjectAction(c, projectId, "envs", "create") {
+		return e.New(e.PermissionDeny, fmt.Errorf("no permission to create env in project '%s'", project.Name),
+			http.StatusForbidden)
+	}
+
+	linked, err := services.IsTemplateInProject(c.DB(), tplId, projectId)
+	if err != nil {
+		return err
+	}
+	if !linked {
+		return e.New(e.TemplateNotExists, fmt.Errorf("template is not linked to project '%s'", project.Name),
+			http.StatusBadRequest)
+	}
+	return nil
+}
+
+// CloneEnv 克隆环境，复制模板、分支、变量、触发器、存活时间及密钥等配置，可选择克隆后立即执行任务
+func CloneEnv(c *ctx.ServiceContext, form *forms.CloneEnvForm) (*models.EnvDetail, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("clone env %s as %s", form.Id, form.Name))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	src, err := getProjectEnv(c, c.DB(), form.Id)
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := getEnvTemplate(c, c.DB(), src)
+	if err != nil {
+		return nil, err
+	}
+
+	projectId := c.ProjectId
+	if form.ProjectId != "" {
+		projectId = form.ProjectId
+	}
+	if err := checkCloneTargetProject(c, projectId, src.TplId); err != nil {
+		return nil, err
+	}
+
+	revision := src.Revision
+	if form.Revision != "" {
+		revision = form.Revision
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	env, err := services.CreateEnv(tx, models.Env{
+		OrgId:     c.OrgId,
+		ProjectId: projectId,
+		CreatorId: c.UserId,
+		TplId:     src.TplId,
+
+		Name:     form.Name,
+		Labels:   src.Labels,
+		RunnerId: src.RunnerId,
+		Status:   models.EnvStatusInactive,
+		OneTime:  src.OneTime,
+		Timeout:  src.Timeout,
+
+		TfVarsFile:   src.TfVarsFile,
+		PlayVarsFile: src.PlayVarsFile,
+		Playbook:     src.Playbook,
+		Revision:     revision,
+		KeyId:        src.KeyId,
+
+		// 新环境未部署，自动销毁时间在部署成功后根据 ttl 计算
+		TTL:          src.TTL,
+		AutoApproval: src.AutoApproval,
+
+		Triggers:          src.Triggers,
+		DependencyTrigger: src.DependencyTrigger,
+	})
+	if err != nil && err.Code() == e.EnvAlreadyExists {
+		_ = tx.Rollback()
+		return nil, e.New(err.Code(), err, http.StatusBadRequest)
+	} else if err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error creating env, err %s", err)
+		return nil, e.New(err.Code(), err, http.StatusInternalServerError)
+	}
+
+	// 复制源环境的环境级变量，被覆盖的变量不复制
+	overrides := make(map[string]bool)
+	for i := range form.Variables {
+		form.Variables[i].Id = ""
+		form.Variables[i].Scope = consts.ScopeEnv
+		overrides[form.Variables[i].Type+"/"+form.Variables[i].Name] = true
+	}
+	if err := services.CopyEnvVariables(tx, src.Id, env, func(v models.Variable) bool {
+		return overrides[v.Type+"/"+v.Name]
+	}); err != nil {
+		_ = tx.Rollback()
+		return nil, e.New(err.Code(), err, http.StatusInternalServerError)
+	}
+	if err := services.OperationVariables(tx, c.UserId, c.OrgId, projectId, env.TplId, env.Id, form.Variables, nil); err != nil {
+		_ = tx.Rollback()
+		return nil, e.New(err.Code(), err, http.StatusInternalServerError)
+	}
+	vars, err, _ := services.GetValidVariables(tx, consts.ScopeEnv, c.OrgId, projectId, env.TplId, env.Id, true)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, e.New(err.Code(), err, http.StatusInternalServerError)
+	}
+	env.Variables = getVariables(vars)
+
+	var task *models.Task
+	if form.TaskType != "" {
+		task, err = services.CreateTask(tx, tpl, env, models.Task{
+			Name:        models.Task{}.GetTaskNameByType(form.TaskType),
+			Type:        form.TaskType,
+			Flow:        models.TaskFlow{},
+			Targets:     []string{},
+			CreatorId:   c.UserId,
+			KeyId:       env.KeyId,
+			RunnerId:    env.RunnerId,
+			Variables:   services.GetVariableBody(env.Variables),
+			StepTimeout: env.Timeout,
+			AutoApprove: env.AutoApproval,
+			Revision:    env.Revision,
+		})
+		if err != nil {
+			_ = tx.Rollback()
+			c.Logger().Errorf("error creating task, err %s", err)
+			return nil, e.New(err.Code(), err, createTaskErrStatus(err))
+		}
+		env.LastTaskId = task.Id
+	}
+
+	if _, err := tx.Save(env); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error save env, err %s", err)
+		return nil, e.New(e.DBError, err, http.StatusInternalServerError)
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error commit env, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+
+	recordAuditLog(c, "envs", env.Id, models.OperationCreate,
+		fmt.Sprintf("clone env %s as %s", src.Name, env.Name), nil,
+		map[string]interface{}{"sourceEnvId": src.Id, "projectId": projectId, "revision": env.Revision})
+
+	env.HideSensitiveVariable()
+	envDetail := &models.EnvDetail{Env: *env}
+	if task != nil {
+		envDetail.TaskId = task.Id
+	}
+	return PopulateLastTask(c.DB(), envDetail), nil
+}
+
+// PromoteEnv 环境晋级，将源环境最后一次部署成功的 commit 部署到目标环境，目标环境使用自身的变量
+func PromoteEnv(c *ctx.ServiceContext, form *forms.PromoteEnvForm) (*models.EnvDetail, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("promote env %s to %s", form.Id, form.TargetEnvId))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+	if form.Id == form.TargetEnvId {
+		return nil, e.New(e.BadParam, fmt.Errorf("target env must be different from source env"), http.StatusBadRequest)
+	}
+	if err := checkTokenEnvScope(c, form.TargetEnvId); err != nil {
+		return nil, err
+	}
+	taskType := form.TaskType
+	if taskType == "" {
+		taskType = models.TaskTypeApply
+	}
+	if taskType != models.TaskTypePlan && taskType != models.TaskTypeApply {
+		return nil, e.New(e.BadParam, fmt.Errorf("invalid task type '%s'", taskType), http.StatusBadRequest)
+	}
+
+	src, err := getProjectEnv(c, c.DB(), form.Id)
+	if err != nil {
+		return nil, err
+	}
+	srcTask, err := services.GetEnvLastAppliedTask(c.DB(), src.Id)
+	if err != nil && err.Code() == e.TaskNotExists {
+		return nil, e.New(e.BadParam, fmt.Errorf("env '%s' has not been deployed", src.Name), http.StatusBadRequest)
+	} else if err != nil {
+		return nil, err
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// 目标环境可以在同组织的其他项目下
+	env, err := services.GetEnvById(services.QueryWithOrgId(tx, c.OrgId), form.TargetEnvId)
+	if err != nil && err.Code() == e.EnvNotExists {
+		_ = tx.Rollback()
+		return nil, e.New(err.Code(), err, http.StatusBadRequest)
+	} else if err != nil {
+		_ = tx.Rollback()
+		return nil, e.New(e.DBError, err, http.StatusInternalServerError)
+	}
+	if env.ProjectId != c.ProjectId && !allowProjectAction(c, env.ProjectId, "envs", "deploy") {
+		_ = tx.Rollback()
+		return nil, e.New(e.PermissionDeny, fmt.Errorf("no permission to deploy env '%s'", env.Name), http.StatusForbidden)
+	}
+	if env.TplId != src.TplId {
+		_ = tx.Rollback()
+		return nil, e.New(e.BadParam, fmt.Errorf("target env must use the same template"), http.StatusBadRequest)
+	}
+	if env.Archived {
+		_ = tx.Rollback()
+		return nil, e.New(e.EnvArchived, http.StatusBadRequest)
+	}
+	if env.Deploying {
+		_ = tx.Rollback()
+		return nil, e.New(e.EnvDeploying, http.StatusBadRequest)
+	}
+	tpl, err := getEnvTemplate(c, tx, env)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	task, err := services.CreateTask(tx, tpl, env, models.Task{
+		Name:        models.Task{}.GetTaskNameByType(taskType),
+		Type:        taskType,
+		Flow:        models.TaskFlow{},
+		Targets:     []string{},
+		CreatorId:   c.UserId,
+		KeyId:       env.KeyId,
+		RunnerId:    env.RunnerId,
+		Variables:   services.GetVariableBody(env.Variables),
+		StepTimeout: env.Timeout,
+		AutoApprove: env.AutoApproval,
+		Revision:    srcTask.Revision,
+		CommitId:    srcTask.CommitId,
+		Extra:       models.TaskExtra{Source: consts.EnvPromote},
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error creating task, err %s", err)
+		return nil, e.New(err.Code(), err, createTaskErrStatus(err))
+	}
+
+	if _, err := tx.Save(env); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error save env, err %s", err)
+		return nil, e.New(e.DBError, err, http.StatusInternalServerError)
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error commit env, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+
+	info := taskAuditInfo(task)
+	info["sourceEnvId"] = src.Id
+	info["sourceTaskId"] = srcTask.Id
+	recordAuditLog(c, "envs", env.Id, taskOperationType(task.Type),
+		fmt.Sprintf("promote env %s to %s", src.Name, env.Name), nil, info)
+
+	env.HideSensitiveVariable()
+	env.MergeTaskStatus()
+	envDetail := &models.EnvDetail{
+		Env:    *env,
+		TaskId: task.Id,
+	}
+	return PopulateLastTask(c.DB(), envDetail), nil
+}
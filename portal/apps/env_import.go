@@ -0,0 +1,243 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"cloudiac/runner"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// tfStateFile terraform state 文件的基本结构，用于校验上传的 state
+type tfStateFile struct {
+	Version   int               `json:"version"`
+	Lineage   string            `json:"lineage"`
+	Resources []json.RawMessage `json:"resources"`
+}
+
+func parseTfStateFile(content []byte) (*tfStateFile, error) {
+	state := tfStateFile{}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, err
+	}
+	// 只支持 terraform 0.12 及以上版本生成的 state
+	if state.Version != 4 {
+		return nil, fmt.Errorf("unsupported state version %d", state.Version)
+	}
+	if state.Lineage == "" {
+		return nil, fmt.Errorf("state lineage is required")
+	}
+	return &state, nil
+}
+
+// getDeployableEnv 获取当前项目下可以执行任务的环境及其模板
+func getDeployableEnv(c *ctx.ServiceContext, tx *db.Session, id models.Id) (*models.Env, *models.Template, e.Error) {
+	env, err := getProjectEnv(c, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if env.Archived {
+		return nil, nil, e.New(e.EnvArchived, http.StatusBadRequest)
+	}
+	if env.Deploying {
+		return nil, nil, e.New(e.EnvDeploying, http.StatusBadRequest)
+	}
+	tpl, err := getEnvTemplate(c, tx, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	return env, tpl, nil
+}
+
+// createEnvImportTask 创建导入任务，flow 为空时使用默认的导入流程
+func createEnvImportTask(c *ctx.ServiceContext, tx *db.Session, tpl *models.Template, env *models.Env,
+	flow models.TaskFlow) (*models.Task, e.Error) {
+	task, err := services.CreateTask(tx, tpl, env, models.Task{
+		Name:        models.Task{}.GetTaskNameByType(models.TaskTypeImport),
+		Type:        models.TaskTypeImport,
+		Flow:        flow,
+		Targets:     []string{},
+		CreatorId:   c.UserId,
+		KeyId:       env.KeyId,
+		RunnerId:    env.RunnerId,
+		Variables:   services.GetVariableBody(env.Variables),
+		StepTimeout: env.Timeout,
+		AutoApprove: env.AutoApproval,
+		Revision:    env.Revision,
+	})
+	if err != nil {
+		c.Logger().Errorf("error creating task, err %s", err)
+		return nil, e.New(err.Code(), err, createTaskErrStatus(err))
+	}
+	return task, nil
+}
+
+// EnvImport 通过 terraform import 将已有的云资源导入到环境
+func EnvImport(c *ctx.ServiceContext, form *forms.ImportEnvForm) (*models.EnvDetail, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("import env resources %s", form.Id))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	args := make([]string, 0, len(form.Resources))
+	addresses := make(map[string]bool)
+	for _, r := range form.Resources {
+		address, id, err := runner.ParseImportArg(r)
+		if err != nil {
+			return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+		}
+		if addresses[address] {
+			return nil, e.New(e.BadParam, fmt.Errorf("duplicate resource address '%s'", address), http.StatusBadRequest)
+		}
+		addresses[address] = true
+		args = append(args, fmt.Sprintf("%s=%s", address, id))
+	}
+
+	flow, er := models.DefaultTaskFlow(models.TaskTypeImport)
+	if er != nil {
+		return nil, e.New(e.InternalError, er)
+	}
+	steps := make([]models.TaskStepBody, 0, len(flow.Steps))
+	for _, step := range flow.Steps {
+		if step.Type == models.TaskStepImport {
+			step.Args = args
+		}
+		steps = append(steps, step)
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	env, tpl, err := getDeployableEnv(c, tx, form.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	task, err := createEnvImportTask(c, tx, tpl, env, models.TaskFlow{Steps: steps})
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error commit env, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+
+	info := taskAuditInfo(task)
+	info["resources"] = args
+	recordAuditLog(c, "envs", env.Id, models.OperationCreate,
+		fmt.Sprintf("import resources to env %s", env.Name), nil, info)
+
+	env.HideSensitiveVariable()
+	envDetail := &models.EnvDetail{
+		Env:    *env,
+		TaskId: task.Id,
+	}
+	return PopulateLastTask(c.DB(), envDetail), nil
+}
+
+// EnvUploadState 上传已有的 terraform state 初始化环境，上传后执行导入任务以同步环境资源
+func EnvUploadState(c *ctx.ServiceContext, form *forms.UploadEnvStateForm) (*models.EnvDetail, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("upload env state %s", form.Id))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	state, er := parseTfStateFile([]byte(form.State))
+	if er != nil {
+		return nil, e.New(e.EnvStateInvalid, er, http.StatusBadRequest)
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	env, tpl, err := getDeployableEnv(c, tx, form.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	// 只允许初始化未部署的环境，已销毁的环境可能存在资源为空的 state，允许覆盖
+	if env.Status != models.EnvStatusInactive {
+		_ = tx.Rollback()
+		return nil, e.New(e.EnvStateAlreadyExists, http.StatusBadRequest)
+	}
+	current, err := services.ConsulKVSearch(env.StatePath)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	} else if current != nil {
+		if s, er := parseTfStateFile([]byte(current.(string))); er == nil && len(s.Resources) > 0 {
+			_ = tx.Rollback()
+			return nil, e.New(e.EnvStateAlreadyExists, http.StatusBadRequest)
+		}
+	}
+
+	// 只执行 init 步骤，由任务结束后的信息采集步骤同步环境资源
+	task, err := createEnvImportTask(c, tx, tpl, env, models.TaskFlow{
+		Steps: []models.TaskStepBody{{Type: models.TaskStepInit}},
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error commit env, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+
+	// 任务提交后再写入 state，避免事务提交失败时远端 state 已被覆盖
+	if err := services.ConsulKVPut(env.StatePath, []byte(form.State)); err != nil {
+		c.Logger().Errorf("error put env state, err %s", err)
+		// 写入失败时(如请求超时) state 可能已被修改，恢复为上传前的 state
+		restoreEnvState(c, env.StatePath, current)
+		if er := services.ChangeTaskStatus(c.DB(), task, models.TaskFailed, "upload state failed"); er != nil {
+			c.Logger().Errorf("error change task status, err %s", er)
+		}
+		return nil, err
+	}
+
+	info := taskAuditInfo(task)
+	info["lineage"] = state.Lineage
+	info["resourceCount"] = len(state.Resources)
+	recordAuditLog(c, "envs", env.Id, models.OperationCreate,
+		fmt.Sprintf("upload state to env %s", env.Name), nil, info)
+
+	env.HideSensitiveVariable()
+	envDetail := &models.EnvDetail{
+		Env:    *env,
+		TaskId: task.Id,
+	}
+	return PopulateLastTask(c.DB(), envDetail), nil
+}
+
+// restoreEnvState 将环境的 state 恢复为 backup，backup 为空时删除 state
+func restoreEnvState(c *ctx.ServiceContext, statePath string, backup interface{}) {
+	var err e.Error
+	if s, ok := backup.(string); ok {
+		err = services.ConsulKVPut(statePath, []byte(s))
+	} else {
+		err = services.ConsulKVDelete(statePath)
+	}
+	if err != nil {
+		c.Logger().Errorf("error restore env state %s, err %s", statePath, err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"fmt"
+	"net/http"
+)
+
+// EnvRollback 使用历史部署任务的 commit 和变量快照重新部署环境。
+// 回滚任务始终需要审批，审批前可以查看执行计划与当前 state 的差异
+func EnvRollback(c *ctx.ServiceContext, form *forms.RollbackEnvForm) (*models.EnvDetail, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("rollback env %s to task %s", form.Id, form.TaskId))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	env, tpl, err := getDeployableEnv(c, tx, form.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	target, err := services.GetTask(services.QueryWithProjectId(tx, c.ProjectId), form.TaskId)
+	if err != nil {
+		_ = tx.Rollback()
+		if err.Code() == e.TaskNotExists {
+			return nil, e.New(err.Code(), err, http.StatusBadRequest)
+		}
+		return nil, e.New(e.DBError, err, http.StatusInternalServerError)
+	}
+	// 只能回滚到本环境部署成功的版本
+	if target.EnvId != env.Id || target.Type != models.TaskTypeApply || target.Status != models.TaskComplete {
+		_ = tx.Rollback()
+		return nil, e.New(e.BadParam, fmt.Errorf("task '%s' is not a complete apply task of env", target.Id), http.StatusBadRequest)
+	}
+
+	task, err := services.CreateTask(tx, tpl, env, models.Task{
+		Name:        models.Task{}.GetTaskNameByType(models.TaskTypeApply),
+		Type:        models.TaskTypeApply,
+		Flow:        models.TaskFlow{},
+		Targets:     []string{},
+		CreatorId:   c.UserId,
+		KeyId:       env.KeyId,
+		RunnerId:    env.RunnerId,
+		Variables:   target.Variables,
+		StepTimeout: env.Timeout,
+		AutoApprove: false,
+		Revision:    target.Revision,
+		CommitId:    target.CommitId,
+		Extra:       models.TaskExtra{Source: consts.EnvRollback, RollbackTaskId: target.Id},
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error creating task, err %s", err)
+		return nil, e.New(err.Code(), err, createTaskErrStatus(err))
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error commit env, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+
+	info := taskAuditInfo(task)
+	info["rollbackTaskId"] = target.Id
+	recordAuditLog(c, "envs", env.Id, models.OperationUpdate,
+		fmt.Sprintf("rollback env %s to task %s", env.Name, target.Id), nil, info)
+
+	env.HideSensitiveVariable()
+	envDetail := &models.EnvDetail{
+		Env:    *env,
+		TaskId: task.Id,
+	}
+	return PopulateLastTask(c.DB(), envDetail), nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/page"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// checkEnvSchedule 检查定时任务配置并计算下次执行时间，暂停时不计算
+func checkEnvSchedule(schedule *models.EnvSchedule) e.Error {
+	if schedule.Action != models.TaskTypeApply && schedule.Action != models.TaskTypeDestroy {
+		return e.New(e.BadParam, fmt.Errorf("invalid action '%s'", schedule.Action), http.StatusBadRequest)
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "Local"
+	}
+
+	next, err := schedule.NextRunTime(time.Now())
+	if err != nil {
+		return e.New(e.EnvScheduleInvalidCron, err, http.StatusBadRequest)
+	}
+	if schedule.Paused {
+		next = nil
+	}
+	schedule.NextRunAt = next
+	return nil
+}
+
+// getEnvSchedule 查询当前项目下的定时任务
+func getEnvSchedule(c *ctx.ServiceContext, id models.Id) (*models.EnvSchedule, e.Error) {
+	query := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	schedule, err := services.GetEnvScheduleById(query, id)
+	if err != nil {
+		if err.Code() == e.EnvScheduleNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		c.Logger().Errorf("error get env schedule, err %s", err)
+		return nil, err
+	}
+	if err := checkTokenEnvScope(c, schedule.EnvId); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// CreateEnvSchedule 创建环境定时任务
+func CreateEnvSchedule(c *ctx.ServiceContext, form *forms.CreateEnvScheduleForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("create env schedule %s", form.Name))
+
+	env, err := getProjectEnv(c, c.DB(), form.EnvId)
+	if err != nil {
+		return nil, err
+	}
+	if env.Archived {
+		return nil, e.New(e.EnvArchived, http.StatusBadRequest)
+	}
+
+	schedule := models.EnvSchedule{
+		OrgId:     c.OrgId,
+		ProjectId: c.ProjectId,
+		EnvId:     env.Id,
+		CreatorId: c.UserId,
+		Name:      form.Name,
+		Action:    form.Action,
+		Cron:      form.Cron,
+		Timezone:  form.Timezone,
+		Paused:    form.Paused,
+	}
+	if err := checkEnvSchedule(&schedule); err != nil {
+		return nil, err
+	}
+
+	s, err := services.CreateEnvSchedule(c.DB(), schedule)
+	if err != nil {
+		c.Logger().Errorf("error create env schedule, err %s", err)
+		return nil, err
+	}
+	return s, nil
+}
+
+// SearchEnvSchedule 查询环境定时任务
+func SearchEnvSchedule(c *ctx.ServiceContext, form *forms.SearchEnvScheduleForm) (interface{}, e.Error) {
+	query := services.QueryEnvSchedule(services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId))
+	if form.EnvId != "" {
+		query = query.Where("env_id = ?", form.EnvId)
+	}
+	query = queryWithTokenEnvScope(c, query, "env_id")
+	if form.SortField() == "" {
+		query = query.Order("created_at DESC")
+	}
+
+	p := page.New(form.CurrentPage(), form.PageSize(), form.Order(query))
+	schedules := make([]*models.EnvSchedule, 0)
+	if err := p.Scan(&schedules); err != nil {
+		c.Logger().Errorf("error search env schedule, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     schedules,
+	}, nil
+}
+
+// EnvScheduleDetail 环境定时任务详情
+func EnvScheduleDetail(c *ctx.ServiceContext, form *forms.DetailEnvScheduleForm) (interface{}, e.Error) {
+	return getEnvSchedule(c, form.Id)
+}
+
+// UpdateEnvSchedule 修改环境定时任务，修改 paused 实现暂停和恢复
+func UpdateEnvSchedule(c *ctx.ServiceContext, form *forms.UpdateEnvScheduleForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("update env schedule %s", form.Id))
+	schedule, err := getEnvSchedule(c, form.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := models.Attrs{}
+	if form.HasKey("name") {
+		attrs["name"] = form.Name
+	}
+	if form.HasKey("action") {
+		schedule.Action = form.Action
+		attrs["action"] = form.Action
+	}
+	if form.HasKey("cron") {
+		schedule.Cron = form.Cron
+		attrs["cron"] = form.Cron
+	}
+	if form.HasKey("timezone") {
+		schedule.Timezone = form.Timezone
+	}
+	if form.HasKey("paused") {
+		schedule.Paused = form.Paused
+		attrs["paused"] = form.Paused
+	}
+	if form.HasKey("action") || form.HasKey("cron") || form.HasKey("timezone") || form.HasKey("paused") {
+		if err := checkEnvSchedule(schedule); err != nil {
+			return nil, err
+		}
+		attrs["timezone"] = schedule.Timezone
+		attrs["next_run_at"] = schedule.NextRunAt
+	}
+
+	schedule, err = services.UpdateEnvSchedule(c.DB(), form.Id, attrs)
+	if err != nil {
+		c.Logger().Errorf("error update env schedule, err %s", err)
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// DeleteEnvSchedule 删除环境定时任务及其执行记录
+func DeleteEnvSchedule(c *ctx.ServiceContext, form *forms.DeleteEnvScheduleForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("delete env schedule %s", form.Id))
+	if _, err := getEnvSchedule(c, form.Id); err != nil {
+		return nil, err
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err := services.DeleteEnvSchedule(tx, form.Id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, e.New(e.DBError, err)
+	}
+	return nil, nil
+}
+
+// SearchEnvScheduleRun 查询环境定时任务的执行记录
+func SearchEnvScheduleRun(c *ctx.ServiceContext, form *forms.SearchEnvScheduleRunForm) (interface{}, e.Error) {
+	if _, err := getEnvSchedule(c, form.Id); err != nil {
+		return nil, err
+	}
+
+	query := services.QueryEnvScheduleRun(c.DB(), form.Id)
+	if form.SortField() == "" {
+		query = query.Order("created_at DESC")
+	}
+	p := page.New(form.CurrentPage(), form.PageSize(), form.Order(query))
+	runs := make([]*models.EnvScheduleRun, 0)
+	if err := p.Scan(&runs); err != nil {
+		c.Logger().Errorf("error search env schedule run, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     runs,
+	}, nil
+}
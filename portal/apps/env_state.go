@@ -0,0 +1,136 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"cloudiac/runner"
+	"cloudiac/utils"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+const envStateOpReplace = "replace"
+
+// 资源地址只允许包含 terraform 地址中的合法字符
+var resourceAddressRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-.\[\]"]+$`)
+
+// envStateOperationFlow 生成资源状态操作的任务流程，replace 通过 plan 和 apply 完成，其他操作通过 state 步骤完成
+func envStateOperationFlow(form *forms.EnvStateOperationForm) models.TaskFlow {
+	steps := []models.TaskStepBody{{Type: models.TaskStepInit}}
+	if form.Operation == envStateOpReplace {
+		args := make([]string, 0, len(form.Addresses))
+		for _, addr := range form.Addresses {
+			args = append(args, fmt.Sprintf("-replace=%s", runner.ShellQuote(addr)))
+		}
+		steps = append(steps,
+			models.TaskStepBody{Type: models.TaskStepPlan, Args: args},
+			models.TaskStepBody{Type: models.TaskStepApply})
+	} else {
+		args := append([]string{form.Operation}, form.Addresses...)
+		if form.Operation == runner.StateOpMv {
+			args = append(args, form.Destination)
+		}
+		steps = append(steps, models.TaskStepBody{Type: models.TaskStepState, Args: args})
+	}
+	return models.TaskFlow{Steps: steps}
+}
+
+// EnvStateOperation 对环境资源执行 replace、taint、state rm、state mv 操作，操作的资源必须是环境当前的资源
+func EnvStateOperation(c *ctx.ServiceContext, form *forms.EnvStateOperationForm) (*models.EnvDetail, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("%s env resources %s", form.Operation, form.Id))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	switch form.Operation {
+	case envStateOpReplace, runner.StateOpTaint, runner.StateOpRm:
+		if form.Destination != "" {
+			return nil, e.New(e.BadParam, fmt.Errorf("'destination' is only valid for mv"), http.StatusBadRequest)
+		}
+	case runner.StateOpMv:
+		if len(form.Addresses) != 1 || form.Destination == "" {
+			return nil, e.New(e.BadParam, fmt.Errorf("mv requires one address and 'destination'"), http.StatusBadRequest)
+		}
+		if !resourceAddressRegex.MatchString(form.Destination) {
+			return nil, e.New(e.BadParam, fmt.Errorf("invalid destination '%s'", form.Destination), http.StatusBadRequest)
+		}
+	default:
+		return nil, e.New(e.BadParam, fmt.Errorf("invalid operation '%s'", form.Operation), http.StatusBadRequest)
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	env, tpl, err := getDeployableEnv(c, tx, form.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	addresses, err := services.GetEnvResourceAddresses(tx, env)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	for _, addr := range form.Addresses {
+		if !utils.StrInArray(addr, addresses...) {
+			_ = tx.Rollback()
+			return nil, e.New(e.BadParam, fmt.Errorf("resource '%s' not found in env", addr), http.StatusBadRequest)
+		}
+	}
+	if form.Operation == runner.StateOpMv && utils.StrInArray(form.Destination, addresses...) {
+		_ = tx.Rollback()
+		return nil, e.New(e.BadParam, fmt.Errorf("resource '%s' already exists", form.Destination), http.StatusBadRequest)
+	}
+
+	task, err := services.CreateTask(tx, tpl, env, models.Task{
+		Name:        fmt.Sprintf("%s %s", models.Task{}.GetTaskNameByType(models.TaskTypeState), form.Operation),
+		Type:        models.TaskTypeState,
+		Flow:        envStateOperationFlow(form),
+		Targets:     []string{},
+		CreatorId:   c.UserId,
+		KeyId:       env.KeyId,
+		RunnerId:    env.RunnerId,
+		Variables:   services.GetVariableBody(env.Variables),
+		StepTimeout: env.Timeout,
+		AutoApprove: env.AutoApproval,
+		Revision:    env.Revision,
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error creating task, err %s", err)
+		return nil, e.New(err.Code(), err, createTaskErrStatus(err))
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error commit env, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+
+	info := taskAuditInfo(task)
+	info["operation"] = form.Operation
+	info["addresses"] = form.Addresses
+	if form.Destination != "" {
+		info["destination"] = form.Destination
+	}
+	recordAuditLog(c, "envs", env.Id, models.OperationUpdate,
+		fmt.Sprintf("%s resources of env %s", form.Operation, env.Name), nil, info)
+
+	env.HideSensitiveVariable()
+	envDetail := &models.EnvDetail{
+		Env:    *env,
+		TaskId: task.Id,
+	}
+	return PopulateLastTask(c.DB(), envDetail), nil
+}
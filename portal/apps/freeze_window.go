@@ -0,0 +1,214 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/page"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"cloudiac/utils"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// parseFreezeWindowTime 解析冻结窗口的起止时间
+func parseFreezeWindowTime(startAt, endAt string) (start models.Time, end models.Time, er e.Error) {
+	var err error
+	if start, err = (models.Time{}).Parse(startAt); err != nil {
+		return start, end, e.New(e.BadParam, fmt.Errorf("invalid startAt: %v", err), http.StatusBadRequest)
+	}
+	if end, err = (models.Time{}).Parse(endAt); err != nil {
+		return start, end, e.New(e.BadParam, fmt.Errorf("invalid endAt: %v", err), http.StatusBadRequest)
+	}
+	if !time.Time(end).After(time.Time(start)) {
+		return start, end, e.New(e.FreezeWindowInvalidTime, http.StatusBadRequest)
+	}
+	return start, end, nil
+}
+
+// checkFreezeWindowEnvs 检查环境是否都在冻结窗口的作用范围(当前组织或项目)内
+func checkFreezeWindowEnvs(c *ctx.ServiceContext, envIds []models.Id) (models.StrSlice, e.Error) {
+	ids := make(models.StrSlice, 0, len(envIds))
+	for _, id := range envIds {
+		if !utils.StrInArray(string(id), ids...) {
+			ids = append(ids, string(id))
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	if err := checkTokenEnvScope(c, envIds...); err != nil {
+		return nil, err
+	}
+
+	query := services.QueryWithOrgId(c.DB().Model(models.Env{}), c.OrgId).Where("id IN (?)", ids)
+	if c.ProjectId != "" {
+		query = services.QueryWithProjectId(query, c.ProjectId)
+	}
+	count, err := query.Count()
+	if err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	if int(count) != len(ids) {
+		return nil, e.New(e.BadParam, fmt.Errorf("invalid env ids"), http.StatusBadRequest)
+	}
+	return ids, nil
+}
+
+// getFreezeWindow 查询当前组织下的冻结窗口，在项目下修改时只允许操作本项目的冻结窗口
+func getFreezeWindow(c *ctx.ServiceContext, id models.Id, writable bool) (*models.FreezeWindow, e.Error) {
+	window, err := services.GetFreezeWindowById(services.QueryWithOrgId(c.DB(), c.OrgId), id)
+	if err != nil {
+		if err.Code() == e.FreezeWindowNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		c.Logger().Errorf("error get freeze window, err %s", err)
+		return nil, err
+	}
+
+	if c.ProjectId != "" && window.ProjectId != c.ProjectId && (writable || window.ProjectId != "") {
+		return nil, e.New(e.FreezeWindowNotExists, http.StatusNotFound)
+	}
+	return window, nil
+}
+
+func freezeOverrideAuditInfo(task *models.Task, reason string) map[string]interface{} {
+	info := taskAuditInfo(task)
+	info["envId"] = task.EnvId
+	info["reason"] = reason
+	return info
+}
+
+// CreateFreezeWindow 创建冻结窗口，在项目下创建时为项目级冻结窗口
+func CreateFreezeWindow(c *ctx.ServiceContext, form *forms.CreateFreezeWindowForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("create freeze window %s", form.Name))
+
+	startAt, endAt, err := parseFreezeWindowTime(form.StartAt, form.EndAt)
+	if err != nil {
+		return nil, err
+	}
+	envIds, err := checkFreezeWindowEnvs(c, form.EnvIds)
+	if err != nil {
+		return nil, err
+	}
+
+	window, err := services.CreateFreezeWindow(c.DB(), models.FreezeWindow{
+		OrgId:     c.OrgId,
+		ProjectId: c.ProjectId,
+		Name:      form.Name,
+		Reason:    form.Reason,
+		StartAt:   startAt,
+		EndAt:     endAt,
+		EnvIds:    envIds,
+		CreatorId: c.UserId,
+	})
+	if err != nil {
+		c.Logger().Errorf("error create freeze window, err %s", err)
+		return nil, err
+	}
+	return window, nil
+}
+
+// SearchFreezeWindow 查询冻结窗口，项目下同时返回组织级冻结窗口
+func SearchFreezeWindow(c *ctx.ServiceContext, form *forms.SearchFreezeWindowForm) (interface{}, e.Error) {
+	query := services.QueryFreezeWindow(services.QueryWithOrgId(c.DB(), c.OrgId))
+	if c.ProjectId != "" {
+		query = query.Where("project_id = '' OR project_id = ?", c.ProjectId)
+	}
+	if form.Q != "" {
+		query = query.WhereLike("name", form.Q)
+	}
+
+	now := time.Now()
+	switch form.Status {
+	case "":
+	case "active":
+		query = query.Where("start_at <= ? AND end_at > ?", now, now)
+	case "upcoming":
+		query = query.Where("start_at > ?", now)
+	case "ended":
+		query = query.Where("end_at <= ?", now)
+	default:
+		return nil, e.New(e.BadParam, fmt.Errorf("invalid status '%s'", form.Status), http.StatusBadRequest)
+	}
+
+	if form.SortField() == "" {
+		query = query.Order("start_at DESC")
+	}
+
+	p := page.New(form.CurrentPage(), form.PageSize(), form.Order(query))
+	windows := make([]*models.FreezeWindow, 0)
+	if err := p.Scan(&windows); err != nil {
+		c.Logger().Errorf("error search freeze window, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     windows,
+	}, nil
+}
+
+// FreezeWindowDetail 冻结窗口详情
+func FreezeWindowDetail(c *ctx.ServiceContext, form *forms.DetailFreezeWindowForm) (interface{}, e.Error) {
+	return getFreezeWindow(c, form.Id, false)
+}
+
+// UpdateFreezeWindow 修改冻结窗口
+func UpdateFreezeWindow(c *ctx.ServiceContext, form *forms.UpdateFreezeWindowForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("update freeze window %s", form.Id))
+	window, err := getFreezeWindow(c, form.Id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := models.Attrs{}
+	if form.HasKey("name") {
+		attrs["name"] = form.Name
+	}
+	if form.HasKey("reason") {
+		attrs["reason"] = form.Reason
+	}
+	if form.HasKey("startAt") || form.HasKey("endAt") {
+		startAt, endAt := window.StartAt, window.EndAt
+		startAtStr, endAtStr := time.Time(startAt).Format(time.RFC3339), time.Time(endAt).Format(time.RFC3339)
+		if form.HasKey("startAt") {
+			startAtStr = form.StartAt
+		}
+		if form.HasKey("endAt") {
+			endAtStr = form.EndAt
+		}
+		if startAt, endAt, err = parseFreezeWindowTime(startAtStr, endAtStr); err != nil {
+			return nil, err
+		}
+		attrs["start_at"] = startAt
+		attrs["end_at"] = endAt
+	}
+	if form.HasKey("envIds") {
+		envIds, err := checkFreezeWindowEnvs(c, form.EnvIds)
+		if err != nil {
+			return nil, err
+		}
+		attrs["env_ids"] = envIds
+	}
+
+	window, err = services.UpdateFreezeWindow(c.DB(), form.Id, attrs)
+	if err != nil {
+		c.Logger().Errorf("error update freeze window, err %s", err)
+		return nil, err
+	}
+	return window, nil
+}
+
+// DeleteFreezeWindow 删除冻结窗口
+func DeleteFreezeWindow(c *ctx.ServiceContext, form *forms.DeleteFreezeWindowForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("delete freeze window %s", form.Id))
+	if _, err := getFreezeWindow(c, form.Id, true); err != nil {
+		return nil, err
+	}
+	return nil, services.DeleteFreezeWindow(c.DB(), form.Id)
+}
@@ -10,6 +10,7 @@ import (
 	"cloudiac/portal/services"
 	"encoding/json"
 	"fmt"
+	"net/http"
 )
 
 func SearchNotification(c *ctx.ServiceContext) (interface{}, e.Error) {
@@ -45,6 +46,13 @@ func UpdateNotificationCfg(c *ctx.ServiceContext, form *forms.UpdateNotification
 		attrs["eventType"] = form.EventType
 	}
 
+	if form.HasKey("labelSelector") {
+		if _, err := models.ParseLabelSelector(form.LabelSelector); err != nil {
+			return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+		}
+		attrs["labelSelector"] = form.LabelSelector
+	}
+
 	if form.HasKey("cfgInfo") {
 		cfgInfo := form.CfgInfo
 		cfgJson, _ := json.Marshal(cfgInfo)
@@ -58,6 +66,10 @@ func UpdateNotificationCfg(c *ctx.ServiceContext, form *forms.UpdateNotification
 func CreateNotificationCfg(c *ctx.ServiceContext, form *forms.CreateNotificationCfgForm) (*models.NotificationCfg, e.Error) {
 	c.AddLogField("action", fmt.Sprintf("create org notification cfg %s", form.NotificationType))
 
+	if _, err := models.ParseLabelSelector(form.LabelSelector); err != nil {
+		return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+	}
+
 	tx := c.Tx().Debug()
 	defer func() {
 		if r := recover(); r != nil {
@@ -86,6 +98,7 @@ func CreateNotificationCfg(c *ctx.ServiceContext, form *forms.CreateNotification
 					NotificationType: form.NotificationType,
 					EventType:        form.EventType,
 					UserId:           userId,
+					LabelSelector:    form.LabelSelector,
 				})
 				if err != nil {
 					if e.IsDuplicate(err) {
@@ -101,6 +114,7 @@ func CreateNotificationCfg(c *ctx.ServiceContext, form *forms.CreateNotification
 				NotificationType: form.NotificationType,
 				EventType:        form.EventType,
 				CfgInfo:          cfgJson,
+				LabelSelector:    form.LabelSelector,
 			})
 			if err != nil {
 				return nil, err
@@ -5,6 +5,7 @@ package apps
 import (
 	"cloudiac/portal/consts/e"
 	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/page"
 	"cloudiac/portal/models"
 	"cloudiac/portal/models/forms"
 	"cloudiac/portal/services"
@@ -99,13 +100,23 @@ func DeleteProjectUser(c *ctx.ServiceContext, form *forms.DeleteProjectOrgUserFo
 	return nil, services.DeleteProjectUser(c.DB(), form.Id)
 }
 
+// SearchProjectAuthorizationUser 项目下授权的用户(包含通过团队授权的用户)，角色为用户在项目下的最高角色
 func SearchProjectAuthorizationUser(c *ctx.ServiceContext, form *forms.SearchProjectAuthorizationUserForm) (interface{}, e.Error) {
 	query := services.QueryUser(c.DB()).
 		Where("status = 'enable'").
 		Order("created_at DESC")
 
+	teamRoles, err := services.GetProjectTeamUserRoles(c.DB(), c.ProjectId)
+	if err != nil {
+		c.Logger().Errorf("error get project team users, err %s", err)
+		return nil, err
+	}
+
 	if c.ProjectId != "" {
 		userIds, _ := services.GetUserIdsByProjectUser(c.DB(), c.ProjectId)
+		for userId := range teamRoles {
+			userIds = append(userIds, userId)
+		}
 		query = query.Where(fmt.Sprintf("%s.id  in (?)", models.User{}.TableName()), userIds)
 	}
 
@@ -116,10 +127,19 @@ func SearchProjectAuthorizationUser(c *ctx.ServiceContext, form *forms.SearchPro
 			LazySelectAppend(fmt.Sprintf("o.role,%s.*", models.User{}.TableName()))
 	}
 
-	rs, err := getPage(query, form, &models.UserWithRoleResp{})
-	if err != nil {
+	p := page.New(form.CurrentPage(), form.PageSize(), form.Order(query))
+	users := make([]*models.UserWithRoleResp, 0)
+	if err := p.Scan(&users); err != nil {
 		c.Logger().Errorf("error get page, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	for _, u := range users {
+		u.Role = services.MaxProjectRole(u.Role, teamRoles[u.Id])
 	}
 
-	return rs, nil
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     users,
+	}, nil
 }
@@ -9,14 +9,35 @@ import (
 	"cloudiac/portal/models"
 	"cloudiac/portal/models/forms"
 	"cloudiac/portal/services"
+	"cloudiac/portal/services/credential"
+	"cloudiac/portal/services/sts"
 	"cloudiac/utils"
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"time"
 )
 
 func CreateResourceAccount(c *ctx.ServiceContext, form *forms.CreateResourceAccountForm) (*models.ResourceAccount, e.Error) {
 	c.AddLogField("action", fmt.Sprintf("create resource_account %s", form.Name))
 
+	if form.Type == "" {
+		form.Type = models.ResourceAccountStatic
+	}
+	if err := checkStsAccountForm(form.StsAccountForm, true); err != nil {
+		return nil, err
+	}
+	if form.Type == models.ResourceAccountStatic {
+		if err := checkTypedParams(form.Provider, form.Params, nil); err != nil {
+			return nil, err
+		}
+	}
+	secret, er := encryptAccessKeySecret(form.AccessKeySecret)
+	if er != nil {
+		return nil, er
+	}
+
 	tx := c.Tx()
 	defer func() {
 		if r := recover(); r != nil {
@@ -34,9 +55,16 @@ func CreateResourceAccount(c *ctx.ServiceContext, form *forms.CreateResourceAcco
 		jsons, _ := parseParams(form.Params, map[string]string{})
 
 		rsAcc := &models.ResourceAccount{
-			Name:        form.Name,
-			Description: form.Description,
-			Params:      models.JSON(string(jsons)),
+			Name:            form.Name,
+			Description:     form.Description,
+			Params:          models.JSON(string(jsons)),
+			Type:            form.Type,
+			Provider:        form.Provider,
+			RoleArn:         form.RoleArn,
+			ExternalId:      form.ExternalId,
+			Region:          form.Region,
+			AccessKeyId:     form.AccessKeyId,
+			AccessKeySecret: secret,
 		}
 		rsAcc.OrgId = c.OrgId
 
@@ -162,13 +190,60 @@ func UpdateResourceAccount(c *ctx.ServiceContext, form *forms.UpdateResourceAcco
 	for _, v := range vars {
 		newVars[v.Id] = v.Value
 	}
+	if form.HasKey("status") {
+		attrs["status"] = []byte(form.Status)
+	}
+
+	// 未传入的 sts 参数使用账号当前的值进行校验
+	sf := forms.StsAccountForm{
+		Type:        ra.Type,
+		Provider:    ra.Provider,
+		RoleArn:     ra.RoleArn,
+		ExternalId:  ra.ExternalId,
+		Region:      ra.Region,
+		AccessKeyId: ra.AccessKeyId,
+	}
+	for _, f := range []struct {
+		key    string
+		column string
+		value  string
+		merged *string
+	}{
+		{"type", "type", form.Type, &sf.Type},
+		{"provider", "provider", form.Provider, &sf.Provider},
+		{"roleArn", "role_arn", form.RoleArn, &sf.RoleArn},
+		{"externalId", "external_id", form.ExternalId, &sf.ExternalId},
+		{"region", "region", form.Region, &sf.Region},
+		{"accessKeyId", "access_key_id", form.AccessKeyId, &sf.AccessKeyId},
+	} {
+		if form.HasKey(f.key) {
+			*f.merged = f.value
+			attrs[f.column] = f.value
+		}
+	}
+	sf.AccessKeySecret = form.AccessKeySecret
+	if err := checkStsAccountForm(sf, ra.AccessKeySecret == ""); err != nil {
+		return nil, err
+	}
+	if sf.Type != models.ResourceAccountSts && (form.HasKey("params") || form.HasKey("provider")) {
+		params := vars
+		if form.HasKey("params") {
+			params = form.Params
+		}
+		if err := checkTypedParams(sf.Provider, params, newVars); err != nil {
+			return nil, err
+		}
+	}
 	if form.HasKey("params") {
 		jsons, _ := parseParams(form.Params, newVars)
 		attrs["params"] = models.JSON(string(jsons))
 	}
-
-	if form.HasKey("status") {
-		attrs["status"] = []byte(form.Status)
+	if form.AccessKeySecret != "" {
+		secret, err := encryptAccessKeySecret(form.AccessKeySecret)
+		if err != nil {
+			return nil, err
+		}
+		attrs["access_key_secret"] = secret
 	}
 
 	rsAccount, err = services.UpdateResourceAccount(c.DB(), form.Id, attrs)
@@ -234,3 +309,140 @@ func getParams(vars []forms.Params) []forms.Params {
 	}
 	return newVars
 }
+
+// checkStsAccountForm 校验账号类型、云厂商及 sts 类型账号的必填参数
+func checkStsAccountForm(form forms.StsAccountForm, requireSecret bool) e.Error {
+	switch form.Type {
+	case models.ResourceAccountStatic, "":
+		if _, ok := credential.GetSchema(form.Provider); form.Provider != "" && !ok {
+			return e.New(e.BadParam, fmt.Errorf("invalid provider '%s'", form.Provider), http.StatusBadRequest)
+		}
+		return nil
+	case models.ResourceAccountSts:
+	default:
+		return e.New(e.BadParam, fmt.Errorf("invalid type '%s'", form.Type), http.StatusBadRequest)
+	}
+
+	if !utils.StrInArray(form.Provider, sts.Providers...) {
+		return e.New(e.BadParam, fmt.Errorf("invalid provider '%s'", form.Provider), http.StatusBadRequest)
+	}
+	if form.RoleArn == "" || form.AccessKeyId == "" {
+		return e.New(e.BadParam, fmt.Errorf("'roleArn' and 'accessKeyId' are required"), http.StatusBadRequest)
+	}
+	if requireSecret && form.AccessKeySecret == "" {
+		return e.New(e.BadParam, fmt.Errorf("'accessKeySecret' is required"), http.StatusBadRequest)
+	}
+	return nil
+}
+
+// checkTypedParams 按云厂商的参数格式校验静态账号的参数，并将敏感参数标记为加密保存。
+// 敏感参数值为空时表示保留原值，existing 为参数 id 到原值的映射
+func checkTypedParams(provider string, params []forms.Params, existing map[string]string) e.Error {
+	schema, ok := credential.GetSchema(provider)
+	if !ok {
+		return nil
+	}
+	values := make(map[string]string)
+	for i, p := range params {
+		if f := schema.Field(p.Key); f != nil && f.Sensitive {
+			isSecret := true
+			params[i].IsSecret = &isSecret
+		}
+		value := p.Value
+		if value == "" && params[i].IsSecret != nil && *params[i].IsSecret {
+			value = existing[p.Id]
+		}
+		values[p.Key] = value
+	}
+	if err := schema.Check(values); err != nil {
+		return e.New(e.BadParam, err, http.StatusBadRequest)
+	}
+	return nil
+}
+
+func encryptAccessKeySecret(secret string) (string, e.Error) {
+	if secret == "" {
+		return "", nil
+	}
+	encrypted, err := utils.AesEncrypt(secret)
+	if err != nil {
+		return "", e.New(e.InternalError, err)
+	}
+	return encrypted, nil
+}
+
+// resourceAccountTestTimeout 测试资源账号连接的超时时间
+const resourceAccountTestTimeout = 30 * time.Second
+
+// ResourceAccountSchemas 云厂商资源账号的参数格式列表
+func ResourceAccountSchemas(c *ctx.ServiceContext) (interface{}, e.Error) {
+	return credential.Schemas(), nil
+}
+
+type TestResourceAccountResp struct {
+	Valid    bool   `json:"valid"`    // 凭证是否有效
+	Identity string `json:"identity"` // 凭证对应的身份信息
+	Message  string `json:"message"`  // 凭证无效时的错误信息
+}
+
+// TestResourceAccount 测试资源账号的凭证是否有效，sts 类型账号测试能否扮演角色
+func TestResourceAccount(c *ctx.ServiceContext, form *forms.TestResourceAccountForm) (*TestResourceAccountResp, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("test resource account %s", form.Id))
+
+	ra, err := services.GetResourceAccountById(c.DB(), form.Id)
+	if err != nil {
+		return nil, err
+	}
+	if ra.OrgId != c.OrgId {
+		return nil, e.New(e.ResourceAccountNotExists, http.StatusNotFound)
+	}
+
+	testCtx, cancel := context.WithTimeout(context.Background(), resourceAccountTestTimeout)
+	defer cancel()
+
+	var (
+		identity string
+		er       error
+	)
+	switch {
+	case ra.Type == models.ResourceAccountSts:
+		identity, er = testStsAccount(testCtx, ra)
+	case ra.Provider != "":
+		values, err := services.GetResourceAccountPlainParams(ra)
+		if err != nil {
+			return nil, err
+		}
+		identity, er = credential.Validate(testCtx, ra.Provider, values)
+	default:
+		return nil, e.New(e.BadParam, fmt.Errorf("resource account without provider can not be tested"), http.StatusBadRequest)
+	}
+	if er != nil {
+		c.Logger().Infof("test resource account %s: %v", ra.Id, er)
+		return &TestResourceAccountResp{Valid: false, Message: er.Error()}, nil
+	}
+	return &TestResourceAccountResp{Valid: true, Identity: identity}, nil
+}
+
+func testStsAccount(ctx context.Context, ra *models.ResourceAccount) (string, error) {
+	issuer, err := sts.GetIssuer(ra.Provider)
+	if err != nil {
+		return "", err
+	}
+	secret, err := utils.AesDecrypt(ra.AccessKeySecret)
+	if err != nil {
+		return "", err
+	}
+	_, err = issuer.AssumeRole(ctx, sts.AssumeRoleInput{
+		AccessKeyId:     ra.AccessKeyId,
+		AccessKeySecret: secret,
+		RoleArn:         ra.RoleArn,
+		SessionName:     fmt.Sprintf("iac-test-%s", ra.Id),
+		ExternalId:      ra.ExternalId,
+		Region:          ra.Region,
+		Duration:        sts.MinDuration,
+	})
+	if err != nil {
+		return "", err
+	}
+	return ra.RoleArn, nil
+}
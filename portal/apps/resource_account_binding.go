@@ -0,0 +1,111 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/page"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"fmt"
+	"net/http"
+)
+
+// CreateResourceAccountBinding 将组织的资源账号绑定到当前项目或项目下的环境，
+// 任务执行时账号变量会作为环境变量注入，同名变量优先级：环境/模板变量 > 环境绑定的账号 > 项目绑定的账号。
+// 只有组织管理员可以绑定(见 rbac 策略)，项目管理员只能查看
+func CreateResourceAccountBinding(c *ctx.ServiceContext, form *forms.CreateResourceAccountBindingForm) (*models.ResourceAccountBinding, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("bind resource account %s", form.ResourceAccountId))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	account, err := services.GetResourceAccountById(c.DB().Where("org_id = ?", c.OrgId), form.ResourceAccountId)
+	if err != nil {
+		if err.Code() == e.ObjectNotExists {
+			return nil, e.New(e.ResourceAccountNotExists, err, http.StatusBadRequest)
+		}
+		return nil, err
+	}
+	if form.EnvId != "" {
+		if _, err := getProjectEnv(c, c.DB(), form.EnvId); err != nil {
+			return nil, err
+		}
+	}
+
+	binding, err := services.CreateResourceAccountBinding(c.DB(), models.ResourceAccountBinding{
+		OrgId:             c.OrgId,
+		ProjectId:         c.ProjectId,
+		EnvId:             form.EnvId,
+		ResourceAccountId: account.Id,
+		CreatorId:         c.UserId,
+	})
+	if err != nil {
+		if err.Code() == e.ResourceAccountBindingExists {
+			return nil, e.New(err.Code(), err, http.StatusBadRequest)
+		}
+		c.Logger().Errorf("error create resource account binding, err %s", err)
+		return nil, err
+	}
+
+	resType, resId := "projects", c.ProjectId
+	if binding.EnvId != "" {
+		resType, resId = "envs", binding.EnvId
+	}
+	recordAuditLog(c, resType, resId, models.OperationCreate,
+		fmt.Sprintf("bind resource account %s", account.Name), nil, binding)
+	return binding, nil
+}
+
+// SearchResourceAccountBinding 查询当前项目下的资源账号绑定关系
+func SearchResourceAccountBinding(c *ctx.ServiceContext, form *forms.SearchResourceAccountBindingForm) (interface{}, e.Error) {
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+	if err := checkTokenEnvScope(c, form.EnvId); err != nil {
+		return nil, err
+	}
+	query := services.QueryResourceAccountBinding(c.DB()).
+		Where("iac_resource_account_binding.org_id = ? AND iac_resource_account_binding.project_id = ?", c.OrgId, c.ProjectId)
+	if form.EnvId != "" {
+		query = query.Where("iac_resource_account_binding.env_id IN (?)", []models.Id{"", form.EnvId})
+	}
+	query = query.Order("iac_resource_account_binding.env_id, iac_resource_account_binding.created_at")
+
+	p := page.New(form.CurrentPage(), form.PageSize(), query)
+	bindings := make([]*models.ResourceAccountBindingResp, 0)
+	if err := p.Scan(&bindings); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     bindings,
+	}, nil
+}
+
+// DeleteResourceAccountBinding 解除资源账号绑定
+func DeleteResourceAccountBinding(c *ctx.ServiceContext, form *forms.DeleteResourceAccountBindingForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("delete resource account binding %s", form.Id))
+	query := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	binding, err := services.GetResourceAccountBindingById(query, form.Id)
+	if err != nil {
+		if err.Code() == e.ResourceAccountBindingNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+	if err := services.DeleteResourceAccountBinding(c.DB(), binding.Id); err != nil {
+		return nil, err
+	}
+
+	resType, resId := "projects", binding.ProjectId
+	if binding.EnvId != "" {
+		resType, resId = "envs", binding.EnvId
+	}
+	recordAuditLog(c, resType, resId, models.OperationDelete,
+		fmt.Sprintf("unbind resource account %s", binding.ResourceAccountId), binding, nil)
+	return nil, nil
+}
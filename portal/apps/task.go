@@ -10,11 +10,14 @@ import (
 	"cloudiac/portal/models"
 	"cloudiac/portal/models/forms"
 	"cloudiac/portal/services"
+	"cloudiac/utils"
 	"fmt"
 	"github.com/gin-contrib/sse"
 	"io"
 	"net/http"
 	"path"
+	"reflect"
+	"sort"
 	"strconv"
 )
 
@@ -24,6 +27,7 @@ func SearchTask(c *ctx.ServiceContext, form *forms.SearchTaskForm) (interface{},
 	if form.EnvId != "" {
 		query = query.Where("env_id = ?", form.EnvId)
 	}
+	query = queryWithTokenEnvScope(c, query, "env_id")
 	// 默认按创建时间逆序排序
 	if form.SortField() == "" {
 		query = query.Order("created_at DESC")
@@ -176,20 +180,134 @@ func ApproveTask(c *ctx.ServiceContext, form *forms.ApproveTaskForm) (interface{
 		return nil, e.New(e.TaskApproveNotPending, http.StatusBadRequest)
 	}
 
-	// 更新审批状态
-	step.ApproverId = c.UserId
+	policy, err := services.GetEnvApprovalPolicy(c.DB(), task.ProjectId, task.EnvId)
+	if err != nil {
+		c.Logger().Errorf("error get approval policy, err %s", err)
+		return nil, err
+	}
+	if err := checkApprover(c, task, policy, form.Action); err != nil {
+		return nil, err
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// 锁定步骤后重新检查状态，避免并发审批时重复变更步骤状态
+	if err := services.LockTaskStep(tx, step.Id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if step, err = services.GetTaskStep(tx, task.Id, task.CurrStep); err != nil {
+		_ = tx.Rollback()
+		return nil, e.AutoNew(err, e.DBError)
+	}
+	if step.Status != models.TaskStepApproving || step.ApproverId != "" {
+		_ = tx.Rollback()
+		return nil, e.New(e.TaskApproveNotPending, http.StatusBadRequest)
+	}
+
+	if _, err := services.CreateTaskApproval(tx, models.TaskApproval{
+		OrgId:     task.OrgId,
+		ProjectId: task.ProjectId,
+		EnvId:     task.EnvId,
+		TaskId:    task.Id,
+		Step:      step.Index,
+		UserId:    c.UserId,
+		Action:    form.Action,
+		Comment:   form.Comment,
+	}); err != nil {
+		_ = tx.Rollback()
+		if err.Code() == e.TaskAlreadyApproved {
+			return nil, e.New(err.Code(), err, http.StatusBadRequest)
+		}
+		c.Logger().Errorf("error create task approval, err %s", err)
+		return nil, err
+	}
+
+	// 任意一个审批人驳回即驳回步骤，审批通过人数满足策略时步骤才通过审批
+	status := models.TaskApprovalStatus{Required: policy.MinApprovers}
 	switch form.Action {
 	case forms.TaskActionApproved:
-		err = services.ApproveTaskStep(c.DB(), task.Id, step.Index, c.UserId)
+		if status.Approved, err = services.CountTaskStepApprovals(tx, task.Id, step.Index); err != nil {
+			break
+		}
+		if status.Approved >= int64(policy.MinApprovers) {
+			status.Satisfied = true
+			err = services.ApproveTaskStep(tx, task.Id, step.Index, c.UserId)
+		}
 	case forms.TaskActionRejected:
-		err = services.RejectTaskStep(c.DB(), task.Id, step.Index, c.UserId)
+		err = services.RejectTaskStep(tx, task.Id, step.Index, c.UserId)
 	}
 	if err != nil {
+		_ = tx.Rollback()
 		c.Logger().Errorf("error approve task, err %s", err)
 		return nil, err
 	}
 
-	return nil, nil
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error commit approve task, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+
+	operationType := models.OperationApprove
+	if form.Action == forms.TaskActionRejected {
+		operationType = models.OperationReject
+	}
+	recordAuditLog(c, "tasks", task.Id, operationType, fmt.Sprintf("%s task step %d", form.Action, step.Index),
+		map[string]interface{}{"status": task.Status, "step": step.Index},
+		map[string]interface{}{"action": form.Action, "approverId": c.UserId, "comment": form.Comment,
+			"approved": status.Approved, "required": status.Required})
+	return status, nil
+}
+
+// checkApprover 检查当前用户是否满足审批策略的审批人要求
+func checkApprover(c *ctx.ServiceContext, task *models.Task, policy *models.ApprovalPolicy, action string) e.Error {
+	if action == forms.TaskActionApproved && policy.DenySelfApproval && task.CreatorId == c.UserId {
+		return e.New(e.TaskSelfApproveDenied, http.StatusForbidden)
+	}
+	if policy.RequiredTeamId != "" {
+		isMember, err := services.IsTeamUser(c.DB(), policy.RequiredTeamId, c.UserId)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return e.New(e.TaskApproverNotInTeam, http.StatusForbidden)
+		}
+	}
+	return nil
+}
+
+// SearchTaskApproval 查询任务的审批记录
+func SearchTaskApproval(c *ctx.ServiceContext, form *forms.SearchTaskApprovalForm) (interface{}, e.Error) {
+	taskQuery := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	if _, err := services.GetTask(taskQuery, form.Id); err != nil {
+		if err.Code() == e.TaskNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+
+	query := services.QueryTaskApprovals(c.DB(), form.Id)
+	if form.SortField() == "" {
+		query = query.Order(fmt.Sprintf("%s.created_at", models.TaskApproval{}.TableName()))
+	}
+	p := page.New(form.CurrentPage(), form.PageSize(), form.Order(query))
+	approvals := make([]*models.TaskApprovalResp, 0)
+	if err := p.Scan(&approvals); err != nil {
+		c.Logger().Errorf("error search task approval, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     approvals,
+	}, nil
 }
 
 func FollowTaskLog(c *ctx.GinRequest, form forms.DetailTaskForm) e.Error {
@@ -256,7 +374,19 @@ func TaskOutput(c *ctx.ServiceContext, form forms.DetailTaskForm) (interface{},
 		return nil, e.New(e.DBError, err)
 	}
 
-	return task.Result.Outputs, nil
+	// 敏感输出的值只返回给管理员，查看时记录审计日志
+	reveal := canRevealSensitive(c, task.ProjectId)
+	outputs, sensitiveOutputs, maskErr := services.MaskTaskOutputs(task.Result.Outputs, reveal)
+	if maskErr != nil {
+		c.Logger().Errorf("error mask task outputs, err %s", maskErr)
+		return nil, e.New(e.InternalError, maskErr)
+	}
+	if reveal && len(sensitiveOutputs) > 0 {
+		recordAuditLog(c, "tasks", task.Id, models.OperationReveal, "view sensitive task outputs",
+			nil, map[string]interface{}{"outputs": sensitiveOutputs})
+	}
+
+	return outputs, nil
 }
 
 // SearchTaskResources 查询环境资源列表
@@ -305,3 +435,90 @@ func SearchTaskResources(c *ctx.ServiceContext, form *forms.SearchTaskResourceFo
 		List:     rs,
 	}, nil
 }
+
+type taskPlanChange struct {
+	Address      string   `json:"address"`
+	Type         string   `json:"type"`
+	Name         string   `json:"name"`
+	Actions      []string `json:"actions"`      // no-op, create, read, update, delete
+	ChangedAttrs []string `json:"changedAttrs"` // 修改的属性名称(只包含 update 的资源)
+}
+
+type taskPlanResp struct {
+	ResAdded     int              `json:"resAdded"`
+	ResChanged   int              `json:"resChanged"`
+	ResDestroyed int              `json:"resDestroyed"`
+	Changes      []taskPlanChange `json:"changes"` // 有变更的资源列表，plan 步骤未完成时为空
+
+	VariableChanges *variableChangesResp `json:"variableChanges"` // 与环境最后一次成功部署相比变化的变量
+}
+
+// planChangedAttrs 比较资源变更前后的属性，返回有变化的属性名称
+func planChangedAttrs(change services.TfPlanResourceChange) []string {
+	before, ok1 := change.Before.(map[string]interface{})
+	after, ok2 := change.After.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return nil
+	}
+	attrs := make([]string, 0)
+	for k, v := range after {
+		if !reflect.DeepEqual(before[k], v) {
+			attrs = append(attrs, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			attrs = append(attrs, k)
+		}
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// TaskPlan 查询任务的执行计划(与当前 state 的差异)及变量的变化，用于在审批前确认资源变更
+func TaskPlan(c *ctx.ServiceContext, form *forms.DetailTaskForm) (*taskPlanResp, e.Error) {
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	taskQuery := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	task, err := services.GetTask(taskQuery, form.Id)
+	if err != nil {
+		if err.Code() == e.TaskNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, e.New(e.DBError, err, http.StatusInternalServerError)
+	}
+
+	plan, err := services.GetTaskPlan(task)
+	if err != nil {
+		c.Logger().Errorf("error get task plan, err %s", err)
+		return nil, err
+	}
+	resp := &taskPlanResp{Changes: make([]taskPlanChange, 0)}
+	if resp.VariableChanges, err = variableChangesSinceLastApply(c.DB(), task.EnvId, &task.CreatedAt, task.Variables); err != nil {
+		c.Logger().Errorf("error get task variable changes, err %s", err)
+		return nil, err
+	}
+	if plan == nil {
+		return resp, nil
+	}
+
+	resp.ResAdded, resp.ResChanged, resp.ResDestroyed = services.CountPlanChanges(plan.ResourceChanges)
+	for _, r := range plan.ResourceChanges {
+		if utils.SliceEqualStr(r.Change.Actions, []string{"no-op"}) {
+			continue
+		}
+		change := taskPlanChange{
+			Address: r.Address,
+			Type:    r.Type,
+			Name:    r.Name,
+			Actions: r.Change.Actions,
+		}
+		if utils.SliceEqualStr(r.Change.Actions, []string{"update"}) {
+			change.ChangedAttrs = planChangedAttrs(r.Change)
+		}
+		resp.Changes = append(resp.Changes, change)
+	}
+	return resp, nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"cloudiac/utils"
+	"fmt"
+	"net/http"
+)
+
+// taskRetryFlow 生成重试任务的执行流程，从失败的步骤开始执行。
+// apply、destroy 步骤失败后原执行计划可能已过期，需要从 plan 步骤开始；不能复用原工作目录时需要先执行 init
+func taskRetryFlow(steps []*models.TaskStep, failed *models.TaskStep, reuseWorkspace bool) (models.TaskFlow, bool) {
+	start := 0
+	for i, s := range steps {
+		if s.Index == failed.Index {
+			start = i
+			break
+		}
+	}
+	if utils.StrInArray(failed.Type, models.TaskStepApply, models.TaskStepDestroy) {
+		for i := start - 1; i >= 0; i-- {
+			if steps[i].Type == models.TaskStepPlan {
+				start = i
+				break
+			}
+		}
+	}
+
+	bodies := make([]models.TaskStepBody, 0, len(steps)-start+1)
+	if start == 0 {
+		reuseWorkspace = false
+	} else if !reuseWorkspace && steps[0].Type == models.TaskStepInit {
+		bodies = append(bodies, steps[0].TaskStepBody)
+	}
+	for _, s := range steps[start:] {
+		bodies = append(bodies, s.TaskStepBody)
+	}
+	return models.TaskFlow{Steps: bodies}, reuseWorkspace
+}
+
+// RetryTask 从失败的步骤开始重新执行任务，新任务使用原任务的 commit 和变量
+func RetryTask(c *ctx.ServiceContext, form *forms.RetryTaskForm) (*models.Task, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("retry task %s", form.Id))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	taskQuery := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	task, err := services.GetTask(taskQuery, form.Id)
+	if err != nil {
+		if err.Code() == e.TaskNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, e.New(e.DBError, err, http.StatusInternalServerError)
+	}
+	if task.Status != models.TaskFailed {
+		return nil, e.New(e.TaskNotRetryable, http.StatusBadRequest)
+	}
+
+	steps, er := services.GetTaskSteps(c.DB(), task.Id)
+	if er != nil {
+		return nil, e.New(e.DBError, er)
+	}
+	var failed *models.TaskStep
+	for _, s := range steps {
+		if utils.StrInArray(s.Status, models.TaskStepFailed, models.TaskStepTimeout) {
+			failed = s
+			break
+		}
+	}
+	if failed == nil {
+		return nil, e.New(e.TaskNotRetryable, http.StatusBadRequest)
+	}
+
+	reuse := false
+	if failed.Index > 0 {
+		// runner 上的工作目录可能已被清理，查询失败时重新执行 init
+		if reuse, er = services.RunnerTaskWorkspaceExists(task); er != nil {
+			c.Logger().Warnf("check task workspace error: %v", er)
+		}
+	}
+	flow, reuse := taskRetryFlow(steps, failed, reuse)
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	env, tpl, err := getDeployableEnv(c, tx, task.EnvId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	// 环境已执行了新的任务时，重试旧任务可能覆盖新的变更
+	if task.IsEffectTask() && env.LastTaskId != task.Id {
+		_ = tx.Rollback()
+		return nil, e.New(e.TaskRetryOutdated, http.StatusBadRequest)
+	}
+
+	newTask, err := services.CreateTask(tx, tpl, env, models.Task{
+		Name:        task.Name,
+		Type:        task.Type,
+		Flow:        flow,
+		Targets:     task.Targets,
+		CreatorId:   c.UserId,
+		KeyId:       task.KeyId,
+		RunnerId:    task.RunnerId,
+		Variables:   task.Variables,
+		StepTimeout: task.StepTimeout,
+		AutoApprove: task.AutoApprove,
+		Revision:    task.Revision,
+		CommitId:    task.CommitId,
+		RetryOf:     task.Id,
+		Extra:       models.TaskExtra{ReuseWorkspace: reuse},
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error creating task, err %s", err)
+		return nil, e.New(err.Code(), err, createTaskErrStatus(err))
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error commit task, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+
+	info := taskAuditInfo(newTask)
+	info["retryOf"] = task.Id
+	info["fromStep"] = failed.Index
+	recordAuditLog(c, "tasks", newTask.Id, models.OperationCreate,
+		fmt.Sprintf("retry task %s of env %s", task.Id, env.Name), nil, info)
+
+	newTask.HideSensitiveVariable()
+	return newTask, nil
+}
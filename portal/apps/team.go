@@ -0,0 +1,281 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/page"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"fmt"
+	"net/http"
+)
+
+// checkOrgUsers 检查用户是否都属于当前组织
+func checkOrgUsers(c *ctx.ServiceContext, userIds []models.Id) e.Error {
+	for _, userId := range userIds {
+		if !services.UserHasOrgRole(userId, c.OrgId, "") {
+			return e.New(e.BadParam, fmt.Errorf("invalid user %s", userId), http.StatusBadRequest)
+		}
+	}
+	return nil
+}
+
+// CreateTeam 创建团队
+func CreateTeam(c *ctx.ServiceContext, form *forms.CreateTeamForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("create team %s", form.Name))
+	if err := checkOrgUsers(c, form.UserIds); err != nil {
+		return nil, err
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	team, err := services.CreateTeam(tx, models.Team{
+		OrgId:       c.OrgId,
+		Name:        form.Name,
+		Description: form.Description,
+		CreatorId:   c.UserId,
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		if err.Code() == e.TeamAlreadyExists {
+			return nil, e.New(err.Code(), err, http.StatusBadRequest)
+		}
+		c.Logger().Errorf("error create team, err %s", err)
+		return nil, err
+	}
+
+	if err := services.AddTeamUsers(tx, team.Id, form.UserIds); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error add team users, err %s", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error commit create team, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	return team, nil
+}
+
+// SearchTeam 查询组织下的团队
+func SearchTeam(c *ctx.ServiceContext, form *forms.SearchTeamForm) (interface{}, e.Error) {
+	teamTable := models.Team{}.TableName()
+	query := services.QueryTeam(services.QueryWithOrgId(c.DB(), c.OrgId, teamTable))
+	if form.Q != "" {
+		query = query.WhereLike(fmt.Sprintf("%s.name", teamTable), form.Q)
+	}
+	if form.SortField() == "" {
+		query = query.Order(fmt.Sprintf("%s.created_at DESC", teamTable))
+	}
+
+	query = query.Joins(fmt.Sprintf("left join %s as u on u.id = %s.creator_id", models.User{}.TableName(), teamTable)).
+		LazySelectAppend(fmt.Sprintf("%s.*", teamTable), "u.name as creator",
+			fmt.Sprintf("(select count(*) from %s where team_id = %s.id) as user_count",
+				models.TeamUser{}.TableName(), teamTable))
+
+	p := page.New(form.CurrentPage(), form.PageSize(), form.Order(query))
+	teams := make([]*models.TeamResp, 0)
+	if err := p.Scan(&teams); err != nil {
+		c.Logger().Errorf("error search team, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     teams,
+	}, nil
+}
+
+// TeamDetail 团队详情
+func TeamDetail(c *ctx.ServiceContext, form *forms.DetailTeamForm) (interface{}, e.Error) {
+	team, err := services.GetTeamById(services.QueryWithOrgId(c.DB(), c.OrgId), form.Id)
+	if err != nil {
+		if err.Code() == e.TeamNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		c.Logger().Errorf("error get team, err %s", err)
+		return nil, err
+	}
+	return team, nil
+}
+
+// UpdateTeam 修改团队信息
+func UpdateTeam(c *ctx.ServiceContext, form *forms.UpdateTeamForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("update team %s", form.Id))
+	attrs := models.Attrs{}
+	if form.HasKey("name") {
+		attrs["name"] = form.Name
+	}
+	if form.HasKey("description") {
+		attrs["description"] = form.Description
+	}
+
+	team, err := services.UpdateTeam(services.QueryWithOrgId(c.DB(), c.OrgId), form.Id, attrs)
+	if err != nil && (err.Code() == e.TeamAliasDuplicate || err.Code() == e.TeamNotExists) {
+		return nil, e.New(err.Code(), err, http.StatusBadRequest)
+	} else if err != nil {
+		c.Logger().Errorf("error update team, err %s", err)
+		return nil, err
+	}
+	return team, nil
+}
+
+// DeleteTeam 删除团队，团队成员将失去通过该团队获得的项目权限
+func DeleteTeam(c *ctx.ServiceContext, form *forms.DeleteTeamForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("delete team %s", form.Id))
+	if _, err := services.GetTeamById(services.QueryWithOrgId(c.DB(), c.OrgId), form.Id); err != nil {
+		if err.Code() == e.TeamNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err := services.DeleteTeam(tx, form.Id); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error delete team, err %s", err)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, e.New(e.DBError, err)
+	}
+	return nil, nil
+}
+
+// SearchTeamUser 查询团队成员
+func SearchTeamUser(c *ctx.ServiceContext, form *forms.SearchTeamUserForm) (interface{}, e.Error) {
+	if _, err := services.GetTeamById(services.QueryWithOrgId(c.DB(), c.OrgId), form.Id); err != nil {
+		if err.Code() == e.TeamNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+
+	userTable := models.User{}.TableName()
+	query := services.QueryUser(c.DB()).
+		Joins(fmt.Sprintf("join %s as tu on tu.user_id = %s.id", models.TeamUser{}.TableName(), userTable)).
+		Where("tu.team_id = ?", form.Id).
+		LazySelectAppend(fmt.Sprintf("%s.*", userTable))
+	if form.SortField() == "" {
+		query = query.Order(fmt.Sprintf("%s.created_at DESC", userTable))
+	}
+
+	return getPage(query, form, models.User{})
+}
+
+// AddTeamUser 添加团队成员
+func AddTeamUser(c *ctx.ServiceContext, form *forms.AddTeamUserForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("add users to team %s", form.Id))
+	if _, err := services.GetTeamById(services.QueryWithOrgId(c.DB(), c.OrgId), form.Id); err != nil {
+		if err.Code() == e.TeamNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+	if err := checkOrgUsers(c, form.UserIds); err != nil {
+		return nil, err
+	}
+
+	if err := services.AddTeamUsers(c.DB(), form.Id, form.UserIds); err != nil {
+		c.Logger().Errorf("error add team users, err %s", err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// DeleteTeamUser 移除团队成员
+func DeleteTeamUser(c *ctx.ServiceContext, form *forms.DeleteTeamUserForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("remove user %s from team %s", form.UserId, form.Id))
+	if _, err := services.GetTeamById(services.QueryWithOrgId(c.DB(), c.OrgId), form.Id); err != nil {
+		if err.Code() == e.TeamNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+	return nil, services.DeleteTeamUser(c.DB(), form.Id, form.UserId)
+}
+
+// CreateProjectTeam 授权团队访问项目，团队成员获得对应的项目角色
+func CreateProjectTeam(c *ctx.ServiceContext, form *forms.CreateProjectTeamForm) (interface{}, e.Error) {
+	if _, ok := consts.ProjectRolePriority[form.Role]; !ok {
+		return nil, e.New(e.InvalidRoleName, http.StatusBadRequest)
+	}
+	if _, err := services.GetTeamById(services.QueryWithOrgId(c.DB(), c.OrgId), form.TeamId); err != nil {
+		if err.Code() == e.TeamNotExists {
+			return nil, e.New(err.Code(), err, http.StatusBadRequest)
+		}
+		return nil, err
+	}
+
+	tp, err := services.CreateTeamProject(c.DB(), models.TeamProject{
+		TeamId:    form.TeamId,
+		ProjectId: c.ProjectId,
+		Role:      form.Role,
+	})
+	if err != nil && err.Code() == e.TeamProjectAlreadyExists {
+		return nil, e.New(err.Code(), err, http.StatusBadRequest)
+	} else if err != nil {
+		c.Logger().Errorf("error create project team, err %s", err)
+		return nil, err
+	}
+	return tp, nil
+}
+
+// SearchProjectTeam 查询项目下授权的团队
+func SearchProjectTeam(c *ctx.ServiceContext, form *forms.SearchProjectTeamForm) (interface{}, e.Error) {
+	query := services.QueryProjectTeams(c.DB(), c.ProjectId)
+	p := page.New(form.CurrentPage(), form.PageSize(), form.Order(query))
+	teams := make([]*models.TeamProjectResp, 0)
+	if err := p.Scan(&teams); err != nil {
+		c.Logger().Errorf("error search project team, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     teams,
+	}, nil
+}
+
+// UpdateProjectTeam 修改团队在项目下的角色
+func UpdateProjectTeam(c *ctx.ServiceContext, form *forms.UpdateProjectTeamForm) (interface{}, e.Error) {
+	attrs := models.Attrs{}
+	if form.HasKey("role") {
+		if _, ok := consts.ProjectRolePriority[form.Role]; !ok {
+			return nil, e.New(e.InvalidRoleName, http.StatusBadRequest)
+		}
+		attrs["role"] = form.Role
+	}
+
+	err := services.UpdateTeamProject(c.DB().
+		Where("id = ?", form.Id).
+		Where("project_id = ?", c.ProjectId), attrs)
+	if err != nil {
+		c.Logger().Errorf("error update project team, err %s", err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// DeleteProjectTeam 取消团队的项目授权
+func DeleteProjectTeam(c *ctx.ServiceContext, form *forms.DeleteProjectTeamForm) (interface{}, e.Error) {
+	return nil, services.DeleteTeamProject(c.DB().Where("project_id = ?", c.ProjectId), form.Id)
+}
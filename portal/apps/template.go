@@ -27,6 +27,8 @@ type SearchTemplateResp struct {
 	RepoId            string      `json:"repoId"`
 	VcsId             string      `json:"vcsId"`
 	RepoAddr          string      `json:"repoAddr"`
+
+	Labels models.Labels `json:"labels"` // 标签
 }
 
 func getRepoAddr(vcsId models.Id, query *db.Session, repoId string) (string, error) {
@@ -52,6 +54,10 @@ func getRepoAddr(vcsId models.Id, query *db.Session, repoId string) (string, err
 func CreateTemplate(c *ctx.ServiceContext, form *forms.CreateTemplateForm) (*models.Template, e.Error) {
 	c.AddLogField("action", fmt.Sprintf("create template %s", form.Name))
 
+	if err := form.Labels.Validate(); err != nil {
+		return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+	}
+
 	repoAddr, er := getRepoAddr(form.VcsId, c.DB(), form.RepoId)
 	if er != nil {
 		return nil, e.New(e.DBError, fmt.Errorf("get repo failed: %v", er))
@@ -68,6 +74,7 @@ func CreateTemplate(c *ctx.ServiceContext, form *forms.CreateTemplateForm) (*mod
 		Name:         form.Name,
 		OrgId:        c.OrgId,
 		Description:  form.Description,
+		Labels:       form.Labels,
 		VcsId:        form.VcsId,
 		RepoId:       form.RepoId,
 		RepoAddr:     repoAddr,
@@ -95,7 +102,7 @@ func CreateTemplate(c *ctx.ServiceContext, form *forms.CreateTemplateForm) (*mod
 	}
 
 	// 创建变量
-	if err := services.OperationVariables(tx, c.OrgId, c.ProjectId,
+	if err := services.OperationVariables(tx, c.UserId, c.OrgId, c.ProjectId,
 		template.Id, "", form.Variables, form.DeleteVariablesId); err != nil {
 		_ = tx.Rollback()
 		c.Logger().Errorf("error operation variables, err %s", err)
@@ -131,6 +138,13 @@ func UpdateTemplate(c *ctx.ServiceContext, form *forms.UpdateTemplateForm) (*mod
 	if form.HasKey("description") {
 		attrs["description"] = form.Description
 	}
+
+	if form.HasKey("labels") {
+		if err := form.Labels.Validate(); err != nil {
+			return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+		}
+		attrs["labels"] = form.Labels
+	}
 	if form.HasKey("playbook") {
 		attrs["playbook"] = form.Playbook
 	}
@@ -180,7 +194,7 @@ func UpdateTemplate(c *ctx.ServiceContext, form *forms.UpdateTemplateForm) (*mod
 		}
 	}
 	if form.HasKey("variables") || form.HasKey("deleteVariablesId") {
-		if err := services.OperationVariables(tx, c.OrgId, c.ProjectId,
+		if err := services.OperationVariables(tx, c.UserId, c.OrgId, c.ProjectId,
 			form.Id, "", form.Variables, form.DeleteVariablesId); err != nil {
 			_ = tx.Rollback()
 			c.Logger().Errorf("error operation variables, err %s", err)
@@ -288,6 +302,13 @@ func SearchTemplate(c *ctx.ServiceContext, form *forms.SearchTemplateForm) (tpl
 		}
 	}
 	query := services.QueryTemplateByOrgId(c.DB(), form.Q, c.OrgId, tplIdList)
+	if form.Labels != "" {
+		selector, err := models.ParseLabelSelector(form.Labels)
+		if err != nil {
+			return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+		}
+		query = services.WhereLabelSelector(query, "iac_template.labels", selector)
+	}
 	p := page.New(form.CurrentPage(), form.PageSize(), query)
 	templates := make([]*SearchTemplateResp, 0)
 	if err := p.Scan(&templates); err != nil {
@@ -44,6 +44,10 @@ func CreateToken(c *ctx.ServiceContext, form *forms.CreateTokenForm) (interface{
 		er        error
 	)
 
+	if c.ApiToken != nil {
+		return nil, e.New(e.PermissionDeny, fmt.Errorf("token can not be created by token"), http.StatusForbidden)
+	}
+
 	tokenStr, _ := utils.GetUUID()
 	if form.ExpiredAt != "" {
 		expiredAt, er = models.Time{}.Parse(form.ExpiredAt)
@@ -52,6 +56,20 @@ func CreateToken(c *ctx.ServiceContext, form *forms.CreateTokenForm) (interface{
 		}
 	}
 
+	if form.Type == consts.TokenApi {
+		if err := checkTokenScopes(c, form.Scopes); err != nil {
+			return nil, err
+		}
+		if form.ProjectRole != "" {
+			if _, ok := consts.ProjectRolePriority[form.ProjectRole]; !ok {
+				return nil, e.New(e.InvalidRoleName, http.StatusBadRequest)
+			}
+		}
+	} else {
+		form.ProjectRole = ""
+		form.Scopes = models.TokenScopes{}
+	}
+
 	token, err := services.CreateToken(c.DB().Debug(), models.Token{
 		Key:         string(tokenStr),
 		Type:        form.Type,
@@ -62,6 +80,8 @@ func CreateToken(c *ctx.ServiceContext, form *forms.CreateTokenForm) (interface{
 		CreatorId:   c.UserId,
 		EnvId:       form.EnvId,
 		Action:      form.Action,
+		ProjectRole: form.ProjectRole,
+		Scopes:      form.Scopes,
 	})
 	if err != nil && err.Code() == e.TokenAlreadyExists {
 		return nil, e.New(err.Code(), err, http.StatusBadRequest)
@@ -107,7 +127,88 @@ func DeleteToken(c *ctx.ServiceContext, form *forms.DeleteTokenForm) (result int
 	return
 }
 
+// checkTokenScopes 检查 token 授权范围内的项目和环境是否属于当前组织
+func checkTokenScopes(c *ctx.ServiceContext, scopes models.TokenScopes) e.Error {
+	if err := scopes.Validate(); err != nil {
+		return e.New(e.BadParam, err, http.StatusBadRequest)
+	}
+	for _, projectId := range scopes.Projects {
+		if project, err := services.GetProjectsById(c.DB(), projectId); err != nil || project.OrgId != c.OrgId {
+			return e.New(e.BadParam, fmt.Errorf("invalid project %s", projectId), http.StatusBadRequest)
+		}
+	}
+	for _, envId := range scopes.Envs {
+		if env, err := services.GetEnvById(c.DB(), envId); err != nil || env.OrgId != c.OrgId {
+			return e.New(e.BadParam, fmt.Errorf("invalid env %s", envId), http.StatusBadRequest)
+		}
+	}
+	return nil
+}
+
+// SearchPersonalToken 查询当前用户的个人访问令牌
+func SearchPersonalToken(c *ctx.ServiceContext, form *forms.SearchPersonalTokenForm) (interface{}, e.Error) {
+	query := services.QueryToken(c.DB(), consts.TokenPersonal).
+		Where("user_id = ?", c.UserId).
+		Order("created_at DESC")
+	rs, err := getPage(query, form, models.Token{})
+	if err != nil {
+		c.Logger().Errorf("error get page, err %s", err)
+		return nil, err
+	}
+	return rs, nil
+}
+
+// CreatePersonalToken 创建个人访问令牌，令牌以当前用户身份访问，权限受 scopes 限制
+func CreatePersonalToken(c *ctx.ServiceContext, form *forms.CreatePersonalTokenForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("create personal token for user %s", c.UserId))
+	if c.ApiToken != nil {
+		return nil, e.New(e.PermissionDeny, fmt.Errorf("token can not be created by token"), http.StatusForbidden)
+	}
+	if err := form.Scopes.Validate(); err != nil {
+		return nil, e.New(e.BadParam, err, http.StatusBadRequest)
+	}
+
+	var expiredAt models.Time
+	if form.ExpiredAt != "" {
+		var er error
+		if expiredAt, er = (models.Time{}).Parse(form.ExpiredAt); er != nil {
+			return nil, e.New(e.BadParam, http.StatusBadRequest, er)
+		}
+	}
+
+	tokenStr, _ := utils.GetUUID()
+	token, err := services.CreateToken(c.DB(), models.Token{
+		Key:         string(tokenStr),
+		Type:        consts.TokenPersonal,
+		UserId:      c.UserId,
+		ExpiredAt:   expiredAt,
+		Description: form.Description,
+		CreatorId:   c.UserId,
+		Scopes:      form.Scopes,
+	})
+	if err != nil {
+		c.Logger().Errorf("error creating personal token, err %s", err)
+		return nil, err
+	}
+	return token, nil
+}
+
+// DeletePersonalToken 删除当前用户的个人访问令牌
+func DeletePersonalToken(c *ctx.ServiceContext, form *forms.DeletePersonalTokenForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("delete personal token %s", form.Id))
+	query := services.QueryToken(c.DB(), consts.TokenPersonal).Where("user_id = ?", c.UserId)
+	if exists, err := query.Where("id = ?", form.Id).Exists(); err != nil {
+		return nil, e.New(e.DBError, err)
+	} else if !exists {
+		return nil, e.New(e.TokenNotExists, http.StatusNotFound)
+	}
+	return nil, services.DeleteToken(c.DB(), form.Id)
+}
+
 func DetailTriggerToken(c *ctx.ServiceContext, form *forms.DetailTriggerTokenForm) (result interface{}, re e.Error) {
+	if err := checkTokenEnvScope(c, form.EnvId); err != nil {
+		return nil, err
+	}
 	token, err := services.DetailTriggerToken(c.DB(), c.OrgId, form.EnvId, form.Action)
 	if err != nil {
 		// 如果不存在直接返回
@@ -180,12 +281,12 @@ func ApiTriggerHandler(c *ctx.ServiceContext, form forms.ApiTriggerHandler) (int
 		AutoApprove: env.AutoApproval,
 	}
 
-	_, err = services.CreateTask(tx, tpl, env, task)
+	newTask, err := services.CreateTask(tx, tpl, env, task)
 
 	if err != nil {
 		_ = tx.Rollback()
 		c.Logger().Errorf("error creating task, err %s", err)
-		return nil, e.New(err.Code(), err, http.StatusInternalServerError)
+		return nil, e.New(err.Code(), err, createTaskErrStatus(err))
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -194,5 +295,14 @@ func ApiTriggerHandler(c *ctx.ServiceContext, form forms.ApiTriggerHandler) (int
 		return nil, e.New(e.DBError, err)
 	}
 
+	// 触发器请求没有登陆用户，审计日志以触发器的创建者作为操作者，并以触发器 token 标识操作来源
+	c.OrgId, c.ProjectId = env.OrgId, env.ProjectId
+	c.UserId = token.CreatorId
+	if c.UserId == "" {
+		c.UserId = consts.SysUserId
+	}
+	c.Username = fmt.Sprintf("trigger:%s", token.Id)
+	recordAuditLog(c, "envs", env.Id, taskOperationType(newTask.Type),
+		fmt.Sprintf("trigger %s env %s", newTask.Type, env.Name), nil, taskAuditInfo(newTask))
 	return nil, nil
 }
@@ -0,0 +1,219 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"cloudiac/utils"
+	"fmt"
+	"net/http"
+)
+
+type variableScope struct {
+	ProjectId models.Id
+	TplId     models.Id
+	EnvId     models.Id
+}
+
+// getVariableScope 检查变量作用域对应的项目、模板或环境，返回保存变量时使用的 id
+func getVariableScope(c *ctx.ServiceContext, query *db.Session, scope string, tplId, envId models.Id) (*variableScope, e.Error) {
+	switch scope {
+	case consts.ScopeOrg:
+		return &variableScope{}, nil
+	case consts.ScopeProject:
+		if c.ProjectId == "" {
+			return nil, e.New(e.BadRequest, fmt.Errorf("project id is required"), http.StatusBadRequest)
+		}
+		return &variableScope{ProjectId: c.ProjectId}, nil
+	case consts.ScopeTemplate:
+		tpl, err := services.GetTemplateById(services.QueryWithOrgId(query, c.OrgId), tplId)
+		if err != nil {
+			if err.Code() == e.TemplateNotExists {
+				return nil, e.New(err.Code(), err, http.StatusBadRequest)
+			}
+			return nil, err
+		}
+		return &variableScope{TplId: tpl.Id}, nil
+	case consts.ScopeEnv:
+		if err := checkTokenEnvScope(c, envId); err != nil {
+			return nil, err
+		}
+		envQuery := services.QueryWithProjectId(services.QueryWithOrgId(query, c.OrgId), c.ProjectId)
+		env, err := services.GetEnvById(envQuery, envId)
+		if err != nil {
+			if err.Code() == e.EnvNotExists {
+				return nil, e.New(err.Code(), err, http.StatusBadRequest)
+			}
+			return nil, err
+		}
+		return &variableScope{ProjectId: env.ProjectId, TplId: env.TplId, EnvId: env.Id}, nil
+	default:
+		return nil, e.New(e.BadParam, fmt.Errorf("invalid scope '%s'", scope), http.StatusBadRequest)
+	}
+}
+
+type importVariableResp struct {
+	DryRun bool                          `json:"dryRun"`
+	Counts map[string]int                `json:"counts"` // 各处理方式的变量数量
+	Items  []services.VariableImportItem `json:"items"`  // 每个变量的处理结果
+}
+
+// ImportVariable 从 tfvars、tfvars.json 或 ansible vars yaml 文件导入变量到指定作用域
+func ImportVariable(c *ctx.ServiceContext, form *forms.ImportVariableForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("import %s variables", form.Scope))
+
+	typ := utils.FirstValueStr(form.Type, services.VariableFileDefaultType(form.Format))
+	entries, err := services.ParseVariableFile(form.Format, []byte(form.Content))
+	if err != nil {
+		return nil, err
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	scope, err := getVariableScope(c, tx, form.Scope, form.TplId, form.EnvId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	existing := make([]models.Variable, 0)
+	if err := services.QueryScopeVariables(tx, form.Scope, c.OrgId, scope.ProjectId, scope.TplId, scope.EnvId).
+		Where("type = ?", typ).Find(&existing); err != nil {
+		_ = tx.Rollback()
+		return nil, e.New(e.DBError, err)
+	}
+	existingM := make(map[models.Id]models.Variable, len(existing))
+	for _, v := range existing {
+		existingM[v.Id] = v
+	}
+
+	items := services.PlanVariableImport(typ, entries, existing, form.Sensitive, form.Overwrite)
+	resp := importVariableResp{DryRun: form.DryRun, Counts: make(map[string]int), Items: items}
+	vars := make([]forms.Variables, 0)
+	for i, item := range items {
+		resp.Counts[item.Action] += 1
+
+		v := forms.Variables{
+			Scope:     form.Scope,
+			Type:      typ,
+			Name:      item.Name,
+			Value:     entries[i].Value,
+			Sensitive: form.Sensitive,
+		}
+		switch item.Action {
+		case services.VariableImportCreate:
+		case services.VariableImportOverwrite:
+			// 覆盖时保留原变量的描述等信息，原来为敏感变量的依然作为敏感变量保存
+			old := existingM[item.Id]
+			v.Id = old.Id
+			v.Sensitive = old.Sensitive || form.Sensitive
+			v.Description = old.Description
+			v.Path = old.Path
+		default:
+			continue
+		}
+		vars = append(vars, v)
+	}
+
+	if form.DryRun || len(vars) == 0 {
+		_ = tx.Rollback()
+		return resp, nil
+	}
+
+	before, after := variableAuditChanges(tx, vars, nil)
+	if err := services.OperationVariables(tx, c.UserId, c.OrgId, scope.ProjectId, scope.TplId, scope.EnvId, vars, nil); err != nil {
+		_ = tx.Rollback()
+		c.Logger().Errorf("error import variables, err %s", err)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, e.New(e.DBError, err)
+	}
+
+	resId := utils.FirstValueStr(string(scope.EnvId), string(scope.TplId))
+	recordAuditLog(c, "variables", models.Id(resId), models.OperationUpdate,
+		fmt.Sprintf("import %s variables", form.Scope), before, after)
+	return resp, nil
+}
+
+type exportSkippedVariable struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+type exportVariableResp struct {
+	FileName string                  `json:"fileName"` // 建议的文件名
+	Content  string                  `json:"content"`  // 文件内容
+	Skipped  []exportSkippedVariable `json:"skipped"`  // 未导出的变量(隐藏的敏感变量或名称不合法的变量)
+}
+
+// ExportVariable 导出指定作用域下定义的变量，敏感变量的值只有管理员可以导出
+func ExportVariable(c *ctx.ServiceContext, form *forms.ExportVariableForm) (interface{}, e.Error) {
+	projectId := models.Id("")
+	if form.Scope == consts.ScopeProject || form.Scope == consts.ScopeEnv {
+		projectId = c.ProjectId
+	}
+	if form.IncludeSensitive && !canRevealSensitive(c, projectId) {
+		return nil, e.New(e.PermissionDeny, fmt.Errorf("export sensitive variables requires admin role"), http.StatusForbidden)
+	}
+
+	typ := utils.FirstValueStr(form.Type, services.VariableFileDefaultType(form.Format))
+	scope, err := getVariableScope(c, c.DB(), form.Scope, form.TplId, form.EnvId)
+	if err != nil {
+		return nil, err
+	}
+	vars := make([]models.Variable, 0)
+	if err := services.QueryScopeVariables(c.DB(), form.Scope, c.OrgId, scope.ProjectId, scope.TplId, scope.EnvId).
+		Where("type = ?", typ).Order("name").Find(&vars); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+
+	resp := exportVariableResp{
+		FileName: services.VariableFileName(form.Format),
+		Skipped:  make([]exportSkippedVariable, 0),
+	}
+	values := make(map[string]string, len(vars))
+	revealed := make([]string, 0)
+	for _, v := range vars {
+		switch {
+		case !services.IsValidVariableName(typ, v.Name):
+			resp.Skipped = append(resp.Skipped, exportSkippedVariable{Name: v.Name, Reason: "invalid variable name"})
+		case v.Sensitive && !form.IncludeSensitive:
+			resp.Skipped = append(resp.Skipped, exportSkippedVariable{Name: v.Name, Reason: "sensitive value redacted"})
+		case v.Sensitive:
+			value, err := utils.AesDecrypt(v.Value)
+			if err != nil {
+				return nil, e.New(e.InternalError, err)
+			}
+			values[v.Name] = value
+			revealed = append(revealed, v.Name)
+		default:
+			values[v.Name] = v.Value
+		}
+	}
+
+	content, err := services.RenderVariableFile(form.Format, values)
+	if err != nil {
+		return nil, err
+	}
+	resp.Content = string(content)
+
+	if len(revealed) > 0 {
+		resId := utils.FirstValueStr(string(scope.EnvId), string(scope.TplId))
+		recordAuditLog(c, "variables", models.Id(resId), models.OperationReveal,
+			fmt.Sprintf("export sensitive %s variables", form.Scope), nil, map[string]interface{}{"variables": revealed})
+	}
+	return resp, nil
+}
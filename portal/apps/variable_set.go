@@ -0,0 +1,267 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package apps
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/page"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/portal/services"
+	"fmt"
+	"net/http"
+)
+
+func getVariableSet(c *ctx.ServiceContext, id models.Id) (*models.VariableSet, e.Error) {
+	set, err := services.GetVariableSetById(services.QueryWithOrgId(c.DB(), c.OrgId), id)
+	if err != nil {
+		if err.Code() == e.VariableSetNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		c.Logger().Errorf("error get variable set, err %s", err)
+		return nil, err
+	}
+	return set, nil
+}
+
+// maskedVariableSet 返回隐藏了敏感变量值的变量集副本
+func maskedVariableSet(set *models.VariableSet) *models.VariableSet {
+	masked := *set
+	masked.Variables = make(models.VariableSetVariables, 0, len(set.Variables))
+	for _, v := range set.Variables {
+		masked.Variables = append(masked.Variables, maskedVariableBody(v))
+	}
+	return &masked
+}
+
+// CreateVariableSet 创建变量集
+func CreateVariableSet(c *ctx.ServiceContext, form *forms.CreateVariableSetForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("create variable set %s", form.Name))
+
+	vars, err := services.BuildVariableSetVariables(form.Variables, nil)
+	if err != nil {
+		return nil, err
+	}
+	set, err := services.CreateVariableSet(c.DB(), models.VariableSet{
+		OrgId:       c.OrgId,
+		Name:        form.Name,
+		Description: form.Description,
+		Variables:   vars,
+		CreatorId:   c.UserId,
+	})
+	if err != nil && err.Code() == e.VariableSetAlreadyExists {
+		return nil, e.New(err.Code(), err, http.StatusBadRequest)
+	} else if err != nil {
+		c.Logger().Errorf("error create variable set, err %s", err)
+		return nil, err
+	}
+
+	recordAuditLog(c, "variable-sets", set.Id, models.OperationCreate,
+		fmt.Sprintf("create variable set %s", set.Name), nil, maskedVariableSet(set))
+	set.HideSensitiveVariable()
+	return set, nil
+}
+
+// SearchVariableSet 查询组织下的变量集
+func SearchVariableSet(c *ctx.ServiceContext, form *forms.SearchVariableSetForm) (interface{}, e.Error) {
+	query := services.QueryVariableSet(services.QueryWithOrgId(c.DB(), c.OrgId))
+	if form.Q != "" {
+		qs := "%" + form.Q + "%"
+		query = query.Where("name LIKE ? OR description LIKE ?", qs, qs)
+	}
+	if form.SortField() == "" {
+		query = query.Order("created_at DESC")
+	}
+
+	p := page.New(form.CurrentPage(), form.PageSize(), form.Order(query))
+	sets := make([]*models.VariableSet, 0)
+	if err := p.Scan(&sets); err != nil {
+		c.Logger().Errorf("error search variable set, err %s", err)
+		return nil, e.New(e.DBError, err)
+	}
+	for _, set := range sets {
+		set.HideSensitiveVariable()
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     sets,
+	}, nil
+}
+
+// VariableSetDetail 变量集详情
+func VariableSetDetail(c *ctx.ServiceContext, form *forms.DetailVariableSetForm) (interface{}, e.Error) {
+	set, err := getVariableSet(c, form.Id)
+	if err != nil {
+		return nil, err
+	}
+	set.HideSensitiveVariable()
+	return set, nil
+}
+
+// UpdateVariableSet 修改变量集，环境在下次计算变量(如修改环境变量)时使用新的值
+func UpdateVariableSet(c *ctx.ServiceContext, form *forms.UpdateVariableSetForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("update variable set %s", form.Id))
+	before, err := getVariableSet(c, form.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := models.Attrs{}
+	if form.HasKey("name") {
+		attrs["name"] = form.Name
+	}
+	if form.HasKey("description") {
+		attrs["description"] = form.Description
+	}
+	if form.HasKey("variables") {
+		vars, err := services.BuildVariableSetVariables(form.Variables, before.Variables)
+		if err != nil {
+			return nil, err
+		}
+		attrs["variables"] = vars
+	}
+
+	set, err := services.UpdateVariableSet(c.DB(), form.Id, attrs)
+	if err != nil && err.Code() == e.VariableSetAlreadyExists {
+		return nil, e.New(err.Code(), err, http.StatusBadRequest)
+	} else if err != nil {
+		c.Logger().Errorf("error update variable set, err %s", err)
+		return nil, err
+	}
+
+	recordAuditLog(c, "variable-sets", set.Id, models.OperationUpdate,
+		fmt.Sprintf("update variable set %s", set.Name), maskedVariableSet(before), maskedVariableSet(set))
+	set.HideSensitiveVariable()
+	return set, nil
+}
+
+// DeleteVariableSet 删除变量集，同时解除变量集的所有关联
+func DeleteVariableSet(c *ctx.ServiceContext, form *forms.DeleteVariableSetForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("delete variable set %s", form.Id))
+	set, err := getVariableSet(c, form.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := c.Tx()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err := services.DeleteVariableSet(tx, set.Id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, e.New(e.DBError, err)
+	}
+
+	recordAuditLog(c, "variable-sets", set.Id, models.OperationDelete,
+		fmt.Sprintf("delete variable set %s", set.Name), maskedVariableSet(set), nil)
+	return nil, nil
+}
+
+// CreateVariableSetAttachment 将变量集关联到当前项目或项目下的环境，
+// 同名同类型变量优先级：环境变量 > 环境关联的变量集 > 项目关联的变量集 > 项目变量 > 模板变量 > 组织变量。
+// 只有组织管理员可以关联(见 rbac 策略)，项目管理员只能查看
+func CreateVariableSetAttachment(c *ctx.ServiceContext, form *forms.CreateVariableSetAttachmentForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("attach variable set %s", form.VariableSetId))
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+
+	set, err := services.GetVariableSetById(services.QueryWithOrgId(c.DB(), c.OrgId), form.VariableSetId)
+	if err != nil {
+		if err.Code() == e.VariableSetNotExists {
+			return nil, e.New(err.Code(), err, http.StatusBadRequest)
+		}
+		return nil, err
+	}
+	if form.EnvId != "" {
+		if _, err := getProjectEnv(c, c.DB(), form.EnvId); err != nil {
+			return nil, err
+		}
+	}
+
+	attachment, err := services.CreateVariableSetAttachment(c.DB(), models.VariableSetAttachment{
+		OrgId:         c.OrgId,
+		ProjectId:     c.ProjectId,
+		EnvId:         form.EnvId,
+		VariableSetId: set.Id,
+		CreatorId:     c.UserId,
+	})
+	if err != nil {
+		if err.Code() == e.VariableSetAttachmentExists {
+			return nil, e.New(err.Code(), err, http.StatusBadRequest)
+		}
+		c.Logger().Errorf("error create variable set attachment, err %s", err)
+		return nil, err
+	}
+
+	resType, resId := "projects", c.ProjectId
+	if attachment.EnvId != "" {
+		resType, resId = "envs", attachment.EnvId
+	}
+	recordAuditLog(c, resType, resId, models.OperationCreate,
+		fmt.Sprintf("attach variable set %s", set.Name), nil, attachment)
+	return attachment, nil
+}
+
+// SearchVariableSetAttachment 查询当前项目下的变量集关联关系
+func SearchVariableSetAttachment(c *ctx.ServiceContext, form *forms.SearchVariableSetAttachmentForm) (interface{}, e.Error) {
+	if c.OrgId == "" || c.ProjectId == "" {
+		return nil, e.New(e.BadRequest, http.StatusBadRequest)
+	}
+	if err := checkTokenEnvScope(c, form.EnvId); err != nil {
+		return nil, err
+	}
+	query := services.QueryVariableSetAttachment(c.DB()).
+		Where("iac_variable_set_attachment.org_id = ? AND iac_variable_set_attachment.project_id = ?", c.OrgId, c.ProjectId)
+	if form.EnvId != "" {
+		query = query.Where("iac_variable_set_attachment.env_id IN (?)", []models.Id{"", form.EnvId})
+	}
+	if form.VariableSetId != "" {
+		query = query.Where("iac_variable_set_attachment.variable_set_id = ?", form.VariableSetId)
+	}
+	query = query.Order("iac_variable_set_attachment.env_id, iac_variable_set_attachment.created_at")
+
+	p := page.New(form.CurrentPage(), form.PageSize(), query)
+	attachments := make([]*models.VariableSetAttachmentResp, 0)
+	if err := p.Scan(&attachments); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     attachments,
+	}, nil
+}
+
+// DeleteVariableSetAttachment 解除变量集关联
+func DeleteVariableSetAttachment(c *ctx.ServiceContext, form *forms.DeleteVariableSetAttachmentForm) (interface{}, e.Error) {
+	c.AddLogField("action", fmt.Sprintf("delete variable set attachment %s", form.Id))
+	query := services.QueryWithProjectId(services.QueryWithOrgId(c.DB(), c.OrgId), c.ProjectId)
+	attachment, err := services.GetVariableSetAttachmentById(query, form.Id)
+	if err != nil {
+		if err.Code() == e.VariableSetAttachmentNotExists {
+			return nil, e.New(err.Code(), err, http.StatusNotFound)
+		}
+		return nil, err
+	}
+	if err := services.DeleteVariableSetAttachment(c.DB(), attachment.Id); err != nil {
+		return nil, err
+	}
+
+	resType, resId := "projects", attachment.ProjectId
+	if attachment.EnvId != "" {
+		resType, resId = "envs", attachment.EnvId
+	}
+	recordAuditLog(c, resType, resId, models.OperationDelete,
+		fmt.Sprintf("detach variable set %s", attachment.VariableSetId), attachment, nil)
+	return nil, nil
+}
@@ -3,15 +3,23 @@
 package apps
 
 import (
+	"cloudiac/portal/consts"
 	"cloudiac/portal/consts/e"
 	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/libs/page"
 	"cloudiac/portal/models"
 	"cloudiac/portal/models/forms"
 	"cloudiac/portal/services"
+	"fmt"
+	"net/http"
 	"sort"
 )
 
 func BatchUpdate(c *ctx.ServiceContext, form *forms.BatchUpdateVariableForm) (interface{}, e.Error) {
+	if err := checkTokenEnvScope(c, form.EnvId); err != nil {
+		return nil, err
+	}
 	tx := c.DB().Begin().Debug()
 	defer func() {
 		if r := recover(); r != nil {
@@ -19,7 +27,8 @@ func BatchUpdate(c *ctx.ServiceContext, form *forms.BatchUpdateVariableForm) (in
 			panic(r)
 		}
 	}()
-	err := services.OperationVariables(tx, c.OrgId, c.ProjectId, form.TplId, form.EnvId, form.Variables, form.DeleteVariablesId)
+	before, after := variableAuditChanges(tx, form.Variables, form.DeleteVariablesId)
+	err := services.OperationVariables(tx, c.UserId, c.OrgId, c.ProjectId, form.TplId, form.EnvId, form.Variables, form.DeleteVariablesId)
 	if err != nil {
 		c.Logger().Errorf("error creating variable, err %s", err)
 		_ = tx.Rollback()
@@ -31,9 +40,59 @@ func BatchUpdate(c *ctx.ServiceContext, form *forms.BatchUpdateVariableForm) (in
 		return nil, e.New(e.DBError, err)
 	}
 
+	resId := form.EnvId
+	if resId == "" {
+		resId = form.TplId
+	}
+	recordAuditLog(c, "variables", resId, models.OperationUpdate, "update variables", before, after)
 	return nil, nil
 }
 
+type auditVariable struct {
+	Id models.Id `json:"id,omitempty"`
+	models.VariableBody
+}
+
+// variableAuditChanges 获取变量变更前后的数据用于记录审计日志，敏感变量的值会被隐藏
+func variableAuditChanges(query *db.Session, variables []forms.Variables, deleteIds []string) (before, after []auditVariable) {
+	ids := make([]string, 0, len(deleteIds))
+	ids = append(ids, deleteIds...)
+	for _, v := range variables {
+		if v.Id != "" {
+			ids = append(ids, string(v.Id))
+		}
+		after = append(after, auditVariable{
+			Id: v.Id,
+			VariableBody: maskedVariableBody(models.VariableBody{
+				Scope:       v.Scope,
+				Type:        v.Type,
+				Name:        v.Name,
+				Value:       v.Value,
+				Sensitive:   v.Sensitive,
+				Description: v.Description,
+				Path:        v.Path,
+			}),
+		})
+	}
+
+	if len(ids) > 0 {
+		olds := make([]models.Variable, 0)
+		if err := query.Model(models.Variable{}).Where("id IN (?)", ids).Find(&olds); err == nil {
+			for _, v := range olds {
+				before = append(before, auditVariable{Id: v.Id, VariableBody: maskedVariableBody(v.VariableBody)})
+			}
+		}
+	}
+	return before, after
+}
+
+func maskedVariableBody(v models.VariableBody) models.VariableBody {
+	if v.Sensitive {
+		v.Value = "******"
+	}
+	return v
+}
+
 type newVariable []VariableResp
 
 func (v newVariable) Len() int {
@@ -52,6 +111,9 @@ type VariableResp struct {
 }
 
 func SearchVariable(c *ctx.ServiceContext, form *forms.SearchVariableForm) (interface{}, e.Error) {
+	if err := checkTokenEnvScope(c, form.EnvId); err != nil {
+		return nil, err
+	}
 	variableM, err, scopes := services.GetValidVariables(c.DB(), form.Scope, c.OrgId, c.ProjectId, form.TplId, form.EnvId, false)
 	if err != nil {
 		return nil, err
@@ -79,3 +141,42 @@ func SearchVariable(c *ctx.ServiceContext, form *forms.SearchVariableForm) (inte
 
 	return rs, nil
 }
+
+// SearchVariableHistory 查询变量变更历史
+func SearchVariableHistory(c *ctx.ServiceContext, form *forms.SearchVariableHistoryForm) (interface{}, e.Error) {
+	query := services.QueryVariableHistory(c.DB()).
+		Where("iac_variable_history.org_id = ? AND iac_variable_history.scope = ?", c.OrgId, form.Scope)
+	switch form.Scope {
+	case consts.ScopeOrg:
+	case consts.ScopeProject:
+		query = query.Where("iac_variable_history.project_id = ?", c.ProjectId)
+	case consts.ScopeTemplate:
+		query = query.Where("iac_variable_history.tpl_id = ?", form.TplId)
+	case consts.ScopeEnv:
+		if err := checkTokenEnvScope(c, form.EnvId); err != nil {
+			return nil, err
+		}
+		query = query.Where("iac_variable_history.project_id = ? AND iac_variable_history.env_id = ?",
+			c.ProjectId, form.EnvId)
+	default:
+		return nil, e.New(e.BadParam, fmt.Errorf("invalid scope '%s'", form.Scope), http.StatusBadRequest)
+	}
+	if form.Name != "" {
+		query = query.Where("iac_variable_history.name = ?", form.Name)
+	}
+	if form.VariableId != "" {
+		query = query.Where("iac_variable_history.variable_id = ?", form.VariableId)
+	}
+	query = query.Order("iac_variable_history.created_at DESC")
+
+	p := page.New(form.CurrentPage(), form.PageSize(), query)
+	histories := make([]*models.VariableHistoryResp, 0)
+	if err := p.Scan(&histories); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return page.PageResp{
+		Total:    p.MustTotal(),
+		PageSize: p.Size,
+		List:     histories,
+	}, nil
+}
@@ -12,6 +12,8 @@ const (
 	DefaultPageSize = 15   // 默认分页大小
 	MaxPageSize     = 5000 // 同时是 csv 最大导出条数
 
+	MaxAuditLogExportSize = 100000 // 审计日志单次导出的最大条数，导出时按 MaxPageSize 分批查询
+
 	MaxLogContentSize = 1024 * 1024 // 最大日志文件大小，超限会被截断
 
 	RunnerConnectTimeout = time.Second * 5
@@ -39,6 +41,11 @@ const (
 	Env                    = "env"
 	TerraformVar           = "TF_VAR_"
 	WorkFlow               = "workflow"
+	DependencyTrigger      = "dependency" // 上游依赖环境部署成功后触发的任务
+	EnvPromote             = "promote"    // 环境晋级触发的任务
+	EnvSchedule            = "schedule"   // 环境定时任务触发的任务
+	EnvRollback            = "rollback"   // 环境回滚触发的任务
+	BulkOperation          = "bulk"       // 批量操作触发的任务
 
 	GitTypeGitLab = "gitlab"
 	GitTypeGitEA  = "gitea"
@@ -79,13 +86,16 @@ const (
 	ScopeProject  = "project"
 	ScopeTemplate = "template"
 	ScopeEnv      = "env"
+	ScopeVarSet   = "varset" // 来自项目或环境关联的变量集，只用于展示变量来源，优先级高于项目变量、低于环境变量
 
 	VarTypeEnv       = "environment"
 	VarTypeTerraform = "terraform"
 	VarTypeAnsible   = "ansible"
+	VarTypeFile      = "file" // 文件变量，值为文件内容，任务执行时写入工作目录并通过同名环境变量传递文件路径
 
-	TokenApi     = "api"     //token类型
-	TokenTrigger = "trigger" //token类型
+	TokenApi      = "api"      //token类型，组织服务账号
+	TokenTrigger  = "trigger"  //token类型
+	TokenPersonal = "personal" //token类型，个人访问令牌
 )
 
 var (
@@ -96,4 +106,12 @@ var (
 		"timeout":  "超时",
 		"pending":  "排队中",
 	}
+
+	// ProjectRolePriority 项目角色权限高低，用户同时拥有多个项目角色时取最高者
+	ProjectRolePriority = map[string]int{
+		ProjectRoleGuest:    1,
+		ProjectRoleOperator: 2,
+		ProjectRoleApprover: 3,
+		ProjectRoleManager:  4,
+	}
 )
@@ -103,6 +103,7 @@ const (
 
 	VariableAlreadyExists  = 30510
 	VariableAliasDuplicate = 30511
+	VariableInvalid        = 30512
 
 	//// token 306
 
@@ -125,6 +126,11 @@ const (
 	EnvArchived            = 30813
 	EnvCannotArchiveActive = 30814
 	EnvDeploying           = 30815
+	EnvOutputRefInvalid    = 30816
+	EnvHasDependents       = 30817
+	EnvDependencyCycle     = 30818
+	EnvStateAlreadyExists  = 30819
+	EnvStateInvalid        = 30820
 
 	//// task 309
 
@@ -133,6 +139,11 @@ const (
 	TaskApproveNotPending = 30913
 	TaskStepNotExists     = 30914
 	TaskNotHaveStep       = 30916
+	TaskAlreadyApproved   = 30917
+	TaskSelfApproveDenied = 30918
+	TaskApproverNotInTeam = 30919
+	TaskNotRetryable      = 30920
+	TaskRetryOutdated     = 30921
 
 	//// ssh key 310
 
@@ -144,6 +155,45 @@ const (
 	//// vcs 311
 
 	VcsNotExists = 31110
+
+	//// team 312
+
+	TeamAlreadyExists        = 31210
+	TeamNotExists            = 31211
+	TeamAliasDuplicate       = 31212
+	TeamProjectAlreadyExists = 31220
+
+	//// freeze window 313
+
+	FreezeWindowNotExists   = 31311
+	FreezeWindowInvalidTime = 31312
+	EnvFrozen               = 31320
+
+	//// approval policy 314
+
+	ApprovalPolicyAlreadyExists = 31410
+	ApprovalPolicyNotExists     = 31411
+
+	//// env schedule 315
+
+	EnvScheduleNotExists   = 31511
+	EnvScheduleInvalidCron = 31512
+
+	//// bulk operation 316
+	BulkOperationNotExists = 31611
+	BulkOperationNoEnv     = 31612
+
+	//// resource account 317
+	ResourceAccountNotExists        = 31711
+	ResourceAccountBindingNotExists = 31712
+	ResourceAccountBindingExists    = 31713
+	ResourceAccountStsFailed        = 31714
+
+	//// variable set 318
+	VariableSetNotExists           = 31811
+	VariableSetAlreadyExists       = 31812
+	VariableSetAttachmentNotExists = 31813
+	VariableSetAttachmentExists    = 31814
 )
 
 var errorMsgs = map[int]map[string]string{
@@ -294,6 +344,21 @@ var errorMsgs = map[int]map[string]string{
 	TaskNotHaveStep: {
 		"zh-cn": "任务无步骤",
 	},
+	TaskAlreadyApproved: {
+		"zh-cn": "已审批过该任务",
+	},
+	TaskSelfApproveDenied: {
+		"zh-cn": "不允许审批自己创建的任务",
+	},
+	TaskApproverNotInTeam: {
+		"zh-cn": "审批人不在指定的审批团队中",
+	},
+	TaskNotRetryable: {
+		"zh-cn": "任务未执行失败，不允许重试",
+	},
+	TaskRetryOutdated: {
+		"zh-cn": "环境已执行了新的任务，不允许重试",
+	},
 	TemplateAlreadyExists: {
 		"zh-cn": "模板名称重复",
 	},
@@ -304,6 +369,9 @@ var errorMsgs = map[int]map[string]string{
 	VariableAliasDuplicate: {
 		"zh-cn": "变量别名重复",
 	},
+	VariableInvalid: {
+		"zh-cn": "变量值与模板中的变量声明不符",
+	},
 
 	ProjectUserAlreadyExists: {
 		"zh-cn": "项目用户已经存在",
@@ -350,6 +418,21 @@ var errorMsgs = map[int]map[string]string{
 	EnvDeploying: {
 		"zh-cn": "环境正在部署中，请不要重复发起",
 	},
+	EnvOutputRefInvalid: {
+		"zh-cn": "环境输出引用无效",
+	},
+	EnvHasDependents: {
+		"zh-cn": "存在依赖该环境的活跃环境，不能销毁",
+	},
+	EnvDependencyCycle: {
+		"zh-cn": "环境之间存在循环依赖",
+	},
+	EnvStateAlreadyExists: {
+		"zh-cn": "环境已存在资源状态",
+	},
+	EnvStateInvalid: {
+		"zh-cn": "无效的 terraform state 文件",
+	},
 	TaskAlreadyExists: {
 		"zh-cn": "任务已经存在",
 	},
@@ -380,4 +463,67 @@ var errorMsgs = map[int]map[string]string{
 	EnvCannotArchiveActive: {
 		"zh-cn": "环境当前状态活跃, 无法归档",
 	},
+	TeamAlreadyExists: {
+		"zh-cn": "团队已存在",
+	},
+	TeamNotExists: {
+		"zh-cn": "团队不存在",
+	},
+	TeamAliasDuplicate: {
+		"zh-cn": "团队名称重复",
+	},
+	TeamProjectAlreadyExists: {
+		"zh-cn": "团队已关联该项目",
+	},
+	FreezeWindowNotExists: {
+		"zh-cn": "冻结窗口不存在",
+	},
+	FreezeWindowInvalidTime: {
+		"zh-cn": "冻结窗口结束时间必须晚于开始时间",
+	},
+	EnvFrozen: {
+		"zh-cn": "环境处于变更冻结期，禁止部署或销毁",
+	},
+	ApprovalPolicyAlreadyExists: {
+		"zh-cn": "审批策略已存在",
+	},
+	ApprovalPolicyNotExists: {
+		"zh-cn": "审批策略不存在",
+	},
+	EnvScheduleNotExists: {
+		"zh-cn": "定时任务不存在",
+	},
+	EnvScheduleInvalidCron: {
+		"zh-cn": "定时任务的 cron 表达式或时区无效",
+	},
+	BulkOperationNotExists: {
+		"zh-cn": "批量操作不存在",
+	},
+	BulkOperationNoEnv: {
+		"zh-cn": "没有匹配的环境",
+	},
+	ResourceAccountNotExists: {
+		"zh-cn": "资源账号不存在",
+	},
+	ResourceAccountBindingNotExists: {
+		"zh-cn": "资源账号绑定关系不存在",
+	},
+	ResourceAccountBindingExists: {
+		"zh-cn": "资源账号已绑定",
+	},
+	ResourceAccountStsFailed: {
+		"zh-cn": "资源账号获取临时凭证失败",
+	},
+	VariableSetNotExists: {
+		"zh-cn": "变量集不存在",
+	},
+	VariableSetAlreadyExists: {
+		"zh-cn": "变量集名称重复",
+	},
+	VariableSetAttachmentNotExists: {
+		"zh-cn": "变量集关联关系不存在",
+	},
+	VariableSetAttachmentExists: {
+		"zh-cn": "变量集已关联",
+	},
 }
@@ -6,4 +6,5 @@ const (
 	RunnerRunTaskURL       = "/api/v1/task/run"
 	RunnerTaskStateURL     = "/api/v1/task/status"
 	RunnerTaskLogFollowURL = "/api/v1/task/log/follow"
+	RunnerTaskWorkspaceURL = "/api/v1/task/workspace"
 )
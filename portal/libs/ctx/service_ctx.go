@@ -26,6 +26,8 @@ type ServiceContext struct {
 	Username     string    // 用户名称
 	IsSuperAdmin bool      // 是否平台管理员
 	UserIpAddr   string
+	ApiToken     *models.Token // 通过个人访问令牌或服务账号访问时的 token
+	AuditLogged  bool          // 请求已单独记录审计日志，无需再由中间件记录
 }
 
 func NewServiceContext(rc RequestContext) *ServiceContext {
@@ -0,0 +1,84 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import (
+	"cloudiac/portal/libs/db"
+	"time"
+)
+
+// ApprovalPolicy 部署/销毁步骤的审批策略，环境级策略优先于项目级策略
+type ApprovalPolicy struct {
+	TimedModel
+
+	OrgId     Id `json:"orgId" gorm:"size:32;not null;comment:组织ID"`     // 组织ID
+	ProjectId Id `json:"projectId" gorm:"size:32;not null;comment:项目ID"` // 项目ID
+	EnvId     Id `json:"envId" gorm:"size:32;default:'';comment:环境ID"`   // 环境ID，为空表示项目级审批策略
+
+	MinApprovers     int  `json:"minApprovers" gorm:"default:1;comment:最少审批人数"`          // 需要的不同审批人数量
+	RequiredTeamId   Id   `json:"requiredTeamId" gorm:"size:32;default:'';comment:审批团队"` // 指定审批团队，设置后只有团队成员可以审批
+	DenySelfApproval bool `json:"denySelfApproval" gorm:"default:false;comment:禁止创建人审批"` // 禁止任务创建人审批自己的任务
+	ExpireMinutes    int  `json:"expireMinutes" gorm:"default:0;comment:审批超时时间(分钟)"`     // 审批超时时间，超时未完成审批的步骤失败，0 表示不超时
+	CreatorId        Id   `json:"creatorId" gorm:"size:32;not null;comment:创建人"`         // 创建人ID
+}
+
+func (ApprovalPolicy) TableName() string {
+	return "iac_approval_policy"
+}
+
+func (p ApprovalPolicy) Migrate(sess *db.Session) error {
+	return p.AddUniqueIndex(sess, "unique__project__env", "project_id", "env_id")
+}
+
+// DefaultApprovalPolicy 未配置审批策略时的默认策略：任意一个有审批权限的用户审批即可
+func DefaultApprovalPolicy() *ApprovalPolicy {
+	return &ApprovalPolicy{MinApprovers: 1}
+}
+
+// ExpireAt 基于步骤进入待审批状态的时间计算审批超时时间，不超时返回 nil
+func (p *ApprovalPolicy) ExpireAt(approvingAt time.Time) *Time {
+	if p.ExpireMinutes <= 0 {
+		return nil
+	}
+	t := Time(approvingAt.Add(time.Duration(p.ExpireMinutes) * time.Minute))
+	return &t
+}
+
+const (
+	ApprovalActionApproved = "approved"
+	ApprovalActionRejected = "rejected"
+)
+
+// TaskApproval 任务步骤的审批记录
+type TaskApproval struct {
+	TimedModel
+
+	OrgId     Id     `json:"orgId" gorm:"size:32;not null"`                                                     // 组织ID
+	ProjectId Id     `json:"projectId" gorm:"size:32;not null"`                                                 // 项目ID
+	EnvId     Id     `json:"envId" gorm:"size:32;not null"`                                                     // 环境ID
+	TaskId    Id     `json:"taskId" gorm:"size:32;not null"`                                                    // 任务ID
+	Step      int    `json:"step" gorm:"not null"`                                                              // 步骤序号
+	UserId    Id     `json:"userId" gorm:"size:32;not null"`                                                    // 审批人ID
+	Action    string `json:"action" gorm:"type:enum('approved','rejected');not null" enums:"approved,rejected"` // 审批动作
+	Comment   string `json:"comment" gorm:"type:text"`                                                          // 审批意见
+}
+
+func (TaskApproval) TableName() string {
+	return "iac_task_approval"
+}
+
+func (a TaskApproval) Migrate(sess *db.Session) error {
+	return a.AddUniqueIndex(sess, "unique__task__step__user", "task_id", "step", "user_id")
+}
+
+type TaskApprovalResp struct {
+	TaskApproval
+	Username string `json:"username"` // 审批人名称
+}
+
+// TaskApprovalStatus 审批后步骤的审批进度
+type TaskApprovalStatus struct {
+	Approved  int64 `json:"approved"`  // 已通过的审批人数
+	Required  int   `json:"required"`  // 需要的审批人数
+	Satisfied bool  `json:"satisfied"` // 是否已满足审批策略
+}
@@ -0,0 +1,21 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApprovalPolicyExpireAt(t *testing.T) {
+	now := time.Now()
+	if at := DefaultApprovalPolicy().ExpireAt(now); at != nil {
+		t.Errorf("default policy should not expire, got %v", time.Time(*at))
+	}
+
+	policy := ApprovalPolicy{ExpireMinutes: 30}
+	at := policy.ExpireAt(now)
+	if at == nil || !time.Time(*at).Equal(now.Add(30*time.Minute)) {
+		t.Errorf("unexpected expire time %v", at)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+const (
+	BulkActionPlan      = TaskTypePlan
+	BulkActionApply     = TaskTypeApply
+	BulkActionDestroy   = TaskTypeDestroy
+	BulkActionVariables = "variables"
+
+	BulkOperationRunning  = "running"
+	BulkOperationComplete = "complete"
+	BulkOperationFailed   = "failed"
+	BulkOperationAborted  = "aborted"
+
+	BulkItemPending  = "pending"
+	BulkItemRunning  = "running"
+	BulkItemComplete = "complete"
+	BulkItemFailed   = "failed"
+	BulkItemSkipped  = "skipped"
+)
+
+// BulkOperation 对多个环境批量执行 plan、部署、销毁或更新变量
+type BulkOperation struct {
+	TimedModel
+
+	OrgId     Id `json:"orgId" gorm:"size:32;not null;comment:组织ID"`           // 组织ID
+	ProjectId Id `json:"projectId" gorm:"size:32;not null;index;comment:项目ID"` // 项目ID
+	CreatorId Id `json:"creatorId" gorm:"size:32;not null;comment:创建人"`        // 创建人ID
+
+	Action      string `json:"action" gorm:"type:enum('plan','apply','destroy','variables');not null" enums:"plan,apply,destroy,variables"` // 操作类型
+	Parallelism int    `json:"parallelism" gorm:"default:5;comment:最大并发数"`                                                                  // 同时执行任务的最大环境数量
+	MaxFailures int    `json:"maxFailures" gorm:"default:0;comment:失败数上限"`                                                                  // 失败的环境数量达到该值后停止执行剩余环境，0 表示不限制
+
+	// 聚合状态，running 执行中，complete 全部成功，failed 部分失败，aborted 因失败过多提前停止
+	Status    string `json:"status" gorm:"type:enum('running','complete','failed','aborted');not null" enums:"running,complete,failed,aborted"`
+	Total     int    `json:"total" gorm:"default:0"`     // 环境总数
+	Succeeded int    `json:"succeeded" gorm:"default:0"` // 执行成功的环境数量
+	Failed    int    `json:"failed" gorm:"default:0"`    // 执行失败的环境数量
+	Skipped   int    `json:"skipped" gorm:"default:0"`   // 被跳过的环境数量
+
+	EndAt *Time `json:"endAt" gorm:"type:datetime;comment:结束时间"` // 结束时间
+}
+
+func (BulkOperation) TableName() string {
+	return "iac_bulk_operation"
+}
+
+// BulkOperationItem 批量操作中单个环境的执行记录
+type BulkOperationItem struct {
+	TimedModel
+
+	BulkId  Id     `json:"bulkId" gorm:"size:32;not null;index"`                                                                                                // 批量操作ID
+	EnvId   Id     `json:"envId" gorm:"size:32;not null"`                                                                                                       // 环境ID
+	TaskId  Id     `json:"taskId" gorm:"size:32;default:''"`                                                                                                    // 创建的任务ID
+	Status  string `json:"status" gorm:"type:enum('pending','running','complete','failed','skipped');not null" enums:"pending,running,complete,failed,skipped"` // 执行状态
+	Message string `json:"message" gorm:"type:text"`                                                                                                            // 失败或跳过的原因
+}
+
+func (BulkOperationItem) TableName() string {
+	return "iac_bulk_operation_item"
+}
+
+type BulkOperationItemResp struct {
+	BulkOperationItem
+	EnvName    string `json:"envName"`    // 环境名称
+	TaskStatus string `json:"taskStatus"` // 任务状态
+}
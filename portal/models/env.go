@@ -33,6 +33,7 @@ type Env struct {
 
 	Name        string `json:"name" gorm:"not null"`                                                                       // 环境名称
 	Description string `json:"description" gorm:"type:text"`                                                               // 环境描述
+	Labels      Labels `json:"labels" gorm:"type:json" swaggertype:"object,string"`                                        // 标签
 	Status      string `json:"status" gorm:"type:enum('active','failed','inactive')" enums:"'active','failed','inactive'"` // 环境状态, active活跃, inactive非活跃,failed错误,running部署中,approving审批中
 	// 任务状态，只同步部署任务的状态(apply,destroy)，plan 任务不会对环境产生影响，所以不同步
 	TaskStatus string `json:"taskStatus" gorm:"type:enum('','approving','running');default:''"`
@@ -66,6 +67,9 @@ type Env struct {
 
 	// 触发器设置
 	Triggers pq.StringArray `json:"triggers" gorm:"type:json" swaggertype:"array,string"` // 触发器。commit（每次推送自动部署），prmr（提交PR/MR的时候自动执行plan）
+
+	// 依赖的环境部署成功后自动执行的任务类型，为空表示不自动执行
+	DependencyTrigger string `json:"dependencyTrigger" gorm:"type:enum('','plan','apply');default:''" enums:"'','plan','apply'"` // 上游依赖环境部署成功后自动执行的任务，plan计划,apply部署
 }
 
 func (Env) TableName() string {
@@ -121,4 +125,6 @@ type EnvDetail struct {
 	TemplateName  string `json:"templateName"`  // 模板名称
 	KeyName       string `json:"keyName"`       // 密钥名称
 	TaskId        Id     `json:"taskId"`        // 当前作业ID
+
+	VariableWarnings []string `json:"variableWarnings,omitempty" gorm:"-"` // 变量检查的警告信息(如模板中未声明的变量)
 }
@@ -0,0 +1,47 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import (
+	"cloudiac/portal/libs/db"
+	"strings"
+)
+
+// EnvOutputRefPrefix 引用其他环境输出的变量值前缀，格式为 env://<envId>/outputs/<name>
+const EnvOutputRefPrefix = "env://"
+
+// ParseEnvOutputRef 解析环境输出引用，非引用格式的值返回 ok=false
+func ParseEnvOutputRef(value string) (envId Id, output string, ok bool) {
+	if !strings.HasPrefix(value, EnvOutputRefPrefix) {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(value, EnvOutputRefPrefix), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] != "outputs" || parts[2] == "" {
+		return "", "", false
+	}
+	return Id(parts[0]), parts[2], true
+}
+
+// EnvDependency 环境间的依赖关系，EnvId 的变量引用了 DependsOnEnvId 的输出
+type EnvDependency struct {
+	AutoUintIdModel
+
+	OrgId          Id `json:"orgId" gorm:"size:32;not null;comment:组织ID"`             // 组织ID
+	EnvId          Id `json:"envId" gorm:"size:32;not null;comment:依赖方环境ID"`          // 依赖方(消费者)环境ID
+	DependsOnEnvId Id `json:"dependsOnEnvId" gorm:"size:32;not null;comment:被依赖环境ID"` // 被依赖(生产者)环境ID
+}
+
+func (EnvDependency) TableName() string {
+	return "iac_env_dependency"
+}
+
+func (d EnvDependency) Migrate(sess *db.Session) error {
+	return d.AddUniqueIndex(sess, "unique__env__depends_on", "env_id", "depends_on_env_id")
+}
+
+type EnvDependencyResp struct {
+	EnvDependency
+	EnvName          string `json:"envName"`          // 依赖方环境名称
+	EnvStatus        string `json:"envStatus"`        // 依赖方环境状态
+	DependsOnEnvName string `json:"dependsOnEnvName"` // 被依赖环境名称
+}
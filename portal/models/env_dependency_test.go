@@ -0,0 +1,28 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import "testing"
+
+func TestParseEnvOutputRef(t *testing.T) {
+	cases := []struct {
+		value  string
+		envId  Id
+		output string
+		ok     bool
+	}{
+		{"env://env-c4i8s1rn6m81fm687b0g/outputs/vpc_id", "env-c4i8s1rn6m81fm687b0g", "vpc_id", true},
+		{"env://env-1/outputs/", "", "", false},
+		{"env://env-1/vpc_id", "", "", false},
+		{"env:///outputs/vpc_id", "", "", false},
+		{"vpc-123", "", "", false},
+	}
+
+	for _, c := range cases {
+		envId, output, ok := ParseEnvOutputRef(c.value)
+		if envId != c.envId || output != c.output || ok != c.ok {
+			t.Errorf("ParseEnvOutputRef(%q) = %q, %q, %v; want %q, %q, %v",
+				c.value, envId, output, ok, c.envId, c.output, c.ok)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import (
+	"cloudiac/utils"
+	"fmt"
+	"time"
+)
+
+// EnvSchedule 环境定时任务，按 cron 表达式周期性地部署或销毁环境
+type EnvSchedule struct {
+	TimedModel
+
+	OrgId     Id `json:"orgId" gorm:"size:32;not null;comment:组织ID"`       // 组织ID
+	ProjectId Id `json:"projectId" gorm:"size:32;not null;comment:项目ID"`   // 项目ID
+	EnvId     Id `json:"envId" gorm:"size:32;not null;index;comment:环境ID"` // 环境ID
+	CreatorId Id `json:"creatorId" gorm:"size:32;not null;comment:创建人"`    // 创建人ID
+
+	Name     string `json:"name" gorm:"not null;comment:名称" example:"工作日早上部署"`                                         // 名称
+	Action   string `json:"action" gorm:"type:enum('apply','destroy');not null;comment:执行的任务类型" enums:"apply,destroy"` // 执行的任务类型，apply部署,destroy销毁
+	Cron     string `json:"cron" gorm:"size:64;not null;comment:cron表达式" example:"0 8 * * 1-5"`                        // cron 表达式(分 时 日 月 周)
+	Timezone string `json:"timezone" gorm:"size:64;not null;default:'Local';comment:时区" example:"Asia/Shanghai"`       // 时区，Local 表示服务器时区
+	Paused   bool   `json:"paused" gorm:"default:false;comment:是否暂停"`                                                  // 是否暂停
+
+	NextRunAt *Time `json:"nextRunAt" gorm:"type:datetime;index;comment:下次执行时间"` // 下次执行时间，暂停时为空
+	LastRunAt *Time `json:"lastRunAt" gorm:"type:datetime;comment:上次执行时间"`       // 上次执行时间
+}
+
+func (EnvSchedule) TableName() string {
+	return "iac_env_schedule"
+}
+
+// NextRunTime 计算 after 之后的下次执行时间
+func (s *EnvSchedule) NextRunTime(after time.Time) (*Time, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone '%s': %v", s.Timezone, err)
+	}
+	cron, err := utils.ParseCron(s.Cron)
+	if err != nil {
+		return nil, err
+	}
+	next := cron.Next(after.In(loc))
+	if next.IsZero() {
+		return nil, fmt.Errorf("cron '%s' never runs", s.Cron)
+	}
+	t := Time(next)
+	return &t, nil
+}
+
+const (
+	EnvScheduleRunCreated = "created"
+	EnvScheduleRunSkipped = "skipped"
+	EnvScheduleRunFailed  = "failed"
+)
+
+// EnvScheduleRun 环境定时任务的执行记录
+type EnvScheduleRun struct {
+	TimedModel
+
+	ScheduleId  Id     `json:"scheduleId" gorm:"size:32;not null;index"`                                                      // 定时任务ID
+	EnvId       Id     `json:"envId" gorm:"size:32;not null"`                                                                 // 环境ID
+	Action      string `json:"action" gorm:"type:enum('apply','destroy');not null" enums:"apply,destroy"`                     // 执行的任务类型
+	TaskId      Id     `json:"taskId" gorm:"size:32;default:''"`                                                              // 创建的任务ID
+	Status      string `json:"status" gorm:"type:enum('created','skipped','failed');not null" enums:"created,skipped,failed"` // 执行结果，created已创建任务,skipped跳过,failed失败
+	Message     string `json:"message" gorm:"type:text"`                                                                      // 跳过或失败的原因
+	ScheduledAt Time   `json:"scheduledAt" gorm:"type:datetime;not null"`                                                     // 计划执行时间
+}
+
+func (EnvScheduleRun) TableName() string {
+	return "iac_env_schedule_run"
+}
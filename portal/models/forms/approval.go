@@ -0,0 +1,43 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package forms
+
+import "cloudiac/portal/models"
+
+type CreateApprovalPolicyForm struct {
+	BaseForm
+
+	EnvId            models.Id `json:"envId" form:"envId"`                       // 环境ID，不传表示项目级审批策略
+	MinApprovers     int       `json:"minApprovers" form:"minApprovers"`         // 需要的不同审批人数量，默认为 1
+	RequiredTeamId   models.Id `json:"requiredTeamId" form:"requiredTeamId"`     // 指定审批团队，设置后只有团队成员可以审批
+	DenySelfApproval bool      `json:"denySelfApproval" form:"denySelfApproval"` // 禁止任务创建人审批自己的任务
+	ExpireMinutes    int       `json:"expireMinutes" form:"expireMinutes"`       // 审批超时时间(分钟)，0 表示不超时
+}
+
+type SearchApprovalPolicyForm struct {
+	PageForm
+
+	EnvId models.Id `form:"envId" json:"envId"` // 环境ID
+}
+
+type DetailApprovalPolicyForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 审批策略ID
+}
+
+type UpdateApprovalPolicyForm struct {
+	BaseForm
+
+	Id               models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 审批策略ID
+	MinApprovers     int       `json:"minApprovers" form:"minApprovers"`                      // 需要的不同审批人数量
+	RequiredTeamId   models.Id `json:"requiredTeamId" form:"requiredTeamId"`                  // 指定审批团队，传空值表示不限制
+	DenySelfApproval bool      `json:"denySelfApproval" form:"denySelfApproval"`              // 禁止任务创建人审批自己的任务
+	ExpireMinutes    int       `json:"expireMinutes" form:"expireMinutes"`                    // 审批超时时间(分钟)，0 表示不超时
+}
+
+type DeleteApprovalPolicyForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 审批策略ID
+}
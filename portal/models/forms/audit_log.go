@@ -0,0 +1,19 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package forms
+
+import "cloudiac/portal/models"
+
+type SearchAuditLogForm struct {
+	PageForm
+
+	OrgId         models.Id `form:"orgId" json:"orgId"`                                   // 组织ID，仅平台管理员可指定，否则为当前组织
+	ProjectId     models.Id `form:"projectId" json:"projectId"`                           // 项目ID
+	UserId        models.Id `form:"userId" json:"userId"`                                 // 操作人ID
+	ResourceType  string    `form:"resourceType" json:"resourceType"`                     // 资源类型，如 envs、tasks、variables
+	ResourceId    models.Id `form:"resourceId" json:"resourceId"`                         // 资源ID
+	OperationType string    `form:"operationType" json:"operationType"`                   // 操作类型
+	StartAt       string    `form:"startAt" json:"startAt"`                               // 开始时间(RFC3339)
+	EndAt         string    `form:"endAt" json:"endAt"`                                   // 结束时间(RFC3339)
+	Format        string    `form:"format" json:"format" enums:"csv,jsonl" default:"csv"` // 导出格式，export=true 时有效
+}
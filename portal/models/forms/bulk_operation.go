@@ -0,0 +1,34 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package forms
+
+import "cloudiac/portal/models"
+
+type CreateBulkOperationForm struct {
+	BaseForm
+
+	Action string `json:"action" form:"action" binding:"required" enums:"plan,apply,destroy,variables"` // 操作类型，plan计划,apply部署,destroy销毁,variables更新变量
+
+	EnvIds []models.Id `json:"envIds" form:"envIds"`                                // 环境ID列表，为空时按筛选条件选择环境
+	TplId  models.Id   `json:"tplId" form:"tplId"`                                  // 按模板筛选环境
+	Q      string      `json:"q" form:"q"`                                          // 按环境名称模糊匹配
+	Status string      `json:"status" form:"status" enums:"active,failed,inactive"` // 按环境状态筛选
+
+	Parallelism int `json:"parallelism" form:"parallelism" binding:"omitempty,min=1,max=50"` // 同时执行任务的最大环境数量，默认 5
+	MaxFailures int `json:"maxFailures" form:"maxFailures" binding:"omitempty,min=0"`        // 失败的环境数量达到该值后停止执行剩余环境，默认 0 不限制
+
+	Variables []Variables `json:"variables" form:"variables"` // 更新变量时按名称和类型新增或覆盖的环境变量
+}
+
+type SearchBulkOperationForm struct {
+	PageForm
+
+	Action string `form:"action" json:"action" enums:"plan,apply,destroy,variables"`    // 操作类型
+	Status string `form:"status" json:"status" enums:"running,complete,failed,aborted"` // 执行状态
+}
+
+type DetailBulkOperationForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 批量操作ID
+}
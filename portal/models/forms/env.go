@@ -20,6 +20,10 @@ type CreateEnvForm struct {
 	OneTime  bool      `form:"oneTime" json:"oneTime" binding:""`                // 一次性环境标识
 	Triggers []string  `form:"triggers" json:"triggers" binding:""`              // 启用触发器，触发器：commit（每次推送自动部署），prmr（提交PR/MR的时候自动执行plan）
 
+	Labels models.Labels `form:"labels" json:"labels" binding:""` // 标签，如 {"team": "payments"}
+
+	DependencyTrigger string `form:"dependencyTrigger" json:"dependencyTrigger" enums:",plan,apply"` // 上游依赖环境部署成功后自动执行的任务，plan计划,apply部署，为空不执行
+
 	AutoApproval bool `form:"autoApproval" json:"autoApproval"  binding:"" enums:"true,false"` // 是否自动审批
 
 	TaskType string `form:"taskType" json:"taskType" binding:"required" enums:"plan,apply"` // 环境创建后触发的任务步骤，plan计划,apply部署
@@ -48,9 +52,13 @@ type UpdateEnvForm struct {
 	RunnerId    string    `form:"runnerId" json:"runnerId" binding:""`              // 环境默认部署通道
 	Archived    bool      `form:"archived" json:"archived" enums:"true,false"`      // 归档状态，默认返回未归档环境
 
+	Labels models.Labels `form:"labels" json:"labels" binding:""` // 标签，会覆盖环境现有的标签
+
 	AutoApproval bool `form:"autoApproval" json:"autoApproval"  binding:"" enums:"true,false"` // 是否自动审批
 
 	Triggers []string `form:"triggers" json:"triggers" binding:""` // 启用触发器，触发器：commit（每次推送自动部署），prmr（提交PR/MR的时候自动执行plan）
+
+	DependencyTrigger string `form:"dependencyTrigger" json:"dependencyTrigger" enums:",plan,apply"` // 上游依赖环境部署成功后自动执行的任务，plan计划,apply部署，为空不执行
 }
 
 type DeployEnvForm struct {
@@ -63,11 +71,11 @@ type DeployEnvForm struct {
 	Triggers     []string `form:"triggers" json:"triggers" binding:""`                             // 启用触发器，触发器：commit（每次推送自动部署），prmr（提交PR/MR的时候自动执行plan）
 	AutoApproval bool     `form:"autoApproval" json:"autoApproval"  binding:"" enums:"true,false"` // 是否自动审批
 
-	TaskType string `form:"taskType" json:"taskType" binding:"required" enums:"plan,apply,destroy"` // 环境创建后触发的任务步骤，plan计划,apply部署,destroy销毁资源
-	Targets  string `form:"targets" json:"targets" binding:""`                                      // Terraform target 参数列表
-	RunnerId string `form:"runnerId" json:"runnerId" binding:""`                                    // 环境默认部署通道
-	Revision string `form:"revision" json:"revision" binding:""`                                    // 分支/标签
-	Timeout  int    `form:"timeout" json:"timeout" binding:""`                                      // 部署超时时间（单位：秒）
+	TaskType string `form:"taskType" json:"taskType" binding:"required" enums:"plan,apply,destroy,refresh,output"` // 环境创建后触发的任务步骤，plan计划,apply部署,destroy销毁资源,refresh同步资源状态,output读取outputs
+	Targets  string `form:"targets" json:"targets" binding:""`                                                     // Terraform target 参数列表
+	RunnerId string `form:"runnerId" json:"runnerId" binding:""`                                                   // 环境默认部署通道
+	Revision string `form:"revision" json:"revision" binding:""`                                                   // 分支/标签
+	Timeout  int    `form:"timeout" json:"timeout" binding:""`                                                     // 部署超时时间（单位：秒）
 
 	Variables         []Variables `form:"variables" json:"variables" binding:""`       // 自定义变量列表，该变量列表会覆盖现有的变量
 	DeleteVariablesId []string    `json:"deleteVariablesId" form:"deleteVariablesId" ` //删除的变量id
@@ -76,6 +84,9 @@ type DeployEnvForm struct {
 	PlayVarsFile string    `form:"playVarsFile" json:"playVarsFile" binding:""` // Ansible playbook 变量文件路径
 	Playbook     string    `form:"playbook" json:"playbook" binding:""`         // Ansible playbook 入口文件路径
 	KeyId        models.Id `form:"keyId" json:"keyId" binding:""`               // 部署密钥ID
+
+	FreezeOverride       bool   `form:"freezeOverride" json:"freezeOverride" enums:"true,false"` // 紧急变更，忽略冻结窗口(仅平台管理员)
+	FreezeOverrideReason string `form:"freezeOverrideReason" json:"freezeOverrideReason"`        // 紧急变更原因，freezeOverride 为 true 时必填
 }
 
 type ArchiveEnvForm struct {
@@ -92,6 +103,12 @@ type SearchEnvForm struct {
 	Q        string `form:"q" json:"q" binding:""`                                                 // 环境名称，支持模糊查询
 	Status   string `form:"status" json:"status" enums:"active,failed,inactive,running,approving"` // 环境状态，active活跃, inactive非活跃,failed错误,running部署中,approving审批中
 	Archived string `form:"archived" json:"archived" enums:"true,false,all"`                       // 归档状态，默认返回未归档环境
+
+	Labels         string    `form:"labels" json:"labels" example:"team=payments,tier!=prod"`                                // 标签选择器，多个条件以逗号分隔，支持 key=value、key!=value、key、!key
+	TplId          models.Id `form:"tplId" json:"tplId"`                                                                     // 模板ID
+	CreatorId      models.Id `form:"creatorId" json:"creatorId"`                                                             // 创建人ID
+	LastTaskStatus string    `form:"lastTaskStatus" json:"lastTaskStatus" enums:"pending,running,approving,failed,complete"` // 最后一次部署任务的状态
+	TtlBefore      string    `form:"ttlBefore" json:"ttlBefore"`                                                             // 自动销毁时间早于该时间的环境
 }
 
 type DeleteEnvForm struct {
@@ -123,6 +140,9 @@ type DestroyEnvForm struct {
 	BaseForm
 
 	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 环境ID，swagger 参数通过 param path 指定，这里忽略
+
+	FreezeOverride       bool   `form:"freezeOverride" json:"freezeOverride" enums:"true,false"` // 紧急变更，忽略冻结窗口(仅平台管理员)
+	FreezeOverrideReason string `form:"freezeOverrideReason" json:"freezeOverrideReason"`        // 紧急变更原因，freezeOverride 为 true 时必填
 }
 
 type SearchEnvVariableForm struct {
@@ -130,3 +150,60 @@ type SearchEnvVariableForm struct {
 
 	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 环境ID，swagger 参数通过 param path 指定，这里忽略
 }
+
+type CloneEnvForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 源环境ID，swagger 参数通过 param path 指定，这里忽略
+
+	Name      string    `form:"name" json:"name" binding:"required,gte=2,lte=64"` // 新环境名称
+	ProjectId models.Id `form:"projectId" json:"projectId" binding:""`            // 目标项目ID，默认为源环境所在项目
+	Revision  string    `form:"revision" json:"revision" binding:""`              // 分支/标签，默认使用源环境的分支/标签
+
+	Variables []Variables `form:"variables" json:"variables" binding:""` // 覆盖的变量列表，同名同类型的源环境变量将被替换
+
+	TaskType string `form:"taskType" json:"taskType" binding:"" enums:",plan,apply"` // 克隆后触发的任务步骤，plan计划,apply部署，为空不执行
+}
+
+type RollbackEnvForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 环境ID，swagger 参数通过 param path 指定，这里忽略
+
+	TaskId models.Id `form:"taskId" json:"taskId" binding:"required"` // 回滚到的历史部署任务ID
+}
+
+type PromoteEnvForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 源环境ID，swagger 参数通过 param path 指定，这里忽略
+
+	TargetEnvId models.Id `form:"targetEnvId" json:"targetEnvId" binding:"required"`      // 目标环境ID
+	TaskType    string    `form:"taskType" json:"taskType" binding:"" enums:"plan,apply"` // 目标环境执行的任务步骤，plan计划,apply部署，默认 apply
+}
+
+type ImportEnvForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 环境ID，swagger 参数通过 param path 指定，这里忽略
+
+	Resources []string `form:"resources" json:"resources" binding:"required,min=1"` // 导入的资源列表，格式为 address=id，如 aws_instance.web=i-12345678
+}
+
+type UploadEnvStateForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 环境ID，swagger 参数通过 param path 指定，这里忽略
+
+	State string `form:"state" json:"state" binding:"required"` // terraform.tfstate 文件内容
+}
+
+type EnvStateOperationForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 环境ID，swagger 参数通过 param path 指定，这里忽略
+
+	Operation   string   `form:"operation" json:"operation" binding:"required" enums:"replace,taint,rm,mv"` // 操作类型，replace重建资源,taint标记资源需要重建,rm从state中移除资源,mv修改资源地址
+	Addresses   []string `form:"addresses" json:"addresses" binding:"required,min=1"`                       // 操作的资源地址，必须是环境当前的资源，mv 操作只能指定一个地址
+	Destination string   `form:"destination" json:"destination" binding:""`                                 // mv 操作的目标地址
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package forms
+
+import "cloudiac/portal/models"
+
+type CreateEnvScheduleForm struct {
+	BaseForm
+
+	EnvId    models.Id `json:"envId" form:"envId" binding:"required"`                         // 环境ID
+	Name     string    `json:"name" form:"name" binding:"required"`                           // 名称
+	Action   string    `json:"action" form:"action" binding:"required" enums:"apply,destroy"` // 执行的任务类型，apply部署,destroy销毁
+	Cron     string    `json:"cron" form:"cron" binding:"required"`                           // cron 表达式(分 时 日 月 周)，如 "0 20 * * 1-5" 表示工作日 20:00
+	Timezone string    `json:"timezone" form:"timezone"`                                      // 时区，如 Asia/Shanghai，默认为服务器时区
+	Paused   bool      `json:"paused" form:"paused" enums:"true,false"`                       // 是否暂停
+}
+
+type SearchEnvScheduleForm struct {
+	PageForm
+
+	EnvId models.Id `form:"envId" json:"envId"` // 环境ID
+}
+
+type DetailEnvScheduleForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 定时任务ID
+}
+
+type UpdateEnvScheduleForm struct {
+	BaseForm
+
+	Id       models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 定时任务ID
+	Name     string    `json:"name" form:"name"`                                      // 名称
+	Action   string    `json:"action" form:"action" enums:"apply,destroy"`            // 执行的任务类型，apply部署,destroy销毁
+	Cron     string    `json:"cron" form:"cron"`                                      // cron 表达式(分 时 日 月 周)
+	Timezone string    `json:"timezone" form:"timezone"`                              // 时区
+	Paused   bool      `json:"paused" form:"paused" enums:"true,false"`               // 是否暂停，恢复时从当前时间开始计算下次执行时间
+}
+
+type DeleteEnvScheduleForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 定时任务ID
+}
+
+type SearchEnvScheduleRunForm struct {
+	PageForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 定时任务ID
+}
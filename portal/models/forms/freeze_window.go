@@ -0,0 +1,45 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package forms
+
+import "cloudiac/portal/models"
+
+type CreateFreezeWindowForm struct {
+	BaseForm
+
+	Name    string      `json:"name" form:"name" binding:"required"`       // 窗口名称
+	Reason  string      `json:"reason" form:"reason"`                      // 冻结原因
+	StartAt string      `json:"startAt" form:"startAt" binding:"required"` // 开始时间(RFC3339)
+	EndAt   string      `json:"endAt" form:"endAt" binding:"required"`     // 结束时间(RFC3339)
+	EnvIds  []models.Id `json:"envIds" form:"envIds"`                      // 冻结的环境ID列表，不传表示组织或项目下的所有环境
+}
+
+type SearchFreezeWindowForm struct {
+	PageForm
+
+	Q      string `form:"q" json:"q" binding:""`                              // 窗口名称，支持模糊搜索
+	Status string `form:"status" json:"status" enums:"active,upcoming,ended"` // 窗口状态，active生效中,upcoming未开始,ended已结束
+}
+
+type DetailFreezeWindowForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 冻结窗口ID
+}
+
+type UpdateFreezeWindowForm struct {
+	BaseForm
+
+	Id      models.Id   `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 冻结窗口ID
+	Name    string      `json:"name" form:"name"`                                      // 窗口名称
+	Reason  string      `json:"reason" form:"reason"`                                  // 冻结原因
+	StartAt string      `json:"startAt" form:"startAt"`                                // 开始时间(RFC3339)
+	EndAt   string      `json:"endAt" form:"endAt"`                                    // 结束时间(RFC3339)
+	EnvIds  []models.Id `json:"envIds" form:"envIds"`                                  // 冻结的环境ID列表
+}
+
+type DeleteFreezeWindowForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 冻结窗口ID
+}
@@ -17,6 +17,7 @@ type UpdateNotificationCfgForm struct {
 	NotificationType string    `form:"notificationType" json:"notificationType" binding:"required"`
 	EventType        string    `form:"eventType" json:"eventType" binding:"required"`
 	CfgInfo          CfgInfo   `form:"cfgInfo" json:"cfgInfo"`
+	LabelSelector    string    `form:"labelSelector" json:"labelSelector"` // 环境标签选择器，为空时接收所有环境的通知
 }
 
 type CreateNotificationCfgForm struct {
@@ -25,6 +26,7 @@ type CreateNotificationCfgForm struct {
 	EventType        string      `form:"eventType" json:"eventType" binding:"required"`
 	UserIds          []models.Id `form:"userIds" json:"userIds"`
 	CfgInfo          CfgInfo     `form:"cfgInfo" json:"cfgInfo"`
+	LabelSelector    string      `form:"labelSelector" json:"labelSelector"` // 环境标签选择器，为空时接收所有环境的通知
 }
 
 type DeleteNotificationCfgForm struct {
@@ -17,6 +17,19 @@ type CreateResourceAccountForm struct {
 	Description  string   `form:"description" json:"description"`
 	Params       []Params `form:"params" json:"params"`
 	CtServiceIds []string `form:"ctServiceIds" json:"ctServiceIds"`
+
+	StsAccountForm
+}
+
+// StsAccountForm sts 类型资源账号的参数
+type StsAccountForm struct {
+	Type            string `form:"type" json:"type" enums:"static,sts"`                                  // 账号类型，默认为 static
+	Provider        string `form:"provider" json:"provider" enums:"aliyun,aws,azure,vsphere,kubernetes"` // 云厂商，sts 类型账号只支持 aws,aliyun
+	RoleArn         string `form:"roleArn" json:"roleArn"`                                               // 扮演的角色 ARN
+	ExternalId      string `form:"externalId" json:"externalId"`                                         // 扮演角色时的 external id(只用于 aws)
+	Region          string `form:"region" json:"region"`                                                 // 区域
+	AccessKeyId     string `form:"accessKeyId" json:"accessKeyId"`                                       // 基础凭证 AccessKeyId
+	AccessKeySecret string `form:"accessKeySecret" json:"accessKeySecret"`                               // 基础凭证 AccessKeySecret，修改时为空表示不修改
 }
 
 type UpdateResourceAccountForm struct {
@@ -27,6 +40,8 @@ type UpdateResourceAccountForm struct {
 	Params       []Params  `form:"params" json:"params"`
 	Status       string    `form:"status" json:"status"`
 	CtServiceIds []string  `form:"ctServiceIds" json:"ctServiceIds"`
+
+	StsAccountForm
 }
 
 type SearchResourceAccountForm struct {
@@ -40,3 +55,28 @@ type DeleteResourceAccountForm struct {
 	PageForm
 	Id models.Id `form:"id" json:"id" binding:"required"`
 }
+
+type CreateResourceAccountBindingForm struct {
+	BaseForm
+
+	ResourceAccountId models.Id `form:"resourceAccountId" json:"resourceAccountId" binding:"required"` // 资源账号ID
+	EnvId             models.Id `form:"envId" json:"envId"`                                            // 环境ID，为空表示绑定到当前项目
+}
+
+type SearchResourceAccountBindingForm struct {
+	PageForm
+
+	EnvId models.Id `form:"envId" json:"envId"` // 环境ID，传入时返回环境及其所属项目的绑定
+}
+
+type DeleteResourceAccountBindingForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 绑定关系ID
+}
+
+type TestResourceAccountForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 资源账号ID
+}
@@ -56,8 +56,15 @@ const (
 type ApproveTaskForm struct {
 	BaseForm
 
-	Id     models.Id `uri:"id" json:"id" swaggerignore:"true"`                                  // 任务ID，swagger 参数通过 param path 指定，这里忽略
-	Action string    `form:"action" json:"action" binding:"required" enums:"approved,rejected"` // 审批动作：approved通过, rejected驳回
+	Id      models.Id `uri:"id" json:"id" swaggerignore:"true"`                                  // 任务ID，swagger 参数通过 param path 指定，这里忽略
+	Action  string    `form:"action" json:"action" binding:"required" enums:"approved,rejected"` // 审批动作：approved通过, rejected驳回
+	Comment string    `form:"comment" json:"comment"`                                            // 审批意见
+}
+
+type SearchTaskApprovalForm struct {
+	PageForm
+
+	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 任务ID，swagger 参数通过 param path 指定，这里忽略
 }
 
 type SearchEnvTasksForm struct {
@@ -72,3 +79,9 @@ type SearchTaskResourceForm struct {
 	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 任务ID，swagger 参数通过 param path 指定，这里忽略
 	Q  string    `form:"q" json:"q" binding:""`            // 资源名称，支持模糊查询
 }
+
+type RetryTaskForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" json:"id" swaggerignore:"true"` // 任务ID，swagger 参数通过 param path 指定，这里忽略
+}
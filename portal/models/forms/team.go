@@ -0,0 +1,83 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package forms
+
+import "cloudiac/portal/models"
+
+type CreateTeamForm struct {
+	BaseForm
+
+	Name        string      `json:"name" form:"name" binding:"required"` // 团队名称
+	Description string      `json:"description" form:"description"`      // 团队描述
+	UserIds     []models.Id `json:"userIds" form:"userIds"`              // 团队成员id
+}
+
+type SearchTeamForm struct {
+	PageForm
+
+	Q string `form:"q" json:"q" binding:""` // 团队名称，支持模糊搜索
+}
+
+type DetailTeamForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 团队ID
+}
+
+type UpdateTeamForm struct {
+	BaseForm
+
+	Id          models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 团队ID
+	Name        string    `json:"name" form:"name"`                                      // 团队名称
+	Description string    `json:"description" form:"description"`                        // 团队描述
+}
+
+type DeleteTeamForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 团队ID
+}
+
+type SearchTeamUserForm struct {
+	PageForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 团队ID
+}
+
+type AddTeamUserForm struct {
+	BaseForm
+
+	Id      models.Id   `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 团队ID
+	UserIds []models.Id `json:"userIds" form:"userIds" binding:"required"`             // 用户id
+}
+
+type DeleteTeamUserForm struct {
+	BaseForm
+
+	Id     models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"`             // 团队ID
+	UserId models.Id `uri:"userId" form:"userId" json:"userId" binding:"" swaggerignore:"true"` // 用户ID
+}
+
+type CreateProjectTeamForm struct {
+	BaseForm
+
+	TeamId models.Id `json:"teamId" form:"teamId" binding:"required"`                                     // 团队id
+	Role   string    `json:"role" form:"role" binding:"required" enums:"manager,approver,operator,guest"` // 角色 (manager,approver,operator,guest)
+}
+
+type SearchProjectTeamForm struct {
+	PageForm
+}
+
+type UpdateProjectTeamForm struct {
+	BaseForm
+
+	Id   uint   `uri:"id" json:"id" form:"id" swaggerignore:"true"`               // 项目团队授权id
+	Role string `json:"role" form:"role" enums:"manager,approver,operator,guest"` // 角色 (manager,approver,operator,guest)
+}
+
+type DeleteProjectTeamForm struct {
+	BaseForm
+
+	Id uint `uri:"id" json:"id" form:"id" swaggerignore:"true"` // 项目团队授权id
+}
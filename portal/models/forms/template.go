@@ -29,6 +29,8 @@ type CreateTemplateForm struct {
 	Variables         []Variables `json:"variables" form:"variables" `
 	DeleteVariablesId []string    `json:"deleteVariablesId" form:"deleteVariablesId" ` //变量id
 	ProjectId         []models.Id `form:"projectId" json:"projectId"`                  // 项目ID
+
+	Labels models.Labels `form:"labels" json:"labels"` // 标签
 }
 
 type SearchTemplateForm struct {
@@ -36,6 +38,7 @@ type SearchTemplateForm struct {
 
 	Q      string `form:"q" json:"q" binding:""`
 	Status string `form:"status" json:"status"`
+	Labels string `form:"labels" json:"labels" example:"team=payments"` // 标签选择器，多个条件以逗号分隔
 }
 
 type UpdateTemplateForm struct {
@@ -55,6 +58,8 @@ type UpdateTemplateForm struct {
 	RepoRevision      string      `form:"repoRevision" json:"repoRevision" binding:""`
 	VcsId             models.Id   `form:"vcsId" json:"vcsId" binding:""`
 	RepoId            string      `form:"repoId" json:"repoId" binding:""`
+
+	Labels models.Labels `form:"labels" json:"labels"` // 标签，会覆盖模板现有的标签
 }
 
 type DeleteTemplateForm struct {
@@ -15,6 +15,27 @@ type CreateTokenForm struct {
 	Description string    `json:"description" form:"description" ` //描述
 	EnvId       models.Id `json:"envId" form:"envId"`              //创建触发器token时必传，其他可不传
 	Action      string    `json:"action" form:"action"`            //创建触发器token时必传，其他可不传('apply','plan','destroy')
+
+	ProjectRole string             `json:"projectRole" form:"projectRole" enums:"manager,approver,operator,guest"` // 服务账号在授权项目下的角色
+	Scopes      models.TokenScopes `json:"scopes" form:"scopes"`                                                   // 授权范围
+}
+
+type CreatePersonalTokenForm struct {
+	BaseForm
+
+	ExpiredAt   string             `json:"expiredAt" form:"expiredAt" `     // 过期时间
+	Description string             `json:"description" form:"description" ` // 描述
+	Scopes      models.TokenScopes `json:"scopes" form:"scopes"`            // 授权范围
+}
+
+type SearchPersonalTokenForm struct {
+	PageForm
+}
+
+type DeletePersonalTokenForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"required" swaggerignore:"true"`
 }
 
 type UpdateTokenForm struct {
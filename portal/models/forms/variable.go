@@ -15,11 +15,12 @@ type BatchUpdateVariableForm struct {
 type Variables struct {
 	Id          models.Id `json:"id" form:"id" `
 	Scope       string    `json:"scope" form:"scope" `             // 应用范围 ('org','template','project','env')
-	Type        string    `json:"type" form:"type" `               // 类型 ('environment','terraform','ansible')
+	Type        string    `json:"type" form:"type" `               // 类型 ('environment','terraform','ansible','file')
 	Name        string    `json:"name" form:"name" `               // 名称
 	Value       string    `json:"value" form:"value" `             // VALUE
 	Sensitive   bool      `json:"sensitive" form:"sensitive" `     // 是否加密
 	Description string    `json:"description" form:"description" ` // 描述
+	Path        string    `json:"path" form:"path" `               // 文件变量写入的路径(相对于任务工作目录)，为空时使用 files/{name}
 }
 
 type SearchVariableForm struct {
@@ -28,3 +29,35 @@ type SearchVariableForm struct {
 	EnvId models.Id `json:"envId" form:"envId" `                   // 环境id
 	Scope string    `json:"scope" form:"scope" binding:"required"` // 应用范围
 }
+
+type SearchVariableHistoryForm struct {
+	PageForm
+	TplId      models.Id `json:"tplId" form:"tplId" `                   // 模板id
+	EnvId      models.Id `json:"envId" form:"envId" `                   // 环境id
+	Scope      string    `json:"scope" form:"scope" binding:"required"` // 应用范围 ('org','template','project','env')
+	Name       string    `json:"name" form:"name" `                     // 变量名称
+	VariableId models.Id `json:"variableId" form:"variableId" `         // 变量id
+}
+
+type ImportVariableForm struct {
+	BaseForm
+	TplId     models.Id `json:"tplId" form:"tplId" `                                                      // 模板id，scope 为 template 时必填
+	EnvId     models.Id `json:"envId" form:"envId" `                                                      // 环境id，scope 为 env 时必填
+	Scope     string    `json:"scope" form:"scope" binding:"required,oneof=org template project env"`     // 应用范围
+	Type      string    `json:"type" form:"type" binding:"omitempty,oneof=environment terraform ansible"` // 导入的变量类型，默认 tfvars 及 json 文件为 terraform，yaml 文件为 ansible
+	Format    string    `json:"format" form:"format" binding:"required,oneof=tfvars json yaml"`           // 文件格式: tfvars, json(.tfvars.json), yaml(ansible vars)
+	Content   string    `json:"content" form:"content" binding:"required"`                                // 文件内容
+	Sensitive bool      `json:"sensitive" form:"sensitive" `                                              // 是否作为敏感变量导入
+	Overwrite bool      `json:"overwrite" form:"overwrite" `                                              // 是否覆盖值不同的同名变量，默认作为冲突不做处理
+	DryRun    bool      `json:"dryRun" form:"dryRun" `                                                    // 只返回预览结果，不做修改
+}
+
+type ExportVariableForm struct {
+	BaseForm
+	TplId            models.Id `json:"tplId" form:"tplId" `                                                      // 模板id，scope 为 template 时必填
+	EnvId            models.Id `json:"envId" form:"envId" `                                                      // 环境id，scope 为 env 时必填
+	Scope            string    `json:"scope" form:"scope" binding:"required,oneof=org template project env"`     // 应用范围
+	Type             string    `json:"type" form:"type" binding:"omitempty,oneof=environment terraform ansible"` // 导出的变量类型，默认 tfvars 及 json 文件为 terraform，yaml 文件为 ansible
+	Format           string    `json:"format" form:"format" binding:"required,oneof=tfvars json yaml"`           // 文件格式: tfvars, json(.tfvars.json), yaml(ansible vars)
+	IncludeSensitive bool      `json:"includeSensitive" form:"includeSensitive" `                                // 是否导出敏感变量的值，需要组织管理员或项目管理员权限
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package forms
+
+import "cloudiac/portal/models"
+
+type CreateVariableSetForm struct {
+	BaseForm
+
+	Name        string      `json:"name" form:"name" binding:"required,gte=2,lte=64"` // 变量集名称
+	Description string      `json:"description" form:"description"`                   // 变量集描述
+	Variables   []Variables `json:"variables" form:"variables"`                       // 变量列表，变量的 scope 及 id 字段无需传入
+}
+
+type SearchVariableSetForm struct {
+	PageForm
+
+	Q string `form:"q" json:"q"` // 模糊搜索变量集名称及描述
+}
+
+type DetailVariableSetForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 变量集ID
+}
+
+type UpdateVariableSetForm struct {
+	BaseForm
+
+	Id          models.Id   `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 变量集ID
+	Name        string      `json:"name" form:"name" binding:"omitempty,gte=2,lte=64"`     // 变量集名称
+	Description string      `json:"description" form:"description"`                        // 变量集描述
+	Variables   []Variables `json:"variables" form:"variables"`                            // 变量列表，传入时替换全部变量，敏感变量的值为空表示不修改
+}
+
+type DeleteVariableSetForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 变量集ID
+}
+
+type CreateVariableSetAttachmentForm struct {
+	BaseForm
+
+	VariableSetId models.Id `form:"variableSetId" json:"variableSetId" binding:"required"` // 变量集ID
+	EnvId         models.Id `form:"envId" json:"envId"`                                    // 环境ID，为空表示关联到当前项目
+}
+
+type SearchVariableSetAttachmentForm struct {
+	PageForm
+
+	EnvId         models.Id `form:"envId" json:"envId"`                 // 环境ID，传入时返回环境及其所属项目的关联
+	VariableSetId models.Id `form:"variableSetId" json:"variableSetId"` // 变量集ID
+}
+
+type DeleteVariableSetAttachmentForm struct {
+	BaseForm
+
+	Id models.Id `uri:"id" form:"id" json:"id" binding:"" swaggerignore:"true"` // 关联关系ID
+}
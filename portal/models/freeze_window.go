@@ -0,0 +1,42 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import (
+	"cloudiac/utils"
+	"time"
+)
+
+// FreezeWindow 变更冻结窗口，窗口期内禁止对环境执行 apply 和 destroy 任务
+type FreezeWindow struct {
+	TimedModel
+
+	OrgId     Id       `json:"orgId" gorm:"size:32;not null;index;comment:组织ID" example:"org-c3et0lo6n88kr92mjgq0"`    // 组织ID
+	ProjectId Id       `json:"projectId" gorm:"size:32;default:'';comment:项目ID" example:"p-c3ek0co6n88ldvq1n6ag"`      // 项目ID，为空表示组织级冻结窗口
+	Name      string   `json:"name" gorm:"not null;comment:窗口名称" example:"国庆封网"`                                       // 窗口名称
+	Reason    string   `json:"reason" gorm:"type:text;comment:冻结原因"`                                                   // 冻结原因
+	StartAt   Time     `json:"startAt" gorm:"type:datetime;not null;comment:开始时间" example:"2021-10-01T00:00:00+08:00"` // 开始时间
+	EndAt     Time     `json:"endAt" gorm:"type:datetime;not null;comment:结束时间" example:"2021-10-08T00:00:00+08:00"`   // 结束时间
+	EnvIds    StrSlice `json:"envIds" gorm:"type:json;comment:冻结的环境"`                                                  // 冻结的环境ID列表，为空表示范围内的所有环境
+	CreatorId Id       `json:"creatorId" gorm:"size:32;not null;comment:创建人"`                                          // 创建人ID
+}
+
+func (FreezeWindow) TableName() string {
+	return "iac_freeze_window"
+}
+
+// IsActive 判断冻结窗口在指定时间是否生效
+func (w *FreezeWindow) IsActive(at time.Time) bool {
+	return !at.Before(time.Time(w.StartAt)) && at.Before(time.Time(w.EndAt))
+}
+
+// Covers 判断冻结窗口是否作用于指定环境
+func (w *FreezeWindow) Covers(env *Env) bool {
+	if env.OrgId != w.OrgId {
+		return false
+	}
+	if w.ProjectId != "" && env.ProjectId != w.ProjectId {
+		return false
+	}
+	return len(w.EnvIds) == 0 || utils.StrInArray(string(env.Id), w.EnvIds...)
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxLabelCount       = 20
+	maxLabelValueLength = 63
+
+	LabelOpEqual     = "="
+	LabelOpNotEqual  = "!="
+	LabelOpExists    = "exists"
+	LabelOpNotExists = "!exists"
+)
+
+// 标签 key 只允许字母、数字及 ._-/，会被用于拼接 json 路径
+var labelKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/-]{0,62}$`)
+
+// Labels 资源标签，用于筛选环境、模板及匹配通知
+type Labels map[string]string
+
+func (v Labels) Value() (driver.Value, error) {
+	return MarshalValue(v)
+}
+
+func (v *Labels) Scan(value interface{}) error {
+	return UnmarshalValue(value, v)
+}
+
+// Validate 检查标签的数量及 key/value 格式
+func (v Labels) Validate() error {
+	if len(v) > maxLabelCount {
+		return fmt.Errorf("too many labels, max %d", maxLabelCount)
+	}
+	for key, value := range v {
+		if !labelKeyRegex.MatchString(key) {
+			return fmt.Errorf("invalid label key '%s'", key)
+		}
+		if utf8.RuneCountInString(value) > maxLabelValueLength || strings.ContainsAny(value, ",=!\"") {
+			return fmt.Errorf("invalid label value '%s'", value)
+		}
+	}
+	return nil
+}
+
+// LabelRequirement 标签选择器中的一个条件
+type LabelRequirement struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+func (r LabelRequirement) Matches(labels Labels) bool {
+	value, exists := labels[r.Key]
+	switch r.Op {
+	case LabelOpEqual:
+		return exists && value == r.Value
+	case LabelOpNotEqual:
+		// 与 kubernetes 一致，不存在该标签也视为不相等
+		return !exists || value != r.Value
+	case LabelOpExists:
+		return exists
+	case LabelOpNotExists:
+		return !exists
+	}
+	return false
+}
+
+// LabelSelector 标签选择器，所有条件都满足时匹配
+type LabelSelector []LabelRequirement
+
+func (s LabelSelector) Matches(labels Labels) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s LabelSelector) String() string {
+	parts := make([]string, 0, len(s))
+	for _, r := range s {
+		switch r.Op {
+		case LabelOpExists:
+			parts = append(parts, r.Key)
+		case LabelOpNotExists:
+			parts = append(parts, "!"+r.Key)
+		default:
+			parts = append(parts, r.Key+r.Op+r.Value)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// ParseLabelSelector 解析标签选择器，多个条件以逗号分隔，支持 key=value、key!=value、key(存在)、!key(不存在)，
+// 如: team=payments,tier!=prod
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	selector := make(LabelSelector, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		r := LabelRequirement{}
+		if i := strings.Index(part, "!="); i >= 0 {
+			r = LabelRequirement{Key: part[:i], Op: LabelOpNotEqual, Value: part[i+2:]}
+		} else if i := strings.Index(part, "="); i >= 0 {
+			r = LabelRequirement{Key: part[:i], Op: LabelOpEqual, Value: strings.TrimPrefix(part[i+1:], "=")}
+		} else if strings.HasPrefix(part, "!") {
+			r = LabelRequirement{Key: part[1:], Op: LabelOpNotExists}
+		} else {
+			r = LabelRequirement{Key: part, Op: LabelOpExists}
+		}
+
+		r.Key, r.Value = strings.TrimSpace(r.Key), strings.TrimSpace(r.Value)
+		if !labelKeyRegex.MatchString(r.Key) {
+			return nil, fmt.Errorf("invalid label key '%s'", r.Key)
+		}
+		if strings.ContainsAny(r.Value, "=!\"") {
+			return nil, fmt.Errorf("invalid label value '%s'", r.Value)
+		}
+		selector = append(selector, r)
+	}
+	return selector, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import "testing"
+
+func TestParseLabelSelector(t *testing.T) {
+	cases := []struct {
+		selector string
+		expect   string
+		ok       bool
+	}{
+		{"team=payments,tier!=prod", "team=payments,tier!=prod", true},
+		{" team == payments , ", "team=payments", true},
+		{"owner,!deprecated", "!deprecated,owner", true},
+		{"", "", true},
+		{"=prod", "", false},
+		{"team=a=b", "", false},
+		{`te"am=a`, "", false},
+	}
+
+	for _, c := range cases {
+		s, err := ParseLabelSelector(c.selector)
+		if (err == nil) != c.ok {
+			t.Errorf("ParseLabelSelector(%q) error = %v, want ok %v", c.selector, err, c.ok)
+			continue
+		}
+		if err == nil && s.String() != c.expect {
+			t.Errorf("ParseLabelSelector(%q) = %q, want %q", c.selector, s.String(), c.expect)
+		}
+	}
+}
+
+func TestLabelSelectorMatches(t *testing.T) {
+	labels := Labels{"team": "payments", "tier": "staging"}
+	cases := []struct {
+		selector string
+		match    bool
+	}{
+		{"team=payments", true},
+		{"team=payments,tier!=prod", true},
+		{"team=payments,tier=prod", false},
+		{"region!=cn", true},
+		{"region", false},
+		{"!region,team", true},
+		{"", true},
+	}
+
+	for _, c := range cases {
+		s, err := ParseLabelSelector(c.selector)
+		if err != nil {
+			t.Fatalf("ParseLabelSelector(%q) error: %v", c.selector, err)
+		}
+		if s.Matches(labels) != c.match {
+			t.Errorf("selector %q matches = %v, want %v", c.selector, !c.match, c.match)
+		}
+	}
+}
+
+func TestLabelsValidate(t *testing.T) {
+	if err := (Labels{"team": "payments", "app.kubernetes.io/name": "web"}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, labels := range []Labels{{"": "a"}, {"-team": "a"}, {"team": "a,b"}, {"team": "a=b"}} {
+		if err := labels.Validate(); err == nil {
+			t.Errorf("expect error for labels %v", labels)
+		}
+	}
+}
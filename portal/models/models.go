@@ -200,11 +200,26 @@ func Init(migrate bool) {
 	autoMigrate(&User{}, sess)
 	autoMigrate(&UserOrg{}, sess)
 	autoMigrate(&UserProject{}, sess)
+	autoMigrate(&Team{}, sess)
+	autoMigrate(&TeamUser{}, sess)
+	autoMigrate(&TeamProject{}, sess)
+	autoMigrate(&FreezeWindow{}, sess)
+	autoMigrate(&ApprovalPolicy{}, sess)
+	autoMigrate(&TaskApproval{}, sess)
+	autoMigrate(&EnvDependency{}, sess)
+	autoMigrate(&EnvSchedule{}, sess)
+	autoMigrate(&EnvScheduleRun{}, sess)
+	autoMigrate(&BulkOperation{}, sess)
+	autoMigrate(&BulkOperationItem{}, sess)
 
 	autoMigrate(&NotificationCfg{}, sess)
 	autoMigrate(&SystemCfg{}, sess)
 	autoMigrate(&ResourceAccount{}, sess)
 	autoMigrate(&CtResourceMap{}, sess)
+	autoMigrate(&ResourceAccountBinding{}, sess)
+	autoMigrate(&VariableSet{}, sess)
+	autoMigrate(&VariableSetAttachment{}, sess)
+	autoMigrate(&VariableHistory{}, sess)
 	autoMigrate(&OperationLog{}, sess)
 	autoMigrate(&Token{}, sess)
 	autoMigrate(&Key{}, sess)
@@ -14,6 +14,8 @@ type NotificationCfg struct {
 	EventType        string `json:"eventType" gorm:"type:enum('all','failure');default:'failure';comment:事件类型"`
 	UserId           Id     `json:"userId" gorm:"size:32;comment:用户ID"`
 	CfgInfo          JSON   `json:"cfgInfo" gorm:"type:json;null;comment:通知配置"`
+	// 为空时接收组织下所有环境的通知，否则只接收标签匹配的环境的通知，如 team=payments,tier!=prod
+	LabelSelector string `json:"labelSelector" gorm:"default:'';comment:环境标签选择器"`
 }
 
 func (NotificationCfg) TableName() string {
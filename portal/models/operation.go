@@ -4,24 +4,55 @@ package models
 
 import (
 	"cloudiac/portal/libs/db"
+	"encoding/json"
+)
+
+const (
+	OperationCreate   = "create"
+	OperationUpdate   = "update"
+	OperationDelete   = "delete"
+	OperationApprove  = "approve"
+	OperationReject   = "reject"
+	OperationDeploy   = "deploy"
+	OperationDestroy  = "destroy"
+	OperationReveal   = "reveal"   // 查看敏感数据
+	OperationOverride = "override" // 紧急变更，忽略冻结窗口
 )
 
 type OperationLog struct {
 	BaseModel
 
-	UserID        Id     `json:"userId" form:"userId" gorm:"size:32"`
-	Username      string `json:"username" form:"username" `
-	UserAddr      string `json:"userAddr" form:"userAddr" `
-	OperationAt   Time   `json:"operationAt"  gorm:"type:datetime" form:"operationAt" `
-	OperationType string `json:"operationType" form:"operationType" `
-	OperationInfo string `json:"operationInfo" form:"operationInfo" `
-	Desc          JSON   `json:"desc" form:"desc" gorm:"type:text"`
+	OrgId         Id     `json:"orgId" form:"orgId" gorm:"size:32;default:'';index"`          // 组织ID
+	ProjectId     Id     `json:"projectId" form:"projectId" gorm:"size:32;default:''"`        // 项目ID
+	UserID        Id     `json:"userId" form:"userId" gorm:"size:32"`                         // 操作人ID
+	Username      string `json:"username" form:"username" `                                   // 操作人名称
+	UserAddr      string `json:"userAddr" form:"userAddr" `                                   // 操作人IP
+	OperationAt   Time   `json:"operationAt"  gorm:"type:datetime;index" form:"operationAt" ` // 操作时间
+	OperationType string `json:"operationType" form:"operationType" `                         // 操作类型(create/update/delete/approve/deploy/reveal...)
+	ResourceType  string `json:"resourceType" form:"resourceType" gorm:"size:32;default:''"`  // 资源类型，如 envs、tasks、variables
+	ResourceId    Id     `json:"resourceId" form:"resourceId" gorm:"size:32;default:''"`      // 资源ID
+	OperationInfo string `json:"operationInfo" form:"operationInfo" `                         // 操作描述
+	Desc          JSON   `json:"desc" form:"desc" gorm:"type:text" swaggertype:"object"`      // 操作详情，记录变更前后的数据
 }
 
 func (o *OperationLog) InsertLog() error {
+	if o.Id == "" {
+		o.Id = NewId("log")
+	}
 	return db.Get().Insert(o)
 }
 
 func (OperationLog) TableName() string {
 	return "iac_operation_log"
 }
+
+// OperationDesc 操作详情，Before 与 After 分别为变更前后的数据
+type OperationDesc struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+func NewOperationDesc(before, after interface{}) JSON {
+	bs, _ := json.Marshal(OperationDesc{Before: before, After: after})
+	return JSON(bs)
+}
@@ -14,8 +14,22 @@ type ResourceAccount struct {
 	Description string `json:"description" gorm:"size:255;comment:资源账号描述"`
 	Params      JSON   `json:"params" gorm:"type:json;null;comment:账号变量"`
 	Status      string `json:"status" gorm:"type:enum('enable','disable');default:'enable';comment:资源账号状态"`
+	Type        string `json:"type" gorm:"type:enum('static','sts');default:'static';comment:账号类型" enums:"static,sts"`     // 账号类型，static固定凭证,sts通过扮演角色获取临时凭证
+	Provider    string `json:"provider" gorm:"size:32;default:'';comment:云厂商" enums:"aliyun,aws,azure,vsphere,kubernetes"` // 云厂商，静态账号指定云厂商时账号参数按云厂商的参数格式校验
+
+	// 以下字段只用于 sts 类型的账号，任务执行时使用基础凭证扮演角色，只将临时凭证注入任务
+	RoleArn         string `json:"roleArn" gorm:"size:255;default:'';comment:角色ARN"`               // 扮演的角色 ARN
+	ExternalId      string `json:"externalId" gorm:"size:255;default:'';comment:外部ID"`             // 扮演角色时的 external id(只用于 aws)
+	Region          string `json:"region" gorm:"size:64;default:'';comment:区域"`                    // STS 接口所在区域，同时作为默认区域注入任务
+	AccessKeyId     string `json:"accessKeyId" gorm:"size:128;default:'';comment:基础凭证AccessKeyId"` // 基础凭证 AccessKeyId
+	AccessKeySecret string `json:"-" gorm:"size:512;default:'';comment:基础凭证AccessKeySecret(加密)"`   // 基础凭证 AccessKeySecret，加密保存，不返回
 }
 
+const (
+	ResourceAccountStatic = "static"
+	ResourceAccountSts    = "sts"
+)
+
 func (ResourceAccount) TableName() string {
 	return "iac_resource_account"
 }
@@ -48,3 +62,28 @@ func (c CtResourceMap) Migrate(sess *db.Session) (err error) {
 
 	return nil
 }
+
+// ResourceAccountBinding 资源账号与项目或环境的绑定关系，EnvId 为空表示绑定到项目下的所有环境
+type ResourceAccountBinding struct {
+	TimedModel
+
+	OrgId             Id `json:"orgId" gorm:"size:32;not null;comment:组织ID"`               // 组织ID
+	ProjectId         Id `json:"projectId" gorm:"size:32;not null;comment:项目ID"`           // 项目ID
+	EnvId             Id `json:"envId" gorm:"size:32;not null;default:'';comment:环境ID"`    // 环境ID，为空表示绑定到项目
+	ResourceAccountId Id `json:"resourceAccountId" gorm:"size:32;not null;comment:资源账号ID"` // 资源账号ID
+	CreatorId         Id `json:"creatorId" gorm:"size:32;not null;comment:创建人"`            // 创建人ID
+}
+
+func (ResourceAccountBinding) TableName() string {
+	return "iac_resource_account_binding"
+}
+
+func (b ResourceAccountBinding) Migrate(sess *db.Session) (err error) {
+	return b.AddUniqueIndex(sess, "unique__project__env__account", "project_id", "env_id", "resource_account_id")
+}
+
+type ResourceAccountBindingResp struct {
+	ResourceAccountBinding
+	ResourceAccountName string `json:"resourceAccountName"` // 资源账号名称
+	EnvName             string `json:"envName"`             // 环境名称
+}
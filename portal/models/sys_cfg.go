@@ -9,8 +9,12 @@ import (
 const (
 	SysCfgNameMaxJobsPerRunner = "MAX_JOBS_PER_RUNNER"
 	SysCfgNamePeriodOfLogSave  = "PERIOD_OF_LOG_SAVE"
+
+	SysCfgNamePeriodOfAuditLogSave = "PERIOD_OF_AUDIT_LOG_SAVE" // 审计日志保存天数，Permanent 表示永久保存
 )
 
+const SysCfgValuePermanent = "Permanent"
+
 type SystemCfg struct {
 	BaseModel
 
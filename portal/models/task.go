@@ -26,6 +26,7 @@ type TaskResult struct {
 	ResAdded     int `json:"resAdded"`
 	ResChanged   int `json:"resChanged"`
 	ResDestroyed int `json:"resDestroyed"`
+	ResDrifted   int `json:"resDrifted"` // refresh 任务检测到的与 state 不一致的资源数量
 
 	Outputs map[string]interface{} `json:"outputs"`
 }
@@ -41,6 +42,10 @@ func (v *TaskResult) Scan(value interface{}) error {
 type TaskExtra struct {
 	Source       string `json:"source,omitempty"`
 	TransitionId string `json:"transitionId,omitempty"`
+
+	FreezeOverride bool `json:"freezeOverride,omitempty"` // 紧急变更，忽略冻结窗口(仅平台管理员)
+	ReuseWorkspace bool `json:"reuseWorkspace,omitempty"` // 重试任务复用原任务在 runner 上的工作目录
+	RollbackTaskId Id   `json:"rollbackTaskId,omitempty"` // 回滚任务使用的历史任务ID
 }
 
 func (v TaskExtra) Value() (driver.Value, error) {
@@ -55,6 +60,10 @@ const (
 	TaskTypePlan    = common.TaskTypePlan
 	TaskTypeApply   = common.TaskTypeApply
 	TaskTypeDestroy = common.TaskTypeDestroy
+	TaskTypeImport  = common.TaskTypeImport
+	TaskTypeState   = common.TaskTypeState
+	TaskTypeRefresh = common.TaskTypeRefresh
+	TaskTypeOutput  = common.TaskTypeOutput
 
 	TaskPending   = common.TaskPending
 	TaskRunning   = common.TaskRunning
@@ -74,19 +83,21 @@ type Task struct {
 	Name      string `json:"name" gorm:"not null;comment:任务名称"` // 任务名称
 	CreatorId Id     `json:"creatorId" gorm:"size:32;not null"`   // 创建人ID
 
-	Type string `json:"type" gorm:"not null;enum('plan', 'apply', 'destroy')" enums:"'plan', 'apply', 'destroy'"` // 任务类型。1. plan: 计划 2. apply: 部署 3. destroy: 销毁
+	Type string `json:"type" gorm:"not null;enum('plan', 'apply', 'destroy', 'import', 'state', 'refresh', 'output')" enums:"'plan', 'apply', 'destroy', 'import', 'state', 'refresh', 'output'"` // 任务类型。1. plan: 计划 2. apply: 部署 3. destroy: 销毁 4. import: 导入资源 5. state: 资源状态操作 6. refresh: 同步资源状态 7. output: 读取 outputs
 
 	RepoAddr string `json:"repoAddr" gorm:"not null"`
 	Revision string `json:"revision" gorm:"not null"`
 	CommitId string `json:"commitId" gorm:"not null"` // 创建任务时 revision 对应的 commit id
 
+	RetryOf Id `json:"retryOf" gorm:"size:32;default:'';comment:重试的原任务ID"` // 重试的原任务ID
+
 	Workdir      string   `json:"workdir" gorm:"default:''"`
 	Playbook     string   `json:"playbook" gorm:"default:''"`
 	TfVarsFile   string   `json:"tfVarsFile" gorm:"default:''"`
 	PlayVarsFile string   `json:"playVarsFile" gorm:"default:''"`
 	Targets      StrSlice `json:"targets" gorm:"type:json"` // 指定 terraform target 参数
 
-	Variables TaskVariables `json:"variables" gorm:"type:json"` // 本次执行使用的所有变量(继承、覆盖计算之后的)
+	Variables TaskVariables `json:"variables" gorm:"type:json"` // 本次执行使用的所有变量(继承、覆盖计算之后的)，scope 及 varSetName 表示变量的来源
 
 	StatePath string `json:"statePath" gorm:"not null"`
 
@@ -142,9 +153,15 @@ func (t *Task) IsEffectTask() bool {
 	return t.IsEffectTaskType(t.Type)
 }
 
-// IsEffectTaskType 是否产生实际数据变动的任务类型
+// IsEffectTaskType 是否产生实际数据变动(包括同步 state 及 outputs)的任务类型
 func (Task) IsEffectTaskType(typ string) bool {
-	return utils.StrInArray(typ, TaskTypeApply, TaskTypeDestroy)
+	return utils.StrInArray(typ, TaskTypeApply, TaskTypeDestroy, TaskTypeImport, TaskTypeState,
+		TaskTypeRefresh, TaskTypeOutput)
+}
+
+// IsSyncTaskType 是否只同步 state 或 outputs 的任务类型，这类任务会更新环境资源及 outputs，但不影响环境状态
+func (Task) IsSyncTaskType(typ string) bool {
+	return utils.StrInArray(typ, TaskTypeRefresh, TaskTypeOutput)
 }
 
 func (Task) GetTaskNameByType(typ string) string {
@@ -155,6 +172,14 @@ func (Task) GetTaskNameByType(typ string) string {
 		return common.TaskTypeApplyName
 	case TaskTypeDestroy:
 		return common.TaskTypeDestroyName
+	case TaskTypeImport:
+		return common.TaskTypeImportName
+	case TaskTypeState:
+		return common.TaskTypeStateName
+	case TaskTypeRefresh:
+		return common.TaskTypeRefreshName
+	case TaskTypeOutput:
+		return common.TaskTypeOutputName
 	default:
 		panic("invalid task type")
 	}
@@ -183,7 +208,7 @@ func (t *Task) Migrate(sess *db.Session) (err error) {
 }
 
 type TaskStepBody struct {
-	Type string   `json:"type" yaml:"type" gorm:"type:enum('init','plan','apply','play','command','destroy')"`
+	Type string   `json:"type" yaml:"type" gorm:"type:enum('init','plan','apply','play','command','destroy','import','state','output')"`
 	Name string   `json:"name,omitempty" yaml:"name" gorm:"size:32;not null"`
 	Args StrSlice `json:"args,omitempty" yaml:"args" gorm:"type:text"`
 }
@@ -193,6 +218,9 @@ const (
 	TaskStepPlan    = common.TaskStepPlan
 	TaskStepApply   = common.TaskStepApply
 	TaskStepDestroy = common.TaskStepDestroy
+	TaskStepImport  = common.TaskStepImport
+	TaskStepState   = common.TaskStepState
+	TaskStepOutput  = common.TaskStepOutput
 	TaskStepPlay    = common.TaskStepPlay
 	TaskStepCommand = common.TaskStepCommand
 	TaskStepCollect = common.TaskStepCollect
@@ -222,13 +250,21 @@ type TaskStep struct {
 	EndAt     *Time  `json:"endAt" gorm:"type:datetime"`
 	LogPath   string `json:"logPath" gorm:""`
 
-	ApproverId Id `json:"approverId" gorm:"size:32;not null"` // 审批者用户 id
+	ApproverId Id `json:"approverId" gorm:"size:32;not null"` // 审批者用户 id(满足审批策略时的最后一个审批者)
+
+	ApprovalExpireAt *Time `json:"approvalExpireAt" gorm:"type:datetime"` // 审批超时时间
+
+	RetryCount int `json:"retryCount" gorm:"default:0"` // 失败后自动重试的次数
 }
 
 func (TaskStep) TableName() string {
 	return "iac_task_step"
 }
 
+func (s *TaskStep) Migrate(sess *db.Session) error {
+	return sess.ModifyModelColumn(s, "type")
+}
+
 func (s *TaskStep) IsStarted() bool {
 	return !utils.StrInArray(s.Status, TaskStepPending, TaskStepApproving)
 }
@@ -241,8 +277,8 @@ func (s *TaskStep) IsApproved() bool {
 	if s.Status == TaskStepRejected {
 		return false
 	}
-	// 只有 apply 和 destroy 步骤需要审批
-	if utils.StrInArray(s.Type, TaskStepApply, TaskStepDestroy) && len(s.ApproverId) == 0 {
+	// 只有 apply、destroy 和 state 步骤需要审批
+	if utils.StrInArray(s.Type, TaskStepApply, TaskStepDestroy, TaskStepState) && len(s.ApproverId) == 0 {
 		return false
 	}
 	return true
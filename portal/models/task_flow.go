@@ -15,6 +15,9 @@ type TaskFlows struct {
 	Plan    TaskFlow `json:"plan" yaml:"plan"`
 	Apply   TaskFlow `json:"apply" yaml:"apply"`
 	Destroy TaskFlow `json:"destroy" yaml:"destroy"`
+	Import  TaskFlow `json:"import" yaml:"import"`
+	Refresh TaskFlow `json:"refresh" yaml:"refresh"`
+	Output  TaskFlow `json:"output" yaml:"output"`
 }
 
 type TaskFlow struct {
@@ -49,6 +52,23 @@ destroy:
     - type: plan
       args: ["-destroy"]
     - type: destroy
+
+import:
+  steps:
+    - type: init
+    - type: import
+
+refresh:
+  steps:
+    - type: init
+    - type: plan
+      args: ["-refresh-only"]
+    - type: apply
+
+output:
+  steps:
+    - type: init
+    - type: output
 `
 
 var defaultTaskFlows TaskFlows
@@ -61,6 +81,12 @@ func GetTaskFlow(flows *TaskFlows, typ string) (TaskFlow, error) {
 		return flows.Apply, nil
 	case common.TaskTypeDestroy:
 		return flows.Destroy, nil
+	case common.TaskTypeImport:
+		return flows.Import, nil
+	case common.TaskTypeRefresh:
+		return flows.Refresh, nil
+	case common.TaskTypeOutput:
+		return flows.Output, nil
 	default:
 		return TaskFlow{}, fmt.Errorf("unknown task type: %v", typ)
 	}
@@ -0,0 +1,69 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import (
+	"cloudiac/portal/libs/db"
+)
+
+// Team 组织下的团队，团队成员继承团队在项目上的角色
+type Team struct {
+	TimedModel
+
+	OrgId       Id     `json:"orgId" gorm:"size:32;not null;comment:组织ID" example:"org-c3et0lo6n88kr92mjgq0"`  // 组织ID
+	Name        string `json:"name" gorm:"not null;comment:团队名称" example:"payments"`                           // 团队名称
+	Description string `json:"description" gorm:"type:text;comment:团队描述"`                                      // 团队描述
+	CreatorId   Id     `json:"creatorId" gorm:"size:32;not null;comment:创建人" example:"u-c3ek0co6n88ldvq1n6ag"` // 创建人ID
+}
+
+func (Team) TableName() string {
+	return "iac_team"
+}
+
+func (t Team) Migrate(sess *db.Session) (err error) {
+	return t.AddUniqueIndex(sess, "unique__org__name", "org_id", "name")
+}
+
+// TeamUser 团队成员
+type TeamUser struct {
+	AutoUintIdModel
+
+	TeamId Id `json:"teamId" gorm:"size:32;not null;comment:团队ID"` // 团队ID
+	UserId Id `json:"userId" gorm:"size:32;not null;comment:用户ID"` // 用户ID
+}
+
+func (TeamUser) TableName() string {
+	return "iac_team_user"
+}
+
+func (t TeamUser) Migrate(sess *db.Session) error {
+	return t.AddUniqueIndex(sess, "unique__team__user", "team_id", "user_id")
+}
+
+// TeamProject 团队在项目下的角色
+type TeamProject struct {
+	AutoUintIdModel
+
+	TeamId    Id     `json:"teamId" gorm:"size:32;not null;comment:团队ID"`                                                                                          // 团队ID
+	ProjectId Id     `json:"projectId" gorm:"size:32;not null;comment:项目ID"`                                                                                       // 项目ID
+	Role      string `json:"role" gorm:"type:enum('manager','approver','operator','guest');default:'operator';comment:角色" enums:"manager,approver,operator,guest"` // 角色
+}
+
+func (TeamProject) TableName() string {
+	return "iac_team_project"
+}
+
+func (t TeamProject) Migrate(sess *db.Session) error {
+	return t.AddUniqueIndex(sess, "unique__team__project", "team_id", "project_id")
+}
+
+type TeamResp struct {
+	Team
+	Creator   string `json:"creator"`   // 创建人名称
+	UserCount int64  `json:"userCount"` // 成员数量
+}
+
+type TeamProjectResp struct {
+	TeamProject
+	TeamName string `json:"teamName"` // 团队名称
+}
@@ -4,6 +4,10 @@ package models
 
 import (
 	"cloudiac/portal/libs/db"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
 )
 
 type Token struct {
@@ -21,6 +25,12 @@ type Token struct {
 	// 触发器需要的字段
 	EnvId  Id     `json:"envId" form:"envId"  gorm:"not null"`
 	Action string `json:"action" form:"action" gorm:"type:enum('apply','plan','destroy');default:'plan'"`
+
+	// 个人访问令牌及服务账号需要的字段
+	UserId      Id          `json:"userId" gorm:"size:32;default:'';comment:个人令牌所属用户"`                                   // 个人访问令牌所属用户
+	ProjectRole string      `json:"projectRole" gorm:"type:enum('','manager','approver','operator','guest');default:''"` // 服务账号在授权项目下的角色
+	Scopes      TokenScopes `json:"scopes" gorm:"type:json;comment:授权范围"`                                                // 授权范围
+	LastUsedAt  *Time       `json:"lastUsedAt" gorm:"type:datetime;comment:最后使用时间"`                                      // 最后使用时间
 }
 
 func (Token) TableName() string {
@@ -35,6 +45,66 @@ func (o Token) Migrate(sess *db.Session) (err error) {
 	return nil
 }
 
+// IsExpired token 是否已过期，未设置过期时间时永不过期
+func (t *Token) IsExpired() bool {
+	return !time.Time(t.ExpiredAt).IsZero() && time.Now().After(time.Time(t.ExpiredAt))
+}
+
+// TokenScopes token 授权范围，各字段为空时表示该维度不做限制
+type TokenScopes struct {
+	Projects []Id     `json:"projects,omitempty"` // 允许访问的项目
+	Envs     []Id     `json:"envs,omitempty"`     // 允许访问的环境
+	Actions  []string `json:"actions,omitempty"`  // 允许的操作，格式为 "资源:动作"，如 tasks:read、envs:deploy，"envs:*" 表示所有动作
+}
+
+func (v TokenScopes) Value() (driver.Value, error) {
+	return MarshalValue(v)
+}
+
+func (v *TokenScopes) Scan(value interface{}) error {
+	return UnmarshalValue(value, v)
+}
+
+func (v TokenScopes) AllowProject(projectId Id) bool {
+	return len(v.Projects) == 0 || idInSlice(projectId, v.Projects)
+}
+
+func (v TokenScopes) AllowEnv(envId Id) bool {
+	return len(v.Envs) == 0 || idInSlice(envId, v.Envs)
+}
+
+func (v TokenScopes) AllowAction(obj string, act string) bool {
+	if len(v.Actions) == 0 {
+		return true
+	}
+	for _, a := range v.Actions {
+		if a == fmt.Sprintf("%s:%s", obj, act) || a == fmt.Sprintf("%s:*", obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 检查 actions 格式
+func (v TokenScopes) Validate() error {
+	for _, a := range v.Actions {
+		parts := strings.Split(a, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid scope action '%s'", a)
+		}
+	}
+	return nil
+}
+
+func idInSlice(id Id, ids []Id) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
 type LoginResp struct {
 	//UserInfo *models.User
 	Token string `json:"token" example:"eyJhbGciO..."` // 登陆令牌
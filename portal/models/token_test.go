@@ -0,0 +1,46 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenScopes(t *testing.T) {
+	scopes := TokenScopes{
+		Envs:    []Id{"env-1"},
+		Actions: []string{"tasks:read", "envs:deploy"},
+	}
+
+	if !scopes.AllowProject("p-1") {
+		t.Errorf("empty projects should not limit project")
+	}
+	if !scopes.AllowEnv("env-1") || scopes.AllowEnv("env-2") {
+		t.Errorf("unexpected env scope result")
+	}
+	if !scopes.AllowAction("tasks", "read") || !scopes.AllowAction("envs", "deploy") {
+		t.Errorf("action in scopes should be allowed")
+	}
+	if scopes.AllowAction("tasks", "approve") || scopes.AllowAction("envs", "destroy") {
+		t.Errorf("action not in scopes should be denied")
+	}
+
+	all := TokenScopes{Actions: []string{"envs:*"}}
+	if !all.AllowAction("envs", "destroy") || all.AllowAction("tasks", "read") {
+		t.Errorf("unexpected wildcard action result")
+	}
+
+	if err := (TokenScopes{Actions: []string{"tasks"}}).Validate(); err == nil {
+		t.Errorf("expect invalid action error")
+	}
+}
+
+func TestTokenIsExpired(t *testing.T) {
+	if (&Token{}).IsExpired() {
+		t.Errorf("token without expire time should not expire")
+	}
+	if !(&Token{ExpiredAt: Time(time.Now().Add(-time.Minute))}).IsExpired() {
+		t.Errorf("token should be expired")
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+const (
+	VariableActionCreate = "create"
+	VariableActionUpdate = "update"
+	VariableActionDelete = "delete"
+
+	// MaskedVariableValue 敏感变量在历史记录及差异中展示的值
+	MaskedVariableValue = "******"
+)
+
+// VariableHistory 变量变更历史，每次新增、修改、删除变量时记录一条，敏感变量的值不做记录
+type VariableHistory struct {
+	TimedModel
+
+	OrgId      Id     `json:"orgId" gorm:"size:32;not null;comment:组织ID"`                                                      // 组织ID
+	ProjectId  Id     `json:"projectId" gorm:"size:32;default:'';comment:项目ID"`                                                // 项目ID
+	TplId      Id     `json:"tplId" gorm:"size:32;default:'';comment:模板ID"`                                                    // 模板ID
+	EnvId      Id     `json:"envId" gorm:"size:32;default:'';comment:环境ID"`                                                    // 环境ID
+	VariableId Id     `json:"variableId" gorm:"size:32;not null;index;comment:变量ID"`                                           // 变量ID
+	Scope      string `json:"scope" gorm:"size:32;not null"`                                                                   // 变量作用域
+	Type       string `json:"type" gorm:"size:32;not null"`                                                                    // 变量类型
+	Name       string `json:"name" gorm:"size:64;not null"`                                                                    // 变量名称
+	Action     string `json:"action" gorm:"type:enum('create','update','delete');not null" enums:"'create','update','delete'"` // 变更操作
+	Sensitive  bool   `json:"sensitive" gorm:"default:false"`                                                                  // 变更后是否为敏感变量(删除时为变更前)
+	OldValue   string `json:"oldValue" gorm:"type:text"`                                                                       // 变更前的值，敏感变量为 ******
+	NewValue   string `json:"newValue" gorm:"type:text"`                                                                       // 变更后的值，敏感变量为 ******
+	OperatorId Id     `json:"operatorId" gorm:"size:32;not null;default:''"`                                                   // 操作人ID
+}
+
+func (VariableHistory) TableName() string {
+	return "iac_variable_history"
+}
+
+type VariableHistoryResp struct {
+	VariableHistory
+	Operator string `json:"operator"` // 操作人
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package models
+
+import (
+	"cloudiac/portal/libs/db"
+	"database/sql/driver"
+)
+
+type VariableSetVariables []VariableBody
+
+func (v VariableSetVariables) Value() (driver.Value, error) {
+	return MarshalValue(v)
+}
+
+func (v *VariableSetVariables) Scan(value interface{}) error {
+	return UnmarshalValue(value, v)
+}
+
+// VariableSet 变量集，组织下一组命名的变量，可以关联到任意项目或环境
+type VariableSet struct {
+	TimedModel
+
+	OrgId       Id                   `json:"orgId" gorm:"size:32;not null;comment:组织ID"`    // 组织ID
+	Name        string               `json:"name" gorm:"size:64;not null;comment:变量集名称"`    // 变量集名称
+	Description string               `json:"description" gorm:"size:255;comment:变量集描述"`     // 变量集描述
+	Variables   VariableSetVariables `json:"variables" gorm:"type:json;comment:变量列表"`       // 变量列表，敏感变量的值加密保存
+	CreatorId   Id                   `json:"creatorId" gorm:"size:32;not null;comment:创建人"` // 创建人ID
+}
+
+func (VariableSet) TableName() string {
+	return "iac_variable_set"
+}
+
+func (s VariableSet) Migrate(sess *db.Session) error {
+	return s.AddUniqueIndex(sess, "unique__org__name", "org_id", "name")
+}
+
+func (s *VariableSet) HideSensitiveVariable() {
+	for index, v := range s.Variables {
+		if v.Sensitive {
+			s.Variables[index].Value = ""
+		}
+	}
+}
+
+// VariableSetAttachment 变量集与项目或环境的关联关系，EnvId 为空表示关联到项目下的所有环境
+type VariableSetAttachment struct {
+	TimedModel
+
+	OrgId         Id `json:"orgId" gorm:"size:32;not null;comment:组织ID"`            // 组织ID
+	ProjectId     Id `json:"projectId" gorm:"size:32;not null;comment:项目ID"`        // 项目ID
+	EnvId         Id `json:"envId" gorm:"size:32;not null;default:'';comment:环境ID"` // 环境ID，为空表示关联到项目
+	VariableSetId Id `json:"variableSetId" gorm:"size:32;not null;comment:变量集ID"`   // 变量集ID
+	CreatorId     Id `json:"creatorId" gorm:"size:32;not null;comment:创建人"`         // 创建人ID
+}
+
+func (VariableSetAttachment) TableName() string {
+	return "iac_variable_set_attachment"
+}
+
+func (a VariableSetAttachment) Migrate(sess *db.Session) error {
+	return a.AddUniqueIndex(sess, "unique__project__env__variable_set", "project_id", "env_id", "variable_set_id")
+}
+
+type VariableSetAttachmentResp struct {
+	VariableSetAttachment
+	VariableSetName string `json:"variableSetName"` // 变量集名称
+	EnvName         string `json:"envName"`         // 环境名称
+}
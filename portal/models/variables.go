@@ -10,11 +10,16 @@ type VariableBody struct {
 
 	// 继承关系依赖数据创建枚举的顺序，后续新增枚举值时请按照新的继承顺序增加
 	Scope       string `json:"scope" gorm:"not null;type:enum('org','template','project','env')"`
-	Type        string `json:"type" gorm:"not null;type:enum('environment','terraform','ansible')"`
+	Type        string `json:"type" gorm:"not null;type:enum('environment','terraform','ansible','file')"`
 	Name        string `json:"name" gorm:"size:64;not null"`
 	Value       string `json:"value" gorm:"type:text"`
 	Sensitive   bool   `json:"sensitive,omitempty" gorm:"default:false"`
 	Description string `json:"description,omitempty" gorm:"type:text"`
+	Path        string `json:"path,omitempty" gorm:"size:255;default:''"` // 文件变量写入的路径(相对于任务工作目录)，为空时使用 files/{name}
+
+	// 来自变量集的变量记录变量集信息，用于展示变量的来源
+	VarSetId   Id     `json:"varSetId,omitempty" gorm:"-"`   // 变量集ID
+	VarSetName string `json:"varSetName,omitempty" gorm:"-"` // 变量集名称
 }
 
 type Variable struct {
@@ -41,5 +46,8 @@ func (v Variable) Migrate(sess *db.Session) error {
 	if err := sess.ModifyModelColumn(&v, "value"); err != nil {
 		return err
 	}
+	if err := sess.ModifyModelColumn(&v, "type"); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,129 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"fmt"
+)
+
+func CreateApprovalPolicy(tx *db.Session, policy models.ApprovalPolicy) (*models.ApprovalPolicy, e.Error) {
+	if policy.Id == "" {
+		policy.Id = models.NewId("ap")
+	}
+	if err := models.Create(tx, &policy); err != nil {
+		if e.IsDuplicate(err) {
+			return nil, e.New(e.ApprovalPolicyAlreadyExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &policy, nil
+}
+
+func UpdateApprovalPolicy(tx *db.Session, id models.Id, attrs models.Attrs) (policy *models.ApprovalPolicy, er e.Error) {
+	policy = &models.ApprovalPolicy{}
+	if _, err := models.UpdateAttr(tx.Where("id = ?", id), &models.ApprovalPolicy{}, attrs); err != nil {
+		return nil, e.New(e.DBError, fmt.Errorf("update approval policy error: %v", err))
+	}
+	if err := tx.Where("id = ?", id).First(policy); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.ApprovalPolicyNotExists)
+		}
+		return nil, e.New(e.DBError, fmt.Errorf("query approval policy error: %v", err))
+	}
+	return
+}
+
+func DeleteApprovalPolicy(tx *db.Session, id models.Id) e.Error {
+	if _, err := tx.Where("id = ?", id).Delete(&models.ApprovalPolicy{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete approval policy error: %v", err))
+	}
+	return nil
+}
+
+func QueryApprovalPolicy(query *db.Session) *db.Session {
+	return query.Model(&models.ApprovalPolicy{})
+}
+
+func GetApprovalPolicyById(query *db.Session, id models.Id) (*models.ApprovalPolicy, e.Error) {
+	policy := models.ApprovalPolicy{}
+	if err := query.Model(models.ApprovalPolicy{}).Where("id = ?", id).First(&policy); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.ApprovalPolicyNotExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &policy, nil
+}
+
+// GetEnvApprovalPolicy 获取环境生效的审批策略，优先使用环境级策略，其次为项目级策略，都未配置时返回默认策略
+func GetEnvApprovalPolicy(query *db.Session, projectId models.Id, envId models.Id) (*models.ApprovalPolicy, e.Error) {
+	policy := models.ApprovalPolicy{}
+	err := query.Model(models.ApprovalPolicy{}).
+		Where("project_id = ?", projectId).
+		Where("env_id = '' OR env_id = ?", envId).
+		Order("env_id DESC").
+		First(&policy)
+	if err != nil {
+		if e.IsRecordNotFound(err) {
+			return models.DefaultApprovalPolicy(), nil
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &policy, nil
+}
+
+// LockTaskStep 在事务中锁定任务步骤，用于串行化同一步骤的并发审批
+func LockTaskStep(tx *db.Session, stepId models.Id) e.Error {
+	var id string
+	if err := tx.Raw(fmt.Sprintf("SELECT id FROM %s WHERE id = ? FOR UPDATE",
+		models.TaskStep{}.TableName()), stepId).Row().Scan(&id); err != nil {
+		return e.New(e.DBError, err)
+	}
+	return nil
+}
+
+func CreateTaskApproval(tx *db.Session, approval models.TaskApproval) (*models.TaskApproval, e.Error) {
+	if approval.Id == "" {
+		approval.Id = models.NewId("apv")
+	}
+	if err := models.Create(tx, &approval); err != nil {
+		if e.IsDuplicate(err) {
+			return nil, e.New(e.TaskAlreadyApproved, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &approval, nil
+}
+
+// CountTaskStepApprovals 统计步骤已通过的审批人数
+func CountTaskStepApprovals(query *db.Session, taskId models.Id, step int) (int64, e.Error) {
+	count, err := query.Model(models.TaskApproval{}).
+		Where("task_id = ? AND step = ? AND action = ?", taskId, step, models.ApprovalActionApproved).
+		Count()
+	if err != nil {
+		return 0, e.New(e.DBError, err)
+	}
+	return count, nil
+}
+
+// QueryTaskApprovals 任务的审批记录
+func QueryTaskApprovals(query *db.Session, taskId models.Id) *db.Session {
+	table := models.TaskApproval{}.TableName()
+	return query.Table(table).
+		Joins(fmt.Sprintf("left join %s as u on u.id = %s.user_id", models.User{}.TableName(), table)).
+		Where(fmt.Sprintf("%s.task_id = ?", table), taskId).
+		LazySelectAppend(fmt.Sprintf("%s.*", table), "u.name as username")
+}
+
+// UpdateTaskStepApprovalExpireAt 设置步骤的审批超时时间
+func UpdateTaskStepApprovalExpireAt(tx *db.Session, step *models.TaskStep, expireAt *models.Time) e.Error {
+	if _, err := tx.Model(&models.TaskStep{}).Where("id = ?", step.Id).
+		UpdateColumn("approval_expire_at", expireAt); err != nil {
+		return e.New(e.DBError, err)
+	}
+	step.ApprovalExpireAt = expireAt
+	return nil
+}
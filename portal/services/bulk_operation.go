@@ -0,0 +1,102 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"fmt"
+)
+
+// CreateBulkOperation 创建批量操作及每个环境的执行记录
+func CreateBulkOperation(tx *db.Session, op models.BulkOperation, envIds []models.Id) (*models.BulkOperation, e.Error) {
+	if op.Id == "" {
+		op.Id = models.NewId("bulk")
+	}
+	op.Total = len(envIds)
+	if err := models.Create(tx, &op); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	for _, envId := range envIds {
+		item := models.BulkOperationItem{
+			BulkId: op.Id,
+			EnvId:  envId,
+			Status: models.BulkItemPending,
+		}
+		item.Id = models.NewId("bulki")
+		if err := models.Create(tx, &item); err != nil {
+			return nil, e.New(e.DBError, err)
+		}
+	}
+	return &op, nil
+}
+
+func QueryBulkOperation(query *db.Session) *db.Session {
+	return query.Model(&models.BulkOperation{})
+}
+
+func GetBulkOperationById(query *db.Session, id models.Id) (*models.BulkOperation, e.Error) {
+	op := models.BulkOperation{}
+	if err := query.Model(models.BulkOperation{}).Where("id = ?", id).First(&op); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.BulkOperationNotExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &op, nil
+}
+
+func UpdateBulkOperation(tx *db.Session, id models.Id, attrs models.Attrs) e.Error {
+	if _, err := tx.Model(&models.BulkOperation{}).Where("id = ?", id).UpdateAttrs(attrs); err != nil {
+		return e.New(e.DBError, fmt.Errorf("update bulk operation error: %v", err))
+	}
+	return nil
+}
+
+func UpdateBulkOperationItem(tx *db.Session, item *models.BulkOperationItem, attrs models.Attrs) e.Error {
+	if _, err := tx.Model(&models.BulkOperationItem{}).Where("id = ?", item.Id).UpdateAttrs(attrs); err != nil {
+		return e.New(e.DBError, fmt.Errorf("update bulk operation item error: %v", err))
+	}
+	return nil
+}
+
+// GetRunningBulkOperations 查询执行中的批量操作
+func GetRunningBulkOperations(query *db.Session) ([]*models.BulkOperation, e.Error) {
+	ops := make([]*models.BulkOperation, 0)
+	if err := query.Model(models.BulkOperation{}).Where("status = ?", models.BulkOperationRunning).
+		Order("created_at").Find(&ops); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return ops, nil
+}
+
+func GetBulkOperationItems(query *db.Session, bulkId models.Id) ([]*models.BulkOperationItem, e.Error) {
+	items := make([]*models.BulkOperationItem, 0)
+	if err := query.Model(models.BulkOperationItem{}).Where("bulk_id = ?", bulkId).
+		Order("created_at, id").Find(&items); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return items, nil
+}
+
+// QueryBulkOperationItems 查询批量操作的执行记录，包含环境名称及任务状态
+func QueryBulkOperationItems(query *db.Session, bulkId models.Id) *db.Session {
+	return query.Model(&models.BulkOperationItem{}).
+		Joins("LEFT JOIN iac_env AS env ON env.id = iac_bulk_operation_item.env_id").
+		Joins("LEFT JOIN iac_task AS task ON task.id = iac_bulk_operation_item.task_id").
+		Where("iac_bulk_operation_item.bulk_id = ?", bulkId).
+		LazySelectAppend("iac_bulk_operation_item.*, env.name AS env_name, task.status AS task_status").
+		Order("iac_bulk_operation_item.created_at, iac_bulk_operation_item.id")
+}
+
+// EnvHasActiveTask 环境是否有未结束的任务
+func EnvHasActiveTask(query *db.Session, envId models.Id) (bool, e.Error) {
+	exists, err := query.Model(&models.Task{}).
+		Where("env_id = ? AND status IN (?)", envId,
+			[]string{models.TaskPending, models.TaskRunning, models.TaskApproving}).Exists()
+	if err != nil {
+		return false, e.New(e.DBError, err)
+	}
+	return exists, nil
+}
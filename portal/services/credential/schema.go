@@ -0,0 +1,126 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+// Package credential 定义各云厂商资源账号的参数格式，并提供凭证有效性校验
+package credential
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	ProviderAliyun     = "aliyun"
+	ProviderAws        = "aws"
+	ProviderAzure      = "azure"
+	ProviderVsphere    = "vsphere"
+	ProviderKubernetes = "kubernetes"
+)
+
+// Field 资源账号的参数定义
+type Field struct {
+	Name        string `json:"name"`        // 参数名称
+	EnvVar      string `json:"envVar"`      // 注入任务时使用的环境变量名
+	Required    bool   `json:"required"`    // 是否必填
+	Sensitive   bool   `json:"sensitive"`   // 是否为敏感参数，敏感参数加密保存且不会返回
+	Description string `json:"description"` // 参数说明
+}
+
+// Schema 云厂商资源账号的参数格式
+type Schema struct {
+	Provider string  `json:"provider"` // 云厂商
+	Name     string  `json:"name"`     // 显示名称
+	Fields   []Field `json:"fields"`   // 参数列表
+}
+
+var schemas = map[string]Schema{
+	ProviderAliyun: {
+		Provider: ProviderAliyun,
+		Name:     "阿里云",
+		Fields: []Field{
+			{Name: "access_key", EnvVar: "ALICLOUD_ACCESS_KEY", Required: true, Description: "AccessKey ID"},
+			{Name: "secret_key", EnvVar: "ALICLOUD_SECRET_KEY", Required: true, Sensitive: true, Description: "AccessKey Secret"},
+			{Name: "region", EnvVar: "ALICLOUD_REGION", Description: "默认区域，如 cn-beijing"},
+		},
+	},
+	ProviderAws: {
+		Provider: ProviderAws,
+		Name:     "AWS",
+		Fields: []Field{
+			{Name: "access_key_id", EnvVar: "AWS_ACCESS_KEY_ID", Required: true, Description: "Access key ID"},
+			{Name: "secret_access_key", EnvVar: "AWS_SECRET_ACCESS_KEY", Required: true, Sensitive: true, Description: "Secret access key"},
+			{Name: "region", EnvVar: "AWS_REGION", Description: "默认区域，如 us-east-1"},
+		},
+	},
+	ProviderAzure: {
+		Provider: ProviderAzure,
+		Name:     "Azure",
+		Fields: []Field{
+			{Name: "client_id", EnvVar: "ARM_CLIENT_ID", Required: true, Description: "服务主体的应用 ID"},
+			{Name: "client_secret", EnvVar: "ARM_CLIENT_SECRET", Required: true, Sensitive: true, Description: "服务主体的密码"},
+			{Name: "tenant_id", EnvVar: "ARM_TENANT_ID", Required: true, Description: "租户 ID"},
+			{Name: "subscription_id", EnvVar: "ARM_SUBSCRIPTION_ID", Required: true, Description: "订阅 ID"},
+		},
+	},
+	ProviderVsphere: {
+		Provider: ProviderVsphere,
+		Name:     "vSphere",
+		Fields: []Field{
+			{Name: "server", EnvVar: "VSPHERE_SERVER", Required: true, Description: "vCenter 地址"},
+			{Name: "user", EnvVar: "VSPHERE_USER", Required: true, Description: "用户名"},
+			{Name: "password", EnvVar: "VSPHERE_PASSWORD", Required: true, Sensitive: true, Description: "密码"},
+			{Name: "allow_unverified_ssl", EnvVar: "VSPHERE_ALLOW_UNVERIFIED_SSL", Description: "是否忽略证书校验，true/false"},
+		},
+	},
+	ProviderKubernetes: {
+		Provider: ProviderKubernetes,
+		Name:     "Kubernetes",
+		Fields: []Field{
+			{Name: "host", EnvVar: "KUBE_HOST", Required: true, Description: "API Server 地址"},
+			{Name: "token", EnvVar: "KUBE_TOKEN", Required: true, Sensitive: true, Description: "ServiceAccount token"},
+			{Name: "cluster_ca_certificate", EnvVar: "KUBE_CLUSTER_CA_CERT_DATA", Description: "集群 CA 证书(PEM)"},
+			{Name: "insecure", EnvVar: "KUBE_INSECURE", Description: "是否忽略证书校验，true/false"},
+		},
+	},
+}
+
+func GetSchema(provider string) (*Schema, bool) {
+	s, ok := schemas[provider]
+	return &s, ok
+}
+
+// Schemas 返回所有云厂商的参数格式
+func Schemas() []Schema {
+	rs := make([]Schema, 0, len(schemas))
+	for _, s := range schemas {
+		rs = append(rs, s)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Provider < rs[j].Provider })
+	return rs
+}
+
+func (s *Schema) Field(name string) *Field {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return &s.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Check 检查参数是否包含所有必填字段，values 为参数名称到值的映射
+func (s *Schema) Check(values map[string]string) error {
+	for _, f := range s.Fields {
+		if f.Required && values[f.Name] == "" {
+			return fmt.Errorf("'%s' is required for %s account", f.Name, s.Provider)
+		}
+	}
+	return nil
+}
+
+// EnvVar 返回参数注入任务时使用的环境变量名，未在 schema 中定义的参数直接使用参数名称
+func (s *Schema) EnvVar(name string) string {
+	if f := s.Field(name); f != nil {
+		return f.EnvVar
+	}
+	return name
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package credential
+
+import "testing"
+
+func TestSchemas(t *testing.T) {
+	for _, s := range Schemas() {
+		hasSensitive := false
+		for _, f := range s.Fields {
+			if f.Name == "" || f.EnvVar == "" {
+				t.Errorf("%s: field name and env var must not be empty", s.Provider)
+			}
+			hasSensitive = hasSensitive || f.Sensitive
+		}
+		if !hasSensitive {
+			t.Errorf("%s: schema has no sensitive field", s.Provider)
+		}
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	s, ok := GetSchema(ProviderAws)
+	if !ok {
+		t.Fatalf("aws schema not found")
+	}
+	if err := s.Check(map[string]string{"access_key_id": "ak"}); err == nil {
+		t.Errorf("expect missing secret_access_key error")
+	}
+	if err := s.Check(map[string]string{"access_key_id": "ak", "secret_access_key": "sk"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, ok := GetSchema("unknown"); ok {
+		t.Errorf("expect unknown provider not found")
+	}
+}
+
+func TestSchemaEnvVar(t *testing.T) {
+	s, _ := GetSchema(ProviderAliyun)
+	cases := map[string]string{
+		"access_key": "ALICLOUD_ACCESS_KEY",
+		"secret_key": "ALICLOUD_SECRET_KEY",
+		"TF_LOG":     "TF_LOG",
+	}
+	for name, want := range cases {
+		if got := s.EnvVar(name); got != want {
+			t.Errorf("EnvVar(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
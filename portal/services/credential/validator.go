@@ -0,0 +1,171 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package credential
+
+import (
+	"cloudiac/portal/services/sts"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const requestTimeout = 30 * time.Second
+
+// Validator 凭证校验接口，values 为参数名称到明文值的映射，凭证有效时返回凭证对应的身份信息
+type Validator interface {
+	Validate(ctx context.Context, values map[string]string) (string, error)
+}
+
+// ValidatorFunc 将函数转换为 Validator
+type ValidatorFunc func(ctx context.Context, values map[string]string) (string, error)
+
+func (f ValidatorFunc) Validate(ctx context.Context, values map[string]string) (string, error) {
+	return f(ctx, values)
+}
+
+var (
+	validatorsLock sync.RWMutex
+	validators     = map[string]Validator{
+		ProviderAliyun:     stsValidator{provider: sts.ProviderAliyun, keyField: "access_key", secretField: "secret_key"},
+		ProviderAws:        stsValidator{provider: sts.ProviderAws, keyField: "access_key_id", secretField: "secret_access_key"},
+		ProviderAzure:      &azureValidator{},
+		ProviderVsphere:    ValidatorFunc(validateVsphere),
+		ProviderKubernetes: ValidatorFunc(validateKubernetes),
+	}
+)
+
+// RegisterValidator 注册(替换)云厂商的凭证校验实现
+func RegisterValidator(provider string, v Validator) {
+	validatorsLock.Lock()
+	defer validatorsLock.Unlock()
+	validators[provider] = v
+}
+
+// Validate 校验凭证是否有效
+func Validate(ctx context.Context, provider string, values map[string]string) (string, error) {
+	validatorsLock.RLock()
+	v, ok := validators[provider]
+	validatorsLock.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unsupported provider '%s'", provider)
+	}
+	if schema, ok := GetSchema(provider); ok {
+		if err := schema.Check(values); err != nil {
+			return "", err
+		}
+	}
+	return v.Validate(ctx, values)
+}
+
+// stsValidator 通过 STS GetCallerIdentity 接口校验 AccessKey
+type stsValidator struct {
+	provider    string
+	keyField    string
+	secretField string
+}
+
+func (v stsValidator) Validate(ctx context.Context, values map[string]string) (string, error) {
+	return sts.GetCallerIdentity(ctx, v.provider, values[v.keyField], values[v.secretField], values["region"])
+}
+
+// azureValidator 通过服务主体获取 access token 校验凭证
+type azureValidator struct {
+	endpoint string // 为空时使用 Azure 公有云地址，用于测试
+}
+
+func (v *azureValidator) Validate(ctx context.Context, values map[string]string) (string, error) {
+	endpoint := v.endpoint
+	if endpoint == "" {
+		endpoint = "https://login.microsoftonline.com"
+	}
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", values["client_id"])
+	form.Set("client_secret", values["client_secret"])
+	form.Set("scope", "https://management.azure.com/.default")
+
+	u := fmt.Sprintf("%s/%s/oauth2/v2.0/token", endpoint, url.PathEscape(values["tenant_id"]))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := doCheckRequest(newHttpClient(false, nil), req); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s@%s", values["client_id"], values["tenant_id"]), nil
+}
+
+// validateVsphere 通过创建 vCenter 会话校验用户名密码
+func validateVsphere(ctx context.Context, values map[string]string) (string, error) {
+	server := values["server"]
+	if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+		server = "https://" + server
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(server, "/")+"/rest/com/vmware/cis/session", nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(values["user"], values["password"])
+	if err := doCheckRequest(newHttpClient(values["allow_unverified_ssl"] == "true", nil), req); err != nil {
+		return "", err
+	}
+	return values["user"], nil
+}
+
+// validateKubernetes 使用 token 访问 API Server，token 无效时 API Server 返回 401
+func validateKubernetes(ctx context.Context, values map[string]string) (string, error) {
+	var pool *x509.CertPool
+	if ca := values["cluster_ca_certificate"]; ca != "" {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			return "", fmt.Errorf("invalid cluster_ca_certificate")
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(values["host"], "/")+"/api", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+values["token"])
+	if err := doCheckRequest(newHttpClient(values["insecure"] == "true", pool), req); err != nil {
+		return "", err
+	}
+	return values["host"], nil
+}
+
+func newHttpClient(insecure bool, rootCAs *x509.CertPool) *http.Client {
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: insecure, //nolint:gosec
+				RootCAs:            rootCAs,
+			},
+		},
+	}
+}
+
+// doCheckRequest 发送请求，返回 2xx 以外的状态码时返回错误
+func doCheckRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("authentication failed, status %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package credential
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateRegistered(t *testing.T) {
+	validatorsLock.RLock()
+	origin := validators[ProviderAws]
+	validatorsLock.RUnlock()
+	defer RegisterValidator(ProviderAws, origin)
+
+	RegisterValidator(ProviderAws, ValidatorFunc(func(ctx context.Context, values map[string]string) (string, error) {
+		if values["secret_access_key"] != "sk" {
+			return "", errors.New("invalid secret")
+		}
+		return "arn:aws:iam::123456789012:user/iac", nil
+	}))
+
+	identity, err := Validate(context.Background(), ProviderAws,
+		map[string]string{"access_key_id": "ak", "secret_access_key": "sk"})
+	if err != nil || identity == "" {
+		t.Errorf("identity = %q, err = %v", identity, err)
+	}
+	if _, err := Validate(context.Background(), ProviderAws,
+		map[string]string{"access_key_id": "ak", "secret_access_key": "bad"}); err == nil {
+		t.Errorf("expect validator error")
+	}
+	// 缺少必填参数时不调用校验接口
+	if _, err := Validate(context.Background(), ProviderAws, map[string]string{"access_key_id": "ak"}); err == nil {
+		t.Errorf("expect missing field error")
+	}
+	if _, err := Validate(context.Background(), "unknown", nil); err == nil {
+		t.Errorf("expect unsupported provider error")
+	}
+}
+
+func TestValidateKubernetes(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api" || r.Header.Get("Authorization") != "Bearer good-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+	}))
+	defer srv.Close()
+
+	values := map[string]string{"host": srv.URL, "token": "good-token", "insecure": "true"}
+	if _, err := validateKubernetes(context.Background(), values); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	values["token"] = "bad-token"
+	if _, err := validateKubernetes(context.Background(), values); err == nil {
+		t.Errorf("expect authentication error")
+	}
+	// 未忽略证书校验时自签名证书无法通过校验
+	values["token"] = "good-token"
+	values["insecure"] = ""
+	if _, err := validateKubernetes(context.Background(), values); err == nil {
+		t.Errorf("expect certificate error")
+	}
+}
+
+func TestValidateVsphere(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.Method != http.MethodPost || r.URL.Path != "/rest/com/vmware/cis/session" ||
+			user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":"session-id"}`))
+	}))
+	defer srv.Close()
+
+	values := map[string]string{"server": srv.URL, "user": "admin", "password": "secret"}
+	if _, err := validateVsphere(context.Background(), values); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	values["password"] = "wrong"
+	if _, err := validateVsphere(context.Background(), values); err == nil {
+		t.Errorf("expect authentication error")
+	}
+}
+
+func TestValidateAzure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.URL.Path != "/tenant/oauth2/v2.0/token" || r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"token"}`))
+	}))
+	defer srv.Close()
+
+	v := &azureValidator{endpoint: srv.URL}
+	values := map[string]string{"client_id": "app", "client_secret": "secret", "tenant_id": "tenant"}
+	if _, err := v.Validate(context.Background(), values); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	values["client_secret"] = "wrong"
+	if _, err := v.Validate(context.Background(), values); err == nil {
+		t.Errorf("expect authentication error")
+	}
+}
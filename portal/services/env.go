@@ -120,11 +120,12 @@ func ChangeEnvStatusWithTaskAndStep(tx *db.Session, id models.Id, task *models.T
 	if task.Exited() {
 		switch task.Status {
 		case models.TaskFailed:
-			if step.Status != models.TaskStepRejected {
+			// 同步 state 或 outputs 的任务失败不影响环境状态
+			if step.Status != models.TaskStepRejected && !task.IsSyncTaskType(task.Type) {
 				envStatus = models.EnvStatusFailed
 			}
 		case models.TaskComplete:
-			if task.Type == models.TaskTypeApply {
+			if task.Type == models.TaskTypeApply || task.Type == models.TaskTypeImport {
 				envStatus = models.EnvStatusActive
 			} else if task.Type == models.TaskTypeDestroy {
 				envStatus = models.EnvStatusInactive
@@ -170,6 +171,9 @@ func GetVariableBody(vars models.EnvVariables) []models.VariableBody {
 			Value:       v.Value,
 			Sensitive:   v.Sensitive,
 			Description: v.Description,
+			Path:        v.Path,
+			VarSetId:    v.VarSetId,
+			VarSetName:  v.VarSetName,
 		})
 	}
 	return vb
@@ -0,0 +1,222 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"cloudiac/utils"
+	"encoding/json"
+	"fmt"
+)
+
+// getEnvOutputs 获取环境最后一次部署成功的输出，requireActive 为 true 时要求环境处于活跃状态。
+// 只允许引用同一项目下环境的输出，避免通过引用读取其他项目的(敏感)输出
+func getEnvOutputs(tx *db.Session, consumer *models.Env, envId models.Id, requireActive bool) (map[string]interface{}, e.Error) {
+	env, err := GetEnvById(tx, envId)
+	if err != nil {
+		if err.Code() == e.EnvNotExists {
+			return nil, e.New(e.EnvOutputRefInvalid, fmt.Errorf("env '%s' not exists", envId))
+		}
+		return nil, err
+	}
+	if env.OrgId != consumer.OrgId {
+		return nil, e.New(e.EnvOutputRefInvalid, fmt.Errorf("env '%s' not exists", envId))
+	}
+	if env.ProjectId != consumer.ProjectId {
+		return nil, e.New(e.EnvOutputRefInvalid, fmt.Errorf("env '%s' is not in the same project", env.Name))
+	}
+	if requireActive && env.Status != models.EnvStatusActive {
+		return nil, e.New(e.EnvOutputRefInvalid, fmt.Errorf("env '%s' is not active", env.Name))
+	}
+
+	task, err := GetEnvLastAppliedTask(tx, envId)
+	if err != nil {
+		if err.Code() == e.TaskNotExists {
+			return nil, e.New(e.EnvOutputRefInvalid, fmt.Errorf("env '%s' has not been deployed", env.Name))
+		}
+		return nil, err
+	}
+	return task.Result.Outputs, nil
+}
+
+// outputValue 将 terraform output 转为变量值，非字符串类型的值使用 json 编码
+func outputValue(output interface{}) (value string, sensitive bool, err error) {
+	o, ok := output.(map[string]interface{})
+	if !ok {
+		return "", false, fmt.Errorf("invalid output")
+	}
+	sensitive, _ = o["sensitive"].(bool)
+	v := o["value"]
+	if o["encrypted"] == true {
+		if v, err = decryptOutputValue(v); err != nil {
+			return "", false, err
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s, sensitive, nil
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return "", false, err
+	}
+	return string(bs), sensitive, nil
+}
+
+// ResolveEnvOutputRefs 将变量中对其他环境输出的引用(env://<envId>/outputs/<name>)替换为输出值，
+// 返回解析后的变量及被引用的环境列表。只能引用同一项目下的环境，敏感变量的值为密文，不支持引用
+func ResolveEnvOutputRefs(tx *db.Session, env *models.Env, vars []models.VariableBody, requireActive bool) (
+	[]models.VariableBody, []models.Id, e.Error) {
+	outputs := make(map[models.Id]map[string]interface{})
+	producers := make([]models.Id, 0)
+
+	resolved := make([]models.VariableBody, 0, len(vars))
+	for _, v := range vars {
+		envId, name, ok := models.ParseEnvOutputRef(v.Value)
+		if v.Sensitive || !ok {
+			resolved = append(resolved, v)
+			continue
+		}
+		if envId == env.Id {
+			return nil, nil, e.New(e.EnvDependencyCycle, fmt.Errorf("variable '%s' references its own env", v.Name))
+		}
+
+		if _, ok := outputs[envId]; !ok {
+			envOutputs, err := getEnvOutputs(tx, env, envId, requireActive)
+			if err != nil {
+				return nil, nil, err
+			}
+			outputs[envId] = envOutputs
+			producers = append(producers, envId)
+		}
+
+		output, ok := outputs[envId][name]
+		if !ok {
+			return nil, nil, e.New(e.EnvOutputRefInvalid, fmt.Errorf("output '%s' not found in env '%s'", name, envId))
+		}
+		value, sensitive, err := outputValue(output)
+		if err != nil {
+			return nil, nil, e.New(e.EnvOutputRefInvalid, fmt.Errorf("output '%s' of env '%s': %v", name, envId, err))
+		}
+		// 敏感的输出引用后同样作为敏感变量处理
+		if sensitive {
+			if value, err = utils.AesEncrypt(value); err != nil {
+				return nil, nil, e.New(e.InternalError, err)
+			}
+		}
+		v.Value = value
+		v.Sensitive = v.Sensitive || sensitive
+		resolved = append(resolved, v)
+	}
+	return resolved, producers, nil
+}
+
+// envDependsOn 判断环境 envId 是否直接或间接依赖了环境 target
+func envDependsOn(tx *db.Session, orgId models.Id, envId models.Id, target models.Id) (bool, e.Error) {
+	deps := make([]models.EnvDependency, 0)
+	if err := tx.Model(models.EnvDependency{}).Where("org_id = ?", orgId).Find(&deps); err != nil {
+		return false, e.New(e.DBError, err)
+	}
+	graph := make(map[models.Id][]models.Id)
+	for _, d := range deps {
+		graph[d.EnvId] = append(graph[d.EnvId], d.DependsOnEnvId)
+	}
+
+	visited := map[models.Id]bool{envId: true}
+	queue := []models.Id{envId}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range graph[id] {
+			if next == target {
+				return true, nil
+			}
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false, nil
+}
+
+// SyncEnvDependencies 更新环境的依赖关系，依赖关系不能成环
+func SyncEnvDependencies(tx *db.Session, env *models.Env, producers []models.Id) e.Error {
+	for _, producer := range producers {
+		cycle, err := envDependsOn(tx, env.OrgId, producer, env.Id)
+		if err != nil {
+			return err
+		} else if cycle {
+			return e.New(e.EnvDependencyCycle, fmt.Errorf("env '%s' already depends on env '%s'", producer, env.Id))
+		}
+	}
+
+	if _, err := tx.Where("env_id = ?", env.Id).Delete(&models.EnvDependency{}); err != nil {
+		return e.New(e.DBError, err)
+	}
+
+	bq := utils.NewBatchSQL(1024, "INSERT IGNORE INTO", models.EnvDependency{}.TableName(),
+		"org_id", "env_id", "depends_on_env_id")
+	for _, producer := range producers {
+		if err := bq.AddRow(env.OrgId, env.Id, producer); err != nil {
+			return e.New(e.DBError, err)
+		}
+	}
+	for bq.HasNext() {
+		sql, args := bq.Next()
+		if _, err := tx.Exec(sql, args...); err != nil {
+			return e.New(e.DBError, err)
+		}
+	}
+	return nil
+}
+
+// GetDependentEnvs 查询依赖该环境的环境(不包含已归档环境)
+func GetDependentEnvs(query *db.Session, envId models.Id) ([]*models.Env, e.Error) {
+	envIds := make([]models.Id, 0)
+	if err := query.Model(models.EnvDependency{}).
+		Where("depends_on_env_id = ?", envId).Pluck("env_id", &envIds); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+
+	envs := make([]*models.Env, 0)
+	if len(envIds) == 0 {
+		return envs, nil
+	}
+	if err := query.Model(models.Env{}).
+		Where("id IN (?) AND archived = ?", envIds, false).
+		Find(&envs); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return envs, nil
+}
+
+// CheckEnvDependents 存在依赖该环境的活跃环境时返回 EnvHasDependents 错误
+func CheckEnvDependents(query *db.Session, env *models.Env) e.Error {
+	envs, err := GetDependentEnvs(query, env.Id)
+	if err != nil {
+		return err
+	}
+	for _, dep := range envs {
+		if dep.Status == models.EnvStatusActive {
+			return e.New(e.EnvHasDependents, fmt.Errorf("env '%s' depends on this env", dep.Name))
+		}
+	}
+	return nil
+}
+
+// QueryEnvDependencies 查询环境的上游依赖(dependsOn=true)或下游依赖方
+func QueryEnvDependencies(query *db.Session, envId models.Id, dependsOn bool) *db.Session {
+	table := models.EnvDependency{}.TableName()
+	envTable := models.Env{}.TableName()
+	query = query.Table(table).
+		Joins(fmt.Sprintf("left join %s as e on e.id = %s.env_id", envTable, table)).
+		Joins(fmt.Sprintf("left join %s as d on d.id = %s.depends_on_env_id", envTable, table)).
+		LazySelectAppend(fmt.Sprintf("%s.*", table), "e.name as env_name", "e.status as env_status",
+			"d.name as depends_on_env_name")
+	if dependsOn {
+		return query.Where(fmt.Sprintf("%s.env_id = ?", table), envId)
+	}
+	return query.Where(fmt.Sprintf("%s.depends_on_env_id = ?", table), envId)
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"fmt"
+	"time"
+)
+
+func CreateEnvSchedule(tx *db.Session, schedule models.EnvSchedule) (*models.EnvSchedule, e.Error) {
+	if schedule.Id == "" {
+		schedule.Id = models.NewId("es")
+	}
+	if err := models.Create(tx, &schedule); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return &schedule, nil
+}
+
+func UpdateEnvSchedule(tx *db.Session, id models.Id, attrs models.Attrs) (schedule *models.EnvSchedule, er e.Error) {
+	schedule = &models.EnvSchedule{}
+	if _, err := models.UpdateAttr(tx.Where("id = ?", id), &models.EnvSchedule{}, attrs); err != nil {
+		return nil, e.New(e.DBError, fmt.Errorf("update env schedule error: %v", err))
+	}
+	if err := tx.Where("id = ?", id).First(schedule); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.EnvScheduleNotExists)
+		}
+		return nil, e.New(e.DBError, fmt.Errorf("query env schedule error: %v", err))
+	}
+	return
+}
+
+func DeleteEnvSchedule(tx *db.Session, id models.Id) e.Error {
+	if _, err := tx.Where("id = ?", id).Delete(&models.EnvSchedule{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete env schedule error: %v", err))
+	}
+	if _, err := tx.Where("schedule_id = ?", id).Delete(&models.EnvScheduleRun{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete env schedule runs error: %v", err))
+	}
+	return nil
+}
+
+func QueryEnvSchedule(query *db.Session) *db.Session {
+	return query.Model(&models.EnvSchedule{})
+}
+
+func GetEnvScheduleById(query *db.Session, id models.Id) (*models.EnvSchedule, e.Error) {
+	schedule := models.EnvSchedule{}
+	if err := query.Model(models.EnvSchedule{}).Where("id = ?", id).First(&schedule); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.EnvScheduleNotExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &schedule, nil
+}
+
+// GetDueEnvSchedules 查询到期需要执行的定时任务
+func GetDueEnvSchedules(query *db.Session, now time.Time, limit int) ([]*models.EnvSchedule, e.Error) {
+	schedules := make([]*models.EnvSchedule, 0)
+	if err := query.Model(models.EnvSchedule{}).
+		Where("paused = ? AND next_run_at <= ?", false, now).
+		Order("next_run_at").Limit(limit).Find(&schedules); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return schedules, nil
+}
+
+// MoveEnvScheduleNextRun 将定时任务的下次执行时间从 current 更新为 next，
+// 执行时间已被修改(如被其他实例处理)时返回 false
+func MoveEnvScheduleNextRun(tx *db.Session, schedule *models.EnvSchedule, current, next *models.Time) (bool, e.Error) {
+	n, err := tx.Model(models.EnvSchedule{}).
+		Where("id = ? AND next_run_at = ?", schedule.Id, current).
+		UpdateAttrs(models.Attrs{"next_run_at": next, "last_run_at": models.Time(time.Now())})
+	if err != nil {
+		return false, e.New(e.DBError, err)
+	}
+	return n > 0, nil
+}
+
+func CreateEnvScheduleRun(tx *db.Session, run models.EnvScheduleRun) (*models.EnvScheduleRun, e.Error) {
+	if run.Id == "" {
+		run.Id = models.NewId("esr")
+	}
+	if err := models.Create(tx, &run); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return &run, nil
+}
+
+func QueryEnvScheduleRun(query *db.Session, scheduleId models.Id) *db.Session {
+	return query.Model(&models.EnvScheduleRun{}).Where("schedule_id = ?", scheduleId)
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"fmt"
+	"time"
+)
+
+func CreateFreezeWindow(tx *db.Session, window models.FreezeWindow) (*models.FreezeWindow, e.Error) {
+	if window.Id == "" {
+		window.Id = models.NewId("fw")
+	}
+	if err := models.Create(tx, &window); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return &window, nil
+}
+
+func UpdateFreezeWindow(tx *db.Session, id models.Id, attrs models.Attrs) (window *models.FreezeWindow, er e.Error) {
+	window = &models.FreezeWindow{}
+	if _, err := models.UpdateAttr(tx.Where("id = ?", id), &models.FreezeWindow{}, attrs); err != nil {
+		return nil, e.New(e.DBError, fmt.Errorf("update freeze window error: %v", err))
+	}
+	if err := tx.Where("id = ?", id).First(window); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.FreezeWindowNotExists)
+		}
+		return nil, e.New(e.DBError, fmt.Errorf("query freeze window error: %v", err))
+	}
+	return
+}
+
+func DeleteFreezeWindow(tx *db.Session, id models.Id) e.Error {
+	if _, err := tx.Where("id = ?", id).Delete(&models.FreezeWindow{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete freeze window error: %v", err))
+	}
+	return nil
+}
+
+func QueryFreezeWindow(query *db.Session) *db.Session {
+	return query.Model(&models.FreezeWindow{})
+}
+
+func GetFreezeWindowById(query *db.Session, id models.Id) (*models.FreezeWindow, e.Error) {
+	window := models.FreezeWindow{}
+	if err := query.Model(models.FreezeWindow{}).Where("id = ?", id).First(&window); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.FreezeWindowNotExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &window, nil
+}
+
+// GetActiveFreezeWindow 查询指定时间作用于环境的冻结窗口，没有生效的冻结窗口时返回 nil
+func GetActiveFreezeWindow(query *db.Session, env *models.Env, at time.Time) (*models.FreezeWindow, e.Error) {
+	windows := make([]*models.FreezeWindow, 0)
+	if err := query.Model(models.FreezeWindow{}).
+		Where("org_id = ?", env.OrgId).
+		Where("project_id = '' OR project_id = ?", env.ProjectId).
+		Where("start_at <= ? AND end_at > ?", at, at).
+		Order("end_at DESC").
+		Find(&windows); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+
+	for _, w := range windows {
+		if w.Covers(env) {
+			return w, nil
+		}
+	}
+	return nil, nil
+}
+
+// CheckEnvFrozen 检查环境当前是否处于冻结期，处于冻结期时返回 EnvFrozen 错误
+func CheckEnvFrozen(query *db.Session, env *models.Env) e.Error {
+	window, err := GetActiveFreezeWindow(query, env, time.Now())
+	if err != nil {
+		return err
+	} else if window != nil {
+		return e.New(e.EnvFrozen, fmt.Errorf("env is frozen by '%s' until %s: %s",
+			window.Name, time.Time(window.EndAt).Format(time.RFC3339), window.Reason))
+	}
+	return nil
+}
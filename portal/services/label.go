@@ -0,0 +1,29 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"fmt"
+)
+
+// WhereLabelSelector 按标签选择器过滤，column 为保存标签的 json 字段(如 iac_env.labels)
+func WhereLabelSelector(query *db.Session, column string, selector models.LabelSelector) *db.Session {
+	for _, r := range selector {
+		// key 已经过格式校验，不会包含引号
+		path := fmt.Sprintf(`$."%s"`, r.Key)
+		switch r.Op {
+		case models.LabelOpEqual:
+			query = query.Where(fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, ?)) = ?", column), path, r.Value)
+		case models.LabelOpNotEqual:
+			query = query.Where(fmt.Sprintf("(JSON_EXTRACT(%s, ?) IS NULL OR JSON_UNQUOTE(JSON_EXTRACT(%s, ?)) != ?)",
+				column, column), path, path, r.Value)
+		case models.LabelOpExists:
+			query = query.Where(fmt.Sprintf("JSON_EXTRACT(%s, ?) IS NOT NULL", column), path)
+		case models.LabelOpNotExists:
+			query = query.Where(fmt.Sprintf("JSON_EXTRACT(%s, ?) IS NULL", column), path)
+		}
+	}
+	return query
+}
@@ -10,16 +10,17 @@ import (
 )
 
 type NotificationResp struct {
-	Id        models.Id `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	EventType string    `json:"eventType"`
+	Id            models.Id `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	EventType     string    `json:"eventType"`
+	LabelSelector string    `json:"labelSelector"`
 }
 
 func SearchNotification(tx *db.Session, orgId models.Id) (interface{}, error) {
 	users := make([]*NotificationResp, 0)
 	err := tx.Table(models.User{}.TableName()).
-		Select(fmt.Sprintf("%s.name, %s.email, n.id, n.event_type", models.User{}.TableName(), models.User{}.TableName())).
+		Select(fmt.Sprintf("%s.name, %s.email, n.id, n.event_type, n.label_selector", models.User{}.TableName(), models.User{}.TableName())).
 		Joins(fmt.Sprintf("right join %s as n on %s.id = n.user_id", models.NotificationCfg{}.TableName(), models.User{}.TableName())).
 		Where(fmt.Sprintf("n.org_id = '%s'", orgId)).Debug().Find(&users)
 	if err != nil {
@@ -58,3 +59,32 @@ func FindOrganizationCfgByUserId(tx *db.Session, orgId models.Id, userId models.
 	return tx.Table(models.NotificationCfg{}.TableName()).
 		Where("org_id = ? AND user_id = ? AND event_type = ?", orgId, userId, eventType).Exists()
 }
+
+// GetEnvNotificationCfgs 获取需要接收环境任务通知的配置，failed 表示任务是否失败，
+// 只返回标签选择器匹配环境标签的配置
+func GetEnvNotificationCfgs(tx *db.Session, env *models.Env, failed bool) ([]*models.NotificationCfg, e.Error) {
+	cfgs := make([]*models.NotificationCfg, 0)
+	if err := tx.Where("org_id = ?", env.OrgId).Find(&cfgs); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return matchEnvNotificationCfgs(cfgs, env, failed), nil
+}
+
+// matchEnvNotificationCfgs 按事件类型及标签选择器筛选环境任务通知的配置
+func matchEnvNotificationCfgs(cfgs []*models.NotificationCfg, env *models.Env, failed bool) []*models.NotificationCfg {
+	matched := make([]*models.NotificationCfg, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.EventType != "all" && !(failed && cfg.EventType == "failure") {
+			continue
+		}
+		selector, err := models.ParseLabelSelector(cfg.LabelSelector)
+		if err != nil {
+			// 保存时已校验，解析失败说明数据异常，跳过该配置
+			continue
+		}
+		if selector.Matches(env.Labels) {
+			matched = append(matched, cfg)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatchEnvNotificationCfgs(t *testing.T) {
+	newCfg := func(userId models.Id, eventType, selector string) *models.NotificationCfg {
+		return &models.NotificationCfg{UserId: userId, EventType: eventType, LabelSelector: selector}
+	}
+	cfgs := []*models.NotificationCfg{
+		newCfg("u-all", "all", ""),
+		newCfg("u-failure", "failure", ""),
+		newCfg("u-payments", "all", "team=payments"),
+		newCfg("u-not-prod", "all", "team=payments,tier!=prod"),
+		newCfg("u-search", "all", "team=search"),
+		newCfg("u-owner", "failure", "owner"),
+		newCfg("u-invalid", "all", "=prod"),
+	}
+
+	userIds := func(cfgs []*models.NotificationCfg) []models.Id {
+		ids := make([]models.Id, 0)
+		for _, c := range cfgs {
+			ids = append(ids, c.UserId)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		labels models.Labels
+		failed bool
+		expect []models.Id
+	}{
+		{models.Labels{"team": "payments", "tier": "staging"}, false,
+			[]models.Id{"u-all", "u-payments", "u-not-prod"}},
+		{models.Labels{"team": "payments", "tier": "prod"}, false,
+			[]models.Id{"u-all", "u-payments"}},
+		{models.Labels{"team": "payments", "tier": "prod", "owner": "bob"}, true,
+			[]models.Id{"u-all", "u-failure", "u-payments", "u-owner"}},
+		{nil, true, []models.Id{"u-all", "u-failure"}},
+	}
+	for _, c := range cases {
+		env := &models.Env{Labels: c.labels}
+		assert.Equal(t, c.expect, userIds(matchEnvNotificationCfgs(cfgs, env, c.failed)), c.labels)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func CreateOperationLog(dbSess *db.Session, log models.OperationLog) e.Error {
+	if log.Id == "" {
+		log.Id = models.NewId("log")
+	}
+	if time.Time(log.OperationAt).IsZero() {
+		log.OperationAt = models.Time(time.Now())
+	}
+	if err := dbSess.Insert(&log); err != nil {
+		return e.New(e.DBError, err)
+	}
+	return nil
+}
+
+func QueryOperationLog(query *db.Session) *db.Session {
+	return query.Model(&models.OperationLog{})
+}
+
+// ParseAuditLogRetention 解析审计日志保存天数，返回 0 表示永久保存
+func ParseAuditLogRetention(value string) (int, error) {
+	if value == "" || value == models.SysCfgValuePermanent {
+		return 0, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days <= 0 {
+		return 0, fmt.Errorf("invalid audit log retention '%s'", value)
+	}
+	return days, nil
+}
+
+// GetAuditLogRetention 获取审计日志保存天数，返回 0 表示永久保存
+func GetAuditLogRetention(dbSess *db.Session) (int, e.Error) {
+	cfg := models.SystemCfg{}
+	if err := QuerySystemConfig(dbSess).Where("name = ?", models.SysCfgNamePeriodOfAuditLogSave).First(&cfg); err != nil {
+		if e.IsRecordNotFound(err) {
+			return 0, nil
+		}
+		return 0, e.New(e.DBError, err)
+	}
+	days, err := ParseAuditLogRetention(cfg.Value)
+	if err != nil {
+		return 0, e.New(e.BadParam, err)
+	}
+	return days, nil
+}
+
+// DeleteOperationLogsBefore 删除指定时间之前的审计日志
+func DeleteOperationLogsBefore(dbSess *db.Session, before time.Time) (int64, e.Error) {
+	n, err := dbSess.Where("operation_at < ?", before).Delete(&models.OperationLog{})
+	if err != nil {
+		return 0, e.New(e.DBError, err)
+	}
+	return n, nil
+}
@@ -101,7 +101,7 @@ func DeleteUserOrgRel(tx *db.Session, userId models.Id, orgId models.Id) e.Error
 	if _, err := tx.Where("user_id = ? AND org_id = ?", userId, orgId).Delete(&models.UserOrg{}); err != nil {
 		return e.New(e.DBError, fmt.Errorf("delete user %v for org %v error: %v", userId, orgId, err))
 	}
-	return nil
+	return DeleteUserOrgTeams(tx, userId, orgId)
 }
 
 func UpdateUserOrgRel(tx *db.Session, userOrg models.UserOrg) e.Error {
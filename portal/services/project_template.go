@@ -35,3 +35,13 @@ func DeleteTemplateProject(tx *db.Session, id models.Id) e.Error {
 	}
 	return nil
 }
+
+// IsTemplateInProject 判断模板是否已关联到项目
+func IsTemplateInProject(query *db.Session, tplId models.Id, projectId models.Id) (bool, e.Error) {
+	exists, err := query.Model(models.ProjectTemplate{}).
+		Where("template_id = ? AND project_id = ?", tplId, projectId).Exists()
+	if err != nil {
+		return false, e.New(e.DBError, err)
+	}
+	return exists, nil
+}
@@ -66,6 +66,17 @@ func GetProjectsByUserOrg(tx *db.Session, userId models.Id, orgId models.Id) ([]
 		Pluck(fmt.Sprintf("%s.id", models.Project{}.TableName()), &ids); err != nil {
 		return nil, e.AutoNew(err, e.DBError)
 	}
+
+	// 通过团队授权的项目，同时直接授权的项目不重复返回
+	teamProjects, err := GetUserTeamProjects(tx.New(), userId, orgId)
+	if err != nil {
+		return nil, err
+	}
+	for _, tp := range teamProjects {
+		if !tp.ProjectId.InArray(ids...) {
+			ids = append(ids, tp.ProjectId)
+		}
+	}
 	return ids, nil
 }
 
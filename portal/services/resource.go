@@ -1,3 +1,130 @@
 // Copyright 2021 CloudJ Company Limited. All rights reserved.
 
 package services
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"reflect"
+	"strings"
+)
+
+// GetEnvResourceAddresses 获取环境当前的资源地址列表
+func GetEnvResourceAddresses(query *db.Session, env *models.Env) ([]string, e.Error) {
+	addresses := make([]string, 0)
+	// env.LastTaskId 在任务开始时即更新(包括 output 任务及失败的任务)，这些任务不一定保存了资源，
+	// 所以使用最后一个执行成功且会同步资源的任务
+	task := models.Task{}
+	if err := query.Model(models.Task{}).
+		Where("env_id = ? AND status = ?", env.Id, models.TaskComplete).
+		Where("type IN (?)", []string{models.TaskTypeApply, models.TaskTypeDestroy, models.TaskTypeImport,
+			models.TaskTypeState, models.TaskTypeRefresh}).
+		Order("created_at DESC").First(&task); err != nil {
+		if e.IsRecordNotFound(err) {
+			return addresses, nil
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	if err := query.Model(models.Resource{}).Where("task_id = ?", task.Id).
+		Pluck("address", &addresses); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return addresses, nil
+}
+
+// 敏感属性名称，terraform 版本较低(state 中没有 sensitive_values)或 state 不可用时按名称判断
+var sensitiveAttrNames = []string{"password", "passwd", "secret", "private_key", "token", "access_key", "secret_key"}
+
+func isSensitiveAttrName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveAttrNames {
+		if name == s || strings.HasSuffix(name, "_"+s) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskResourceAttrs 隐藏资源属性中的敏感值。sensitive 为 state 中资源的 sensitive_values，
+// 结构与属性值相同，值为 true 的属性为敏感属性；此外名称为 password、secret 等的字符串属性同样视为敏感属性
+func MaskResourceAttrs(attrs map[string]interface{}, sensitive interface{}) map[string]interface{} {
+	if attrs == nil {
+		return nil
+	}
+	masked, _ := maskAttrValue(attrs, sensitive, false).(map[string]interface{})
+	return masked
+}
+
+func maskAttrValue(value interface{}, sensitive interface{}, sensitiveName bool) interface{} {
+	if value == nil {
+		return nil
+	}
+	if s, ok := sensitive.(bool); ok && s {
+		return models.MaskedVariableValue
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		sm, _ := sensitive.(map[string]interface{})
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = maskAttrValue(val, sm[k], isSensitiveAttrName(k))
+		}
+		return m
+	case []interface{}:
+		sl, _ := sensitive.([]interface{})
+		l := make([]interface{}, len(v))
+		for i, val := range v {
+			var s interface{}
+			if i < len(sl) {
+				s = sl[i]
+			}
+			l[i] = maskAttrValue(val, s, sensitiveName)
+		}
+		return l
+	case string:
+		if sensitiveName && v != "" {
+			return models.MaskedVariableValue
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// ScrubTaskResources 重新隐藏任务已保存的资源属性中的敏感值，state 为任务的 tfstate.json 内容(可以为空)，
+// 返回更新的资源数量
+func ScrubTaskResources(tx *db.Session, taskId models.Id, state *TfState) (int, e.Error) {
+	sensitive := make(map[string]interface{})
+	if state != nil {
+		modules := []TfStateModule{state.Values.RootModule}
+		modules = append(modules, state.Values.ChildModules...)
+		for len(modules) > 0 {
+			m := modules[0]
+			modules = append(modules[1:], m.ChildModules...)
+			for _, r := range m.Resources {
+				sensitive[r.Address] = r.SensitiveValues
+			}
+		}
+	}
+
+	rs := make([]models.Resource, 0)
+	if err := tx.Model(models.Resource{}).Where("task_id = ?", taskId).Find(&rs); err != nil {
+		return 0, e.New(e.DBError, err)
+	}
+
+	count := 0
+	for _, r := range rs {
+		attrs := MaskResourceAttrs(r.Attrs, sensitive[r.Address])
+		if reflect.DeepEqual(map[string]interface{}(r.Attrs), attrs) {
+			continue
+		}
+		if _, err := tx.Model(models.Resource{}).Where("id = ?", r.Id).
+			UpdateColumn("attrs", models.ResAttrs(attrs)); err != nil {
+			return count, e.New(e.DBError, err)
+		}
+		count += 1
+	}
+	return count, nil
+}
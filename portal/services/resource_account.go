@@ -3,11 +3,17 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 	//"errors"
 	"cloudiac/portal/consts/e"
 	"cloudiac/portal/libs/db"
 	"cloudiac/portal/models"
+	"cloudiac/portal/services/credential"
+	"cloudiac/portal/services/sts"
+	"cloudiac/utils"
 )
 
 func CreateResourceAccount(tx *db.Session, rsAccount *models.ResourceAccount) (*models.ResourceAccount, e.Error) {
@@ -98,3 +104,164 @@ func DeleteCtResourceMap(tx *db.Session, rsAccountId models.Id) e.Error {
 	}
 	return nil
 }
+
+func CreateResourceAccountBinding(tx *db.Session, binding models.ResourceAccountBinding) (*models.ResourceAccountBinding, e.Error) {
+	if binding.Id == "" {
+		binding.Id = models.NewId("rab")
+	}
+	if err := models.Create(tx, &binding); err != nil {
+		if e.IsDuplicate(err) {
+			return nil, e.New(e.ResourceAccountBindingExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &binding, nil
+}
+
+func GetResourceAccountBindingById(query *db.Session, id models.Id) (*models.ResourceAccountBinding, e.Error) {
+	binding := models.ResourceAccountBinding{}
+	if err := query.Model(models.ResourceAccountBinding{}).Where("id = ?", id).First(&binding); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.ResourceAccountBindingNotExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &binding, nil
+}
+
+func DeleteResourceAccountBinding(tx *db.Session, id models.Id) e.Error {
+	if _, err := tx.Where("id = ?", id).Delete(&models.ResourceAccountBinding{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete resource account binding error: %v", err))
+	}
+	return nil
+}
+
+// QueryResourceAccountBinding 查询资源账号绑定关系，同时返回资源账号及环境名称
+func QueryResourceAccountBinding(query *db.Session) *db.Session {
+	return query.Model(&models.ResourceAccountBinding{}).
+		Joins("left join iac_resource_account as ra on ra.id = iac_resource_account_binding.resource_account_id").
+		Joins("left join iac_env as env on env.id = iac_resource_account_binding.env_id").
+		LazySelectAppend("iac_resource_account_binding.*", "ra.name as resource_account_name", "env.name as env_name")
+}
+
+// GetEnvResourceAccounts 获取环境可以使用的资源账号，先返回项目级绑定，再返回环境级绑定，
+// 同一级别按绑定时间排序。禁用的账号及限制了部署通道且不包含 runnerId 的账号会被忽略
+func GetEnvResourceAccounts(query *db.Session, projectId, envId models.Id, runnerId string) ([]*models.ResourceAccount, e.Error) {
+	bindings := make([]*models.ResourceAccountBinding, 0)
+	if err := query.Model(&models.ResourceAccountBinding{}).
+		Where("project_id = ? AND (env_id = '' OR env_id = ?)", projectId, envId).
+		Order("env_id != '', created_at").Find(&bindings); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+
+	accounts := make([]*models.ResourceAccount, 0, len(bindings))
+	for _, b := range bindings {
+		account, err := GetResourceAccountById(query, b.ResourceAccountId)
+		if err != nil {
+			if err.Code() == e.ObjectNotExists {
+				continue
+			}
+			return nil, err
+		}
+		if account.Status != models.Enable {
+			continue
+		}
+		serviceIds, er := FindCtResourceMap(query, account.Id)
+		if er != nil {
+			return nil, e.AutoNew(er, e.DBError)
+		}
+		if len(serviceIds) > 0 && !utils.StrInArray(runnerId, serviceIds...) {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// resourceAccountParam 资源账号变量，敏感变量的值为加密后的值
+type resourceAccountParam struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	IsSecret *bool  `json:"isSecret"`
+}
+
+// GetResourceAccountPlainParams 返回账号参数名称到明文值的映射
+func GetResourceAccountPlainParams(account *models.ResourceAccount) (map[string]string, e.Error) {
+	values := make(map[string]string)
+	if account.Params.IsNull() {
+		return values, nil
+	}
+	params := make([]resourceAccountParam, 0)
+	if err := json.Unmarshal(account.Params, &params); err != nil {
+		return nil, e.New(e.JSONParseError, fmt.Errorf("parse resource account '%s' params: %v", account.Name, err))
+	}
+	for _, p := range params {
+		if p.Key == "" {
+			continue
+		}
+		value := p.Value
+		if p.IsSecret != nil && *p.IsSecret {
+			decrypted, err := utils.AesDecrypt(p.Value)
+			if err != nil {
+				return nil, e.New(e.InternalError, fmt.Errorf("decrypt resource account '%s' param: %v", account.Name, err))
+			}
+			value = decrypted
+		}
+		values[p.Key] = value
+	}
+	return values, nil
+}
+
+// GetResourceAccountEnvVars 将资源账号的变量合并为环境变量，后面的账号覆盖前面账号的同名变量，变量值均以加密形式返回，由 runner 解密。
+// sts 类型的账号会扮演角色签发有效期为 duration 的临时凭证，只注入临时凭证，sessionName 用于标识凭证的使用者
+func GetResourceAccountEnvVars(ctx context.Context, accounts []*models.ResourceAccount,
+	sessionName string, duration time.Duration) (map[string]string, e.Error) {
+	vars := make(map[string]string)
+	for _, account := range accounts {
+		params, err := GetResourceAccountPlainParams(account)
+		if err != nil {
+			return nil, err
+		}
+		// 指定了云厂商的静态账号，参数名称按 schema 转换为对应的环境变量名
+		schema, typed := credential.GetSchema(account.Provider)
+		plainVars := make(map[string]string)
+		for k, v := range params {
+			if typed && account.Type != models.ResourceAccountSts {
+				k = schema.EnvVar(k)
+			}
+			plainVars[k] = v
+		}
+
+		if account.Type == models.ResourceAccountSts {
+			secret, err := utils.AesDecrypt(account.AccessKeySecret)
+			if err != nil {
+				return nil, e.New(e.InternalError, fmt.Errorf("decrypt resource account '%s' secret: %v", account.Name, err))
+			}
+			stsVars, _, err := sts.IssueEnvVars(ctx, account.Provider, sts.AssumeRoleInput{
+				AccessKeyId:     account.AccessKeyId,
+				AccessKeySecret: secret,
+				RoleArn:         account.RoleArn,
+				SessionName:     sessionName,
+				ExternalId:      account.ExternalId,
+				Region:          account.Region,
+				Duration:        duration,
+			})
+			if err != nil {
+				return nil, e.New(e.ResourceAccountStsFailed, fmt.Errorf("resource account '%s': %v", account.Name, err))
+			}
+			for k, v := range stsVars {
+				plainVars[k] = v
+			}
+		}
+
+		// 账号变量都是凭证信息，全部加密后传给 runner
+		for k, v := range plainVars {
+			encrypted, err := utils.AesEncrypt(v)
+			if err != nil {
+				return nil, e.New(e.InternalError, err)
+			}
+			vars[k] = utils.EncodeSecretVar(encrypted, true)
+		}
+	}
+	return vars, nil
+}
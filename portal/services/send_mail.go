@@ -23,7 +23,7 @@ func (sm *sendMail) SendMail() {
 	tmpl, err := template.New("sendMail").Parse("<table>" +
 		"<tr><td>模板名称: </td><td>{{.Name}}</td></tr>" +
 		"<tr><td>模板 Id: </td><td>{{.Id}}</td></tr>" +
-		"<tr><td>作业 Id: </td><td>{{.Id}}</td></tr>" +
+		"<tr><td>作业 Id: </td><td>{{.TaskId}}</td></tr>" +
 		"<tr><td>作业类型: </td><td>{{.TaskType}}</td></tr>" +
 		"<tr><td>作业状态: </td><td>{{.Status}}</td></tr>" +
 		"<tr><td>runnerId: </td><td>{{.RunnerId}}</td></tr>" +
@@ -46,7 +46,7 @@ func (sm *sendMail) SendMail() {
 	}{
 		RunnerId: sm.Task.RunnerId,
 		CommitId: sm.Task.CommitId,
-		//TaskType:    sm.Task.TaskType,
+		TaskType: sm.Task.Type,
 		Status:   sm.Task.Status,
 		TaskId:   sm.Task.Id,
 		TaskName: sm.Task.Name,
@@ -60,26 +60,6 @@ func (sm *sendMail) SendMail() {
 	//"您已在云模板:%s下成功名称为:%s的plan作业"
 	//"【%s】<br>【%s】[%s][P%d]<tr><td>Metric: </td><td>%s</td></tr><tr><td>Tags: </td><td>%s</td></tr><tr><td>Strategy: </td><td>%s</td></tr><tr><td>Note: </td><td>%s</td></tr><tr><td>Current: </td><td>%d/%d</td></tr><tr><td>Time: </td><td>%s</td></tr></table><br><br>",
 	content := string(buffer.Bytes())
-	fmt.Printf(
-		"<table>"+
-			"<tr><td>模板名称: </td><td>%s</td></tr>"+
-			"<tr><td>模板guid: </td><td>%s</td></tr>"+
-			"<tr><td>作业guid: </td><td>%s</td></tr>"+
-			"<tr><td>作业类型: </td><td>%s</td></tr>"+
-			"<tr><td>作业状态: </td><td>%s</td></tr>"+
-			"<tr><td>CtRunnerId: </td><td>%s</td></tr>"+
-			"<tr><td>CommitId: </td><td>%s</td></tr>"+
-			"<tr><td>Branch: </td><td>%s</td></tr>"+
-			"</table>",
-		sm.Tpl.Name,
-		sm.Tpl.Id,
-		sm.Task.Id,
-		sm.Task.Type,
-		sm.Task.Status,
-		sm.Task.RunnerId,
-		sm.Task.CommitId,
-		sm.Tpl.RepoRevision,
-	)
 	_ = mail.SendMail(sm.Tos, subject, content)
 }
 
@@ -0,0 +1,165 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package sts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	aliyunStsVersion      = "2015-04-01"
+	aliyunTimestampFormat = "2006-01-02T15:04:05Z"
+)
+
+// aliyunIssuer 通过阿里云 STS AssumeRole 接口签发临时凭证，请求使用 RPC 风格签名(HMAC-SHA1)
+type aliyunIssuer struct {
+	client   *http.Client
+	endpoint string // 为空时根据 region 生成，用于测试
+}
+
+type aliyunErrorResponse struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+type aliyunCallerIdentityResponse struct {
+	AccountId string `json:"AccountId"`
+	Arn       string `json:"Arn"`
+}
+
+type aliyunAssumeRoleResponse struct {
+	Credentials struct {
+		AccessKeyId     string `json:"AccessKeyId"`
+		AccessKeySecret string `json:"AccessKeySecret"`
+		SecurityToken   string `json:"SecurityToken"`
+		Expiration      string `json:"Expiration"`
+	} `json:"Credentials"`
+}
+
+func (i *aliyunIssuer) AssumeRole(ctx context.Context, input AssumeRoleInput) (*Credentials, error) {
+	result := aliyunAssumeRoleResponse{}
+	if err := i.call(ctx, map[string]string{
+		"Action":          "AssumeRole",
+		"RoleArn":         input.RoleArn,
+		"RoleSessionName": input.SessionName,
+		"DurationSeconds": strconv.Itoa(int(input.Duration.Seconds())),
+	}, input.AccessKeyId, input.AccessKeySecret, input.Region, &result); err != nil {
+		return nil, err
+	}
+
+	c := result.Credentials
+	if c.AccessKeyId == "" {
+		return nil, fmt.Errorf("no credentials in response")
+	}
+	expiration, err := time.Parse(aliyunTimestampFormat, c.Expiration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration '%s'", c.Expiration)
+	}
+	return &Credentials{
+		AccessKeyId:     c.AccessKeyId,
+		AccessKeySecret: c.AccessKeySecret,
+		SessionToken:    c.SecurityToken,
+		Expiration:      expiration,
+	}, nil
+}
+
+func (i *aliyunIssuer) GetCallerIdentity(ctx context.Context, accessKeyId, accessKeySecret, region string) (string, error) {
+	result := aliyunCallerIdentityResponse{}
+	if err := i.call(ctx, map[string]string{"Action": "GetCallerIdentity"},
+		accessKeyId, accessKeySecret, region, &result); err != nil {
+		return "", err
+	}
+	return result.Arn, nil
+}
+
+// call 调用 STS 接口，并将返回的 json 解析到 result
+func (i *aliyunIssuer) call(ctx context.Context, params map[string]string, accessKeyId, secret, region string,
+	result interface{}) error {
+	endpoint := "https://sts.aliyuncs.com/"
+	if region != "" {
+		endpoint = fmt.Sprintf("https://sts.%s.aliyuncs.com/", region)
+	}
+	if i.endpoint != "" {
+		endpoint = i.endpoint
+	}
+
+	params["Version"] = aliyunStsVersion
+	params["Format"] = "JSON"
+	params["AccessKeyId"] = accessKeyId
+	params["SignatureMethod"] = "HMAC-SHA1"
+	params["SignatureVersion"] = "1.0"
+	params["SignatureNonce"] = xid.New().String()
+	params["Timestamp"] = time.Now().UTC().Format(aliyunTimestampFormat)
+	params["Signature"] = aliyunSignature(http.MethodGet, params, secret)
+
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := aliyunErrorResponse{}
+		if er := json.Unmarshal(content, &errResp); er == nil && errResp.Code != "" {
+			return fmt.Errorf("%s: %s", errResp.Code, errResp.Message)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(content, result); err != nil {
+		return fmt.Errorf("unmarshal response: %v", err)
+	}
+	return nil
+}
+
+// aliyunSignature 计算 RPC 风格接口的签名
+func aliyunSignature(method string, params map[string]string, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, aliyunPercentEncode(k)+"="+aliyunPercentEncode(params[k]))
+	}
+	stringToSign := method + "&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(strings.Join(pairs, "&"))
+
+	h := hmac.New(sha1.New, []byte(secret+"&"))
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func aliyunPercentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
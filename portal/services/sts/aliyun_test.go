@@ -0,0 +1,67 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package sts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAliyunAssumeRole(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		params := make(map[string]string)
+		for k := range query {
+			if k != "Signature" {
+				params[k] = query.Get(k)
+			}
+		}
+		if sign := aliyunSignature(http.MethodGet, params, "secret"); sign != query.Get("Signature") {
+			t.Errorf("signature mismatch, got %s want %s", query.Get("Signature"), sign)
+		}
+		if query.Get("RoleArn") != "acs:ram::123:role/iac" || query.Get("DurationSeconds") != "3600" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"RequestId":"1","Credentials":{"AccessKeyId":"STS.temp","AccessKeySecret":"tempsecret",` +
+			`"SecurityToken":"token","Expiration":"2021-08-01T10:15:00Z"}}`))
+	}))
+	defer server.Close()
+
+	issuer := &aliyunIssuer{client: server.Client(), endpoint: server.URL + "/"}
+	creds, err := issuer.AssumeRole(context.Background(), AssumeRoleInput{
+		AccessKeyId:     "LTAI",
+		AccessKeySecret: "secret",
+		RoleArn:         "acs:ram::123:role/iac",
+		SessionName:     "iac-run-1",
+		Duration:        MaxDuration,
+	})
+	if err != nil {
+		t.Fatalf("assume role error: %v", err)
+	}
+	if creds.AccessKeyId != "STS.temp" || creds.SessionToken != "token" || creds.Expiration.IsZero() {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+}
+
+func TestAliyunAssumeRoleError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"RequestId":"1","Code":"InvalidParameter.RoleArn","Message":"role not exists"}`))
+	}))
+	defer server.Close()
+
+	issuer := &aliyunIssuer{client: server.Client(), endpoint: server.URL + "/"}
+	_, err := issuer.AssumeRole(context.Background(), AssumeRoleInput{Duration: MinDuration})
+	if err == nil || !strings.Contains(err.Error(), "InvalidParameter.RoleArn") {
+		t.Errorf("expect InvalidParameter.RoleArn error, got %v", err)
+	}
+}
+
+func TestAliyunPercentEncode(t *testing.T) {
+	if s := aliyunPercentEncode("a b*c~/"); s != "a%20b%2Ac~%2F" {
+		t.Errorf("aliyunPercentEncode = %s", s)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package sts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	awsStsVersion       = "2011-06-15"
+	awsStsGlobalRegion  = "us-east-1"
+	awsSignAlgorithm    = "AWS4-HMAC-SHA256"
+	awsFormContentType  = "application/x-www-form-urlencoded; charset=utf-8"
+	awsAmzDateFormat    = "20060102T150405Z"
+	awsSignedHeaderList = "content-type;host;x-amz-date"
+)
+
+// awsIssuer 通过 AWS STS AssumeRole 接口签发临时凭证，请求使用 Signature V4 签名
+type awsIssuer struct {
+	client   *http.Client
+	endpoint string // 为空时根据 region 生成，用于测试
+}
+
+type awsAssumeRoleResponse struct {
+	Result struct {
+		Credentials struct {
+			AccessKeyId     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"Credentials"`
+	} `xml:"AssumeRoleResult"`
+}
+
+type awsCallerIdentityResponse struct {
+	Result struct {
+		Account string `xml:"Account"`
+		Arn     string `xml:"Arn"`
+	} `xml:"GetCallerIdentityResult"`
+}
+
+type awsErrorResponse struct {
+	Error struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+}
+
+func (i *awsIssuer) AssumeRole(ctx context.Context, input AssumeRoleInput) (*Credentials, error) {
+	form := url.Values{}
+	form.Set("Action", "AssumeRole")
+	form.Set("RoleArn", input.RoleArn)
+	form.Set("RoleSessionName", input.SessionName)
+	form.Set("DurationSeconds", strconv.Itoa(int(input.Duration.Seconds())))
+	if input.ExternalId != "" {
+		form.Set("ExternalId", input.ExternalId)
+	}
+
+	result := awsAssumeRoleResponse{}
+	if err := i.call(ctx, form, input.AccessKeyId, input.AccessKeySecret, input.Region, &result); err != nil {
+		return nil, err
+	}
+	c := result.Result.Credentials
+	if c.AccessKeyId == "" {
+		return nil, fmt.Errorf("no credentials in response")
+	}
+	return &Credentials{
+		AccessKeyId:     c.AccessKeyId,
+		AccessKeySecret: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+		Expiration:      c.Expiration,
+	}, nil
+}
+
+func (i *awsIssuer) GetCallerIdentity(ctx context.Context, accessKeyId, accessKeySecret, region string) (string, error) {
+	form := url.Values{}
+	form.Set("Action", "GetCallerIdentity")
+
+	result := awsCallerIdentityResponse{}
+	if err := i.call(ctx, form, accessKeyId, accessKeySecret, region, &result); err != nil {
+		return "", err
+	}
+	return result.Result.Arn, nil
+}
+
+// call 调用 STS 接口，并将返回的 xml 解析到 result
+func (i *awsIssuer) call(ctx context.Context, form url.Values, accessKeyId, secret, region string, result interface{}) error {
+	endpoint := "https://sts.amazonaws.com/"
+	if region != "" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+	} else {
+		region = awsStsGlobalRegion
+	}
+	if i.endpoint != "" {
+		endpoint = i.endpoint
+	}
+
+	form.Set("Version", awsStsVersion)
+	body := form.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	awsSignRequest(req, []byte(body), accessKeyId, secret, region, "sts", time.Now())
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := awsErrorResponse{}
+		if er := xml.Unmarshal(content, &errResp); er == nil && errResp.Error.Code != "" {
+			return fmt.Errorf("%s: %s", errResp.Error.Code, errResp.Error.Message)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := xml.Unmarshal(content, result); err != nil {
+		return fmt.Errorf("unmarshal response: %v", err)
+	}
+	return nil
+}
+
+// awsSignRequest 使用 Signature V4 对请求签名，只签名 content-type、host、x-amz-date 三个头
+func awsSignRequest(req *http.Request, body []byte, accessKeyId, secret, region, service string, now time.Time) {
+	amzDate := now.UTC().Format(awsAmzDateFormat)
+	date := amzDate[:8]
+	req.Header.Set("Content-Type", awsFormContentType)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalPath(req.URL.Path),
+		req.URL.Query().Encode(),
+		"content-type:" + awsFormContentType,
+		"host:" + req.URL.Host,
+		"x-amz-date:" + amzDate,
+		"",
+		awsSignedHeaderList,
+		hexSha256(body),
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{awsSignAlgorithm, amzDate, scope, hexSha256([]byte(canonicalRequest))}, "\n")
+	signature := hex.EncodeToString(hmacSha256(awsSigningKey(secret, date, region, service), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSignAlgorithm, accessKeyId, scope, awsSignedHeaderList, signature))
+}
+
+func awsSigningKey(secret, date, region, service string) []byte {
+	key := hmacSha256([]byte("AWS4"+secret), date)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, service)
+	return hmacSha256(key, "aws4_request")
+}
+
+func awsCanonicalPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSha256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package sts
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAwsSigningKey(t *testing.T) {
+	// AWS 文档中的签名示例
+	key := awsSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20150830", "us-east-1", "iam")
+	if hex.EncodeToString(key) != "c4afb1cc5771d871763a393e44b703571b55cc28424d1a5e86da6ed3c154a4b9" {
+		t.Errorf("unexpected signing key %x", key)
+	}
+}
+
+func TestAwsSignRequest(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	now, _ := time.Parse(awsAmzDateFormat, "20150830T123600Z")
+	awsSignRequest(req, nil, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "iam", now)
+
+	expect := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if auth := req.Header.Get("Authorization"); auth != expect {
+		t.Errorf("Authorization = %s, want %s", auth, expect)
+	}
+}
+
+func TestAwsAssumeRole(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Errorf("unexpected Authorization %s", r.Header.Get("Authorization"))
+		}
+		for _, s := range []string{"Action=AssumeRole", "DurationSeconds=900", "RoleSessionName=iac-run-1", "ExternalId=ext"} {
+			if !strings.Contains(string(body), s) {
+				t.Errorf("request body %s does not contain %s", body, s)
+			}
+		}
+		_, _ = w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASIATEMP</AccessKeyId>
+      <SecretAccessKey>tempsecret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>2021-08-01T10:15:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`))
+	}))
+	defer server.Close()
+
+	issuer := &awsIssuer{client: server.Client(), endpoint: server.URL + "/"}
+	creds, err := issuer.AssumeRole(context.Background(), AssumeRoleInput{
+		AccessKeyId:     "AKID",
+		AccessKeySecret: "secret",
+		RoleArn:         "arn:aws:iam::123456789012:role/iac",
+		SessionName:     "iac-run-1",
+		ExternalId:      "ext",
+		Duration:        MinDuration,
+	})
+	if err != nil {
+		t.Fatalf("assume role error: %v", err)
+	}
+	if creds.AccessKeyId != "ASIATEMP" || creds.AccessKeySecret != "tempsecret" || creds.SessionToken != "token" ||
+		creds.Expiration.Format(time.RFC3339) != "2021-08-01T10:15:00Z" {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+}
+
+func TestAwsAssumeRoleError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<ErrorResponse><Error><Code>AccessDenied</Code><Message>not authorized</Message></Error></ErrorResponse>`))
+	}))
+	defer server.Close()
+
+	issuer := &awsIssuer{client: server.Client(), endpoint: server.URL + "/"}
+	_, err := issuer.AssumeRole(context.Background(), AssumeRoleInput{Duration: MinDuration})
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("expect AccessDenied error, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+// Package sts 通过云厂商的 STS AssumeRole 接口签发临时凭证
+package sts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	ProviderAws    = "aws"
+	ProviderAliyun = "aliyun"
+
+	// 各云厂商 AssumeRole 支持的最短有效期为 15 分钟，角色默认的最长有效期为 1 小时
+	MinDuration = 15 * time.Minute
+	MaxDuration = time.Hour
+
+	requestTimeout = 30 * time.Second
+)
+
+var Providers = []string{ProviderAws, ProviderAliyun}
+
+// AssumeRoleInput 扮演角色的参数，AccessKeyId/AccessKeySecret 为用于扮演角色的基础凭证
+type AssumeRoleInput struct {
+	AccessKeyId     string
+	AccessKeySecret string
+	RoleArn         string
+	SessionName     string
+	ExternalId      string // 只用于 aws
+	Region          string
+	Duration        time.Duration
+}
+
+// Credentials 临时凭证
+type Credentials struct {
+	AccessKeyId     string
+	AccessKeySecret string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// Issuer 临时凭证签发接口
+type Issuer interface {
+	AssumeRole(ctx context.Context, input AssumeRoleInput) (*Credentials, error)
+}
+
+// IdentityGetter 查询凭证对应的身份，用于校验凭证是否有效
+type IdentityGetter interface {
+	GetCallerIdentity(ctx context.Context, accessKeyId, accessKeySecret, region string) (string, error)
+}
+
+var (
+	issuersLock sync.RWMutex
+	issuers     = map[string]Issuer{
+		ProviderAws:    &awsIssuer{client: &http.Client{Timeout: requestTimeout}},
+		ProviderAliyun: &aliyunIssuer{client: &http.Client{Timeout: requestTimeout}},
+	}
+)
+
+// Register 注册(替换)云厂商的临时凭证签发实现，主要用于测试
+func Register(provider string, issuer Issuer) {
+	issuersLock.Lock()
+	defer issuersLock.Unlock()
+	issuers[provider] = issuer
+}
+
+func GetIssuer(provider string) (Issuer, error) {
+	issuersLock.RLock()
+	defer issuersLock.RUnlock()
+	if issuer, ok := issuers[provider]; ok {
+		return issuer, nil
+	}
+	return nil, fmt.Errorf("unsupported sts provider '%s'", provider)
+}
+
+// IssueEnvVars 扮演角色获取临时凭证，并转换为对应云厂商 terraform provider 使用的环境变量
+func IssueEnvVars(ctx context.Context, provider string, input AssumeRoleInput) (map[string]string, *Credentials, error) {
+	issuer, err := GetIssuer(provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	if input.Duration < MinDuration {
+		input.Duration = MinDuration
+	} else if input.Duration > MaxDuration {
+		input.Duration = MaxDuration
+	}
+
+	creds, err := issuer.AssumeRole(ctx, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s assume role '%s': %v", provider, input.RoleArn, err)
+	}
+
+	vars := make(map[string]string)
+	switch provider {
+	case ProviderAws:
+		vars["AWS_ACCESS_KEY_ID"] = creds.AccessKeyId
+		vars["AWS_SECRET_ACCESS_KEY"] = creds.AccessKeySecret
+		vars["AWS_SESSION_TOKEN"] = creds.SessionToken
+		if input.Region != "" {
+			vars["AWS_REGION"] = input.Region
+			vars["AWS_DEFAULT_REGION"] = input.Region
+		}
+	case ProviderAliyun:
+		vars["ALICLOUD_ACCESS_KEY"] = creds.AccessKeyId
+		vars["ALICLOUD_SECRET_KEY"] = creds.AccessKeySecret
+		vars["ALICLOUD_SECURITY_TOKEN"] = creds.SessionToken
+		if input.Region != "" {
+			vars["ALICLOUD_REGION"] = input.Region
+		}
+	}
+	return vars, creds, nil
+}
+
+// GetCallerIdentity 查询凭证对应的身份(arn)，凭证无效时返回错误
+func GetCallerIdentity(ctx context.Context, provider, accessKeyId, accessKeySecret, region string) (string, error) {
+	issuer, err := GetIssuer(provider)
+	if err != nil {
+		return "", err
+	}
+	getter, ok := issuer.(IdentityGetter)
+	if !ok {
+		return "", fmt.Errorf("provider '%s' does not support get caller identity", provider)
+	}
+	return getter.GetCallerIdentity(ctx, accessKeyId, accessKeySecret, region)
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package sts
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeIssuer struct {
+	input AssumeRoleInput
+}
+
+func (f *fakeIssuer) AssumeRole(ctx context.Context, input AssumeRoleInput) (*Credentials, error) {
+	f.input = input
+	return &Credentials{
+		AccessKeyId:     "temp-ak",
+		AccessKeySecret: "temp-sk",
+		SessionToken:    "temp-token",
+		Expiration:      time.Now().Add(input.Duration),
+	}, nil
+}
+
+func TestIssueEnvVars(t *testing.T) {
+	origin, _ := GetIssuer(ProviderAws)
+	fake := &fakeIssuer{}
+	Register(ProviderAws, fake)
+	defer Register(ProviderAws, origin)
+
+	vars, _, err := IssueEnvVars(context.Background(), ProviderAws, AssumeRoleInput{
+		RoleArn:  "arn:aws:iam::123456789012:role/iac",
+		Region:   "us-west-2",
+		Duration: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("issue env vars error: %v", err)
+	}
+	if fake.input.Duration != MinDuration {
+		t.Errorf("duration = %s, want %s", fake.input.Duration, MinDuration)
+	}
+	expect := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "temp-ak",
+		"AWS_SECRET_ACCESS_KEY": "temp-sk",
+		"AWS_SESSION_TOKEN":     "temp-token",
+		"AWS_REGION":            "us-west-2",
+		"AWS_DEFAULT_REGION":    "us-west-2",
+	}
+	if len(vars) != len(expect) {
+		t.Errorf("unexpected vars %v", vars)
+	}
+	for k, v := range expect {
+		if vars[k] != v {
+			t.Errorf("vars[%s] = %s, want %s", k, vars[k], v)
+		}
+	}
+
+	if _, _, err := IssueEnvVars(context.Background(), "unknown", AssumeRoleInput{}); err == nil {
+		t.Errorf("expect error for unknown provider")
+	}
+}
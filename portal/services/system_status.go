@@ -68,6 +68,36 @@ func ConsulKVSearch(key string) (interface{}, e.Error) {
 
 }
 
+func ConsulKVPut(key string, value []byte) e.Error {
+	conf := configs.Get()
+	config := api.DefaultConfig()
+	config.Address = conf.Consul.Address
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return e.New(e.ConsulConnError, err)
+	}
+	if _, err := client.KV().Put(&api.KVPair{Key: key, Value: value}, nil); err != nil {
+		return e.New(e.ConsulConnError, err)
+	}
+	return nil
+}
+
+func ConsulKVDelete(key string) e.Error {
+	conf := configs.Get()
+	config := api.DefaultConfig()
+	config.Address = conf.Consul.Address
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return e.New(e.ConsulConnError, err)
+	}
+	if _, err := client.KV().Delete(key, nil); err != nil {
+		return e.New(e.ConsulConnError, err)
+	}
+	return nil
+}
+
 func RunnerSearch() (interface{}, e.Error) {
 	resp := make([]*api.AgentService, 0)
 
@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"time"
 )
@@ -37,9 +38,30 @@ func GetTask(dbSess *db.Session, id models.Id) (*models.Task, e.Error) {
 	return &task, nil
 }
 
+// GetEnvLastAppliedTask 获取环境最后一次执行成功的部署任务
+func GetEnvLastAppliedTask(query *db.Session, envId models.Id) (*models.Task, e.Error) {
+	task := models.Task{}
+	if err := query.Model(models.Task{}).
+		Where("env_id = ? AND type = ? AND status = ?", envId, models.TaskTypeApply, models.TaskComplete).
+		Order("created_at DESC").First(&task); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.TaskNotExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &task, nil
+}
+
 func CreateTask(tx *db.Session, tpl *models.Template, env *models.Env, pt models.Task) (*models.Task, e.Error) {
 	logger := logs.Get().WithField("func", "CreateTask")
 
+	// 冻结期内禁止 apply 和 destroy，plan 任务不受影响
+	if pt.IsEffectTaskType(pt.Type) && !pt.IsSyncTaskType(pt.Type) && !pt.Extra.FreezeOverride {
+		if er := CheckEnvFrozen(tx, env); er != nil {
+			return nil, er
+		}
+	}
+
 	var (
 		err      error
 		firstVal = utils.FirstValueStr
@@ -59,6 +81,7 @@ func CreateTask(tx *db.Session, tpl *models.Template, env *models.Env, pt models
 		KeyId:       models.Id(firstVal(string(pt.KeyId), string(env.KeyId))),
 		Extra:       pt.Extra,
 		Revision:    firstVal(pt.Revision, env.Revision, tpl.RepoRevision),
+		RetryOf:     pt.RetryOf,
 
 		OrgId:     env.OrgId,
 		ProjectId: env.ProjectId,
@@ -87,7 +110,8 @@ func CreateTask(tx *db.Session, tpl *models.Template, env *models.Env, pt models
 		}
 	}
 
-	task.RepoAddr, task.CommitId, err = getTaskRepoAddrAndCommitId(tx, tpl, task.Revision)
+	// 指定了 commitId 时(如环境晋级、回滚)直接使用指定的版本，不再解析分支(分支可能已被删除)
+	task.RepoAddr, task.CommitId, err = getTaskRepoAddrAndCommitId(tx, tpl, task.Revision, pt.CommitId)
 	if err != nil {
 		return nil, e.New(e.InternalError, err)
 	}
@@ -107,6 +131,27 @@ func CreateTask(tx *db.Session, tpl *models.Template, env *models.Env, pt models
 		}
 	}
 
+	{ // 环境依赖
+		isDestroy := task.Type == models.TaskTypeDestroy
+		if isDestroy {
+			if er := CheckEnvDependents(tx, env); er != nil {
+				return nil, er
+			}
+		}
+
+		// 解析变量中引用的其他环境输出，销毁任务不要求被引用的环境处于活跃状态
+		vars, producers, er := ResolveEnvOutputRefs(tx, env, task.Variables, !isDestroy)
+		if er != nil {
+			return nil, er
+		}
+		task.Variables = vars
+		if !isDestroy {
+			if er := SyncEnvDependencies(tx, env, producers); er != nil {
+				return nil, er
+			}
+		}
+	}
+
 	if _, err = tx.Save(&task); err != nil {
 		return nil, e.New(e.DBError, errors.Wrapf(err, "save task"))
 	}
@@ -153,7 +198,8 @@ func CreateTask(tx *db.Session, tpl *models.Template, env *models.Env, pt models
 	return &task, nil
 }
 
-func getTaskRepoAddrAndCommitId(tx *db.Session, tpl *models.Template, revision string) (repoAddr, commitId string, err error) {
+func getTaskRepoAddrAndCommitId(tx *db.Session, tpl *models.Template, revision string, pinnedCommitId string) (
+	repoAddr, commitId string, err error) {
 	var (
 		u         *url.URL
 		repoToken = tpl.RepoToken
@@ -161,7 +207,7 @@ func getTaskRepoAddrAndCommitId(tx *db.Session, tpl *models.Template, revision s
 
 	repoAddr = tpl.RepoAddr
 	if tpl.VcsId == "" { // 用户直接填写的 repo 地址
-		commitId = revision
+		commitId = utils.FirstValueStr(pinnedCommitId, revision)
 	} else {
 		var (
 			vcs  *models.Vcs
@@ -179,7 +225,7 @@ func getTaskRepoAddrAndCommitId(tx *db.Session, tpl *models.Template, revision s
 			return "", "", err
 		}
 
-		commitId, err = repo.BranchCommitId(revision)
+		commitId, err = resolveTaskCommitId(repo, revision, pinnedCommitId)
 		if err != nil {
 			return "", "", e.New(e.VcsError, err)
 		}
@@ -215,6 +261,14 @@ func getTaskRepoAddrAndCommitId(tx *db.Session, tpl *models.Template, revision s
 	return repoAddr, commitId, nil
 }
 
+// resolveTaskCommitId 获取任务要执行的 commit id，指定了 pinnedCommitId 时直接使用，否则解析分支或 tag 当前的 commit
+func resolveTaskCommitId(repo vcsrv.RepoIface, revision string, pinnedCommitId string) (string, error) {
+	if pinnedCommitId != "" {
+		return pinnedCommitId, nil
+	}
+	return repo.BranchCommitId(revision)
+}
+
 func GetTaskById(tx *db.Session, id models.Id) (*models.Task, e.Error) {
 	o := models.Task{}
 	if err := tx.Where("id = ?", id).First(&o); err != nil {
@@ -304,6 +358,7 @@ type TfStateModule struct {
 type TfStateVariable struct {
 	Value     interface{} `json:"value"`
 	Sensitive bool        `json:"sensitive,omitempty"`
+	Encrypted bool        `json:"encrypted,omitempty"` // 保存到任务结果时敏感输出的值 json 编码后加密保存
 }
 
 type TfStateResource struct {
@@ -315,6 +370,8 @@ type TfStateResource struct {
 	Index        interface{} `json:"index"` // index 可以为整型或字符串
 
 	Values map[string]interface{} `json:"values"`
+	// 结构与 values 相同，值为 true 的属性为敏感属性(terraform >= 0.15)
+	SensitiveValues interface{} `json:"sensitive_values,omitempty"`
 }
 
 func UnmarshalStateJson(bs []byte) (*TfState, error) {
@@ -338,7 +395,7 @@ func traverseStateModule(module *TfStateModule) (rs []*models.Resource) {
 			Type:     r.Type,
 			Name:     r.Name,
 			Index:    idx,
-			Attrs:    r.Values,
+			Attrs:    MaskResourceAttrs(r.Values, r.SensitiveValues),
 		})
 	}
 
@@ -376,9 +433,17 @@ func SaveTaskResources(tx *db.Session, task *models.Task, values TfStateValues)
 	return nil
 }
 
+// SaveTaskOutputs 保存任务的输出，terraform 标记为 sensitive 的输出加密后保存
 func SaveTaskOutputs(dbSess *db.Session, task *models.Task, vars map[string]TfStateVariable) error {
 	task.Result.Outputs = make(map[string]interface{}, 0)
 	for k, v := range vars {
+		if v.Sensitive && !v.Encrypted {
+			encrypted, err := encryptOutputValue(v.Value)
+			if err != nil {
+				return err
+			}
+			v = TfStateVariable{Value: encrypted, Sensitive: true, Encrypted: true}
+		}
 		task.Result.Outputs[k] = v
 	}
 	if _, err := dbSess.Model(&models.Task{}).Where("id = ?", task.Id).
@@ -388,11 +453,91 @@ func SaveTaskOutputs(dbSess *db.Session, task *models.Task, vars map[string]TfSt
 	return nil
 }
 
+// MaskTaskOutputs 返回隐藏了敏感输出值的任务输出及敏感输出的名称列表，
+// reveal 为 true 时返回敏感输出解密后的值
+func MaskTaskOutputs(outputs map[string]interface{}, reveal bool) (map[string]interface{}, []string, error) {
+	masked := make(map[string]interface{}, len(outputs))
+	names := make([]string, 0)
+	for name, output := range outputs {
+		o, ok := output.(map[string]interface{})
+		if !ok || o["sensitive"] != true {
+			masked[name] = output
+			continue
+		}
+		names = append(names, name)
+
+		value := o["value"]
+		if !reveal {
+			value = models.MaskedVariableValue
+		} else if o["encrypted"] == true {
+			var err error
+			if value, err = decryptOutputValue(o["value"]); err != nil {
+				return nil, nil, errors.Wrapf(err, "decrypt output '%s'", name)
+			}
+		}
+		masked[name] = map[string]interface{}{"value": value, "sensitive": true}
+	}
+	sort.Strings(names)
+	return masked, names, nil
+}
+
+// EncryptTaskOutputs 加密任务结果中以明文保存的敏感输出，返回加密的输出数量
+func EncryptTaskOutputs(tx *db.Session, task *models.Task) (int, e.Error) {
+	count := 0
+	for name, output := range task.Result.Outputs {
+		o, ok := output.(map[string]interface{})
+		if !ok || o["sensitive"] != true || o["encrypted"] == true {
+			continue
+		}
+		encrypted, err := encryptOutputValue(o["value"])
+		if err != nil {
+			return count, e.New(e.InternalError, errors.Wrapf(err, "encrypt output '%s'", name))
+		}
+		task.Result.Outputs[name] = TfStateVariable{Value: encrypted, Sensitive: true, Encrypted: true}
+		count += 1
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	if _, err := tx.Model(&models.Task{}).Where("id = ?", task.Id).
+		UpdateColumn("result", task.Result); err != nil {
+		return count, e.New(e.DBError, err)
+	}
+	return count, nil
+}
+
+// encryptOutputValue 将输出值 json 编码后加密
+func encryptOutputValue(value interface{}) (string, error) {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return utils.AesEncrypt(string(bs))
+}
+
+// decryptOutputValue 解密 SaveTaskOutputs 加密保存的输出值
+func decryptOutputValue(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid encrypted value")
+	}
+	plain, err := utils.AesDecrypt(s)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(plain), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
 // TfPlan doc: https://www.terraform.io/docs/internals/json-format.html#plan-representation
 type TfPlan struct {
 	FormatVersion string `json:"format_version"`
 
 	ResourceChanges []TfPlanResource `json:"resource_changes"`
+	ResourceDrift   []TfPlanResource `json:"resource_drift"` // refresh 检测到的资源漂移(terraform >= 0.15.4)
 }
 
 type TfPlanResource struct {
@@ -420,10 +565,24 @@ func UnmarshalPlanJson(bs []byte) (*TfPlan, error) {
 	return &plan, err
 }
 
-func SaveTaskChanges(dbSess *db.Session, task *models.Task, rs []TfPlanResource) error {
-	task.Result.ResAdded = 0
-	task.Result.ResChanged = 0
-	task.Result.ResDestroyed = 0
+// GetTaskPlan 读取任务 plan 步骤生成的执行计划，plan 步骤未执行完成时返回 nil
+func GetTaskPlan(task *models.Task) (*TfPlan, e.Error) {
+	bs, err := logstorage.Get().Read(task.PlanJsonPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	plan, err := UnmarshalPlanJson(bs)
+	if err != nil {
+		return nil, e.New(e.InternalError, fmt.Errorf("unmarshal plan json: %v", err))
+	}
+	return plan, nil
+}
+
+// CountPlanChanges 统计执行计划中新增、修改、删除的资源数量
+func CountPlanChanges(rs []TfPlanResource) (added, changed, destroyed int) {
 	for _, r := range rs {
 		actions := r.Change.Actions
 		switch {
@@ -431,14 +590,35 @@ func SaveTaskChanges(dbSess *db.Session, task *models.Task, rs []TfPlanResource)
 			utils.SliceEqualStr(actions, []string{"create", "delete"}):
 			continue
 		case utils.SliceEqualStr(actions, []string{"create"}):
-			task.Result.ResAdded += 1
+			added += 1
 		case utils.SliceEqualStr(actions, []string{"update"}),
 			utils.SliceEqualStr(actions, []string{"delete", "create"}):
-			task.Result.ResChanged += 1
+			changed += 1
 		case utils.SliceEqualStr(actions, []string{"delete"}):
-			task.Result.ResDestroyed += 1
+			destroyed += 1
 		default:
-			logs.Get().WithField("taskId", task.Id).Errorf("unknown change actions: %v", actions)
+			logs.Get().WithField("address", r.Address).Errorf("unknown change actions: %v", actions)
+		}
+	}
+	return
+}
+
+func SaveTaskChanges(dbSess *db.Session, task *models.Task, rs []TfPlanResource) error {
+	task.Result.ResAdded, task.Result.ResChanged, task.Result.ResDestroyed = CountPlanChanges(rs)
+
+	if _, err := dbSess.Model(&models.Task{}).Where("id = ?", task.Id).
+		UpdateColumn("result", task.Result); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SaveTaskDrift 统计 refresh 任务检测到的资源漂移，漂移不计入资源变更
+func SaveTaskDrift(dbSess *db.Session, task *models.Task, rs []TfPlanResource) error {
+	task.Result.ResDrifted = 0
+	for _, r := range rs {
+		if !utils.SliceEqualStr(r.Change.Actions, []string{"no-op"}) {
+			task.Result.ResDrifted += 1
 		}
 	}
 
@@ -529,6 +709,38 @@ var (
 	ErrRunnerTaskNotExists = errors.New("runner task not exists")
 )
 
+// RunnerTaskWorkspaceExists 查询 runner 上是否还保留了任务的工作目录
+func RunnerTaskWorkspaceExists(task *models.Task) (bool, error) {
+	runnerAddr, err := GetRunnerAddress(task.RunnerId)
+	if err != nil {
+		return false, errors.Wrapf(err, "get runner address")
+	}
+
+	params := url.Values{}
+	params.Add("envId", string(task.EnvId))
+	params.Add("taskId", string(task.Id))
+	requestUrl := fmt.Sprintf("%s?%s", utils.JoinURL(runnerAddr, consts.RunnerTaskWorkspaceURL), params.Encode())
+	respData, err := utils.HttpService(requestUrl, http.MethodGet, nil, nil,
+		int(consts.RunnerConnectTimeout.Seconds()), int(consts.RunnerConnectTimeout.Seconds()))
+	if err != nil {
+		return false, err
+	}
+
+	resp := struct {
+		Error  string `json:"error"`
+		Result struct {
+			Exists bool `json:"exists"`
+		} `json:"result"`
+	}{}
+	if err := json.Unmarshal(respData, &resp); err != nil {
+		return false, fmt.Errorf("unexpected response: %s", respData)
+	}
+	if resp.Error != "" {
+		return false, fmt.Errorf(resp.Error)
+	}
+	return resp.Result.Exists, nil
+}
+
 // 从 runner 获取任务日志，直到任务结束
 func fetchRunnerTaskStepLog(ctx context.Context, runnerId string, step *models.TaskStep, writer io.Writer) error {
 	logger := logs.Get().WithField("func", "fetchRunnerTaskStepLog").
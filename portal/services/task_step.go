@@ -118,6 +118,26 @@ func ChangeTaskStepStatus(dbSess *db.Session, task *models.Task, taskStep *model
 	return ChangeTaskStatusWithStep(dbSess, task, taskStep)
 }
 
+// ResetTaskStepForRetry 重置失败的步骤为 pending 状态以便自动重试，并增加重试次数
+func ResetTaskStepForRetry(dbSess *db.Session, taskStep *models.TaskStep) e.Error {
+	taskStep.RetryCount += 1
+	taskStep.Status = models.TaskStepPending
+	taskStep.Message = ""
+	taskStep.StartAt = nil
+	taskStep.EndAt = nil
+
+	if _, err := dbSess.Model(&models.TaskStep{}).Where("id = ?", taskStep.Id).UpdateAttrs(models.Attrs{
+		"retry_count": taskStep.RetryCount,
+		"status":      taskStep.Status,
+		"message":     taskStep.Message,
+		"start_at":    nil,
+		"end_at":      nil,
+	}); err != nil {
+		return e.New(e.DBError, err)
+	}
+	return nil
+}
+
 func createTaskStep(tx *db.Session, task models.Task, stepBody models.TaskStepBody, index int, nextStep models.Id) (
 	*models.TaskStep, e.Error) {
 	s := models.TaskStep{
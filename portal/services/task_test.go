@@ -3,6 +3,9 @@
 package services
 
 import (
+	"cloudiac/portal/models"
+	"cloudiac/portal/services/vcsrv"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -149,3 +152,71 @@ func TestParseStateJson(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestMaskTaskOutputs(t *testing.T) {
+	outputs := map[string]interface{}{
+		"public_ip": map[string]interface{}{"value": []interface{}{"1.2.3.4"}},
+		"password":  map[string]interface{}{"value": "cipher", "sensitive": true, "encrypted": true},
+		"token":     map[string]interface{}{"value": "plain", "sensitive": true},
+	}
+
+	masked, names, err := MaskTaskOutputs(outputs, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"password", "token"}, names)
+	assert.Equal(t, outputs["public_ip"], masked["public_ip"])
+	assert.Equal(t, map[string]interface{}{"value": models.MaskedVariableValue, "sensitive": true}, masked["password"])
+	assert.Equal(t, map[string]interface{}{"value": models.MaskedVariableValue, "sensitive": true}, masked["token"])
+	// 不修改原输出
+	assert.Equal(t, "plain", outputs["token"].(map[string]interface{})["value"])
+}
+
+func TestMaskResourceAttrs(t *testing.T) {
+	state, err := UnmarshalStateJson([]byte(`{"values": {"root_module": {"resources": [{
+		"address": "alicloud_db_account.default",
+		"values": {
+			"name": "admin",
+			"account_password": "p@ssw0rd",
+			"kms_encrypted_password": "",
+			"connection": [{"host": "1.2.3.4", "key": "pem"}],
+			"token_ttl": 60
+		},
+		"sensitive_values": {"connection": [{"key": true}]}
+	}]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := traverseStateModule(&state.Values.RootModule)
+	assert.Equal(t, models.ResAttrs{
+		"name":                   "admin",
+		"account_password":       models.MaskedVariableValue,
+		"kms_encrypted_password": "",
+		"connection":             []interface{}{map[string]interface{}{"host": "1.2.3.4", "key": models.MaskedVariableValue}},
+		"token_ttl":              float64(60),
+	}, rs[0].Attrs)
+
+	assert.Equal(t, map[string]interface{}{"secret": models.MaskedVariableValue},
+		MaskResourceAttrs(map[string]interface{}{"secret": map[string]interface{}{"a": 1}}, map[string]interface{}{"secret": true}))
+	assert.Nil(t, MaskResourceAttrs(nil, nil))
+}
+
+// deletedBranchRepo 模拟分支已被删除的仓库
+type deletedBranchRepo struct {
+	vcsrv.RepoIface
+}
+
+func (deletedBranchRepo) BranchCommitId(branch string) (string, error) {
+	return "", fmt.Errorf("branch '%s' not found", branch)
+}
+
+func TestResolveTaskCommitId(t *testing.T) {
+	repo := deletedBranchRepo{}
+
+	// 回滚或晋级时指定了 commit，分支无法解析也可以创建任务
+	commitId, err := resolveTaskCommitId(repo, "feature/deleted", "2f3c1a9")
+	assert.NoError(t, err)
+	assert.Equal(t, "2f3c1a9", commitId)
+
+	_, err = resolveTaskCommitId(repo, "feature/deleted", "")
+	assert.Error(t, err)
+}
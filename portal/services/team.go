@@ -0,0 +1,216 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"cloudiac/utils"
+	"fmt"
+)
+
+func CreateTeam(tx *db.Session, team models.Team) (*models.Team, e.Error) {
+	if team.Id == "" {
+		team.Id = models.NewId("team")
+	}
+	if err := models.Create(tx, &team); err != nil {
+		if e.IsDuplicate(err) {
+			return nil, e.New(e.TeamAlreadyExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &team, nil
+}
+
+func UpdateTeam(tx *db.Session, id models.Id, attrs models.Attrs) (team *models.Team, er e.Error) {
+	team = &models.Team{}
+	if _, err := models.UpdateAttr(tx.Where("id = ?", id), &models.Team{}, attrs); err != nil {
+		if e.IsDuplicate(err) {
+			return nil, e.New(e.TeamAliasDuplicate)
+		}
+		return nil, e.New(e.DBError, fmt.Errorf("update team error: %v", err))
+	}
+	if err := tx.Where("id = ?", id).First(team); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.TeamNotExists)
+		}
+		return nil, e.New(e.DBError, fmt.Errorf("query team error: %v", err))
+	}
+	return
+}
+
+// DeleteTeam 删除团队，同时删除团队成员及项目授权
+func DeleteTeam(tx *db.Session, id models.Id) e.Error {
+	if _, err := tx.Where("team_id = ?", id).Delete(&models.TeamUser{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete team user error: %v", err))
+	}
+	if _, err := tx.Where("team_id = ?", id).Delete(&models.TeamProject{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete team project error: %v", err))
+	}
+	if _, err := tx.Where("id = ?", id).Delete(&models.Team{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete team error: %v", err))
+	}
+	return nil
+}
+
+func QueryTeam(query *db.Session) *db.Session {
+	return query.Model(&models.Team{})
+}
+
+func GetTeamById(query *db.Session, id models.Id) (*models.Team, e.Error) {
+	team := models.Team{}
+	if err := query.Model(models.Team{}).Where("id = ?", id).First(&team); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.TeamNotExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &team, nil
+}
+
+// AddTeamUsers 添加团队成员，已在团队中的用户会被忽略
+func AddTeamUsers(tx *db.Session, teamId models.Id, userIds []models.Id) e.Error {
+	bq := utils.NewBatchSQL(1024, "INSERT IGNORE INTO", models.TeamUser{}.TableName(),
+		"team_id", "user_id")
+
+	for _, userId := range userIds {
+		if err := bq.AddRow(teamId, userId); err != nil {
+			return e.New(e.DBError, err)
+		}
+	}
+
+	for bq.HasNext() {
+		sql, args := bq.Next()
+		if _, err := tx.Exec(sql, args...); err != nil {
+			return e.New(e.DBError, err)
+		}
+	}
+	return nil
+}
+
+func DeleteTeamUser(tx *db.Session, teamId models.Id, userId models.Id) e.Error {
+	if _, err := tx.Where("team_id = ? AND user_id = ?", teamId, userId).Delete(&models.TeamUser{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete team user error: %v", err))
+	}
+	return nil
+}
+
+// DeleteUserOrgTeams 将用户从组织下的所有团队中移除
+func DeleteUserOrgTeams(tx *db.Session, userId models.Id, orgId models.Id) e.Error {
+	teamIds := make([]models.Id, 0)
+	if err := tx.Model(models.Team{}).Where("org_id = ?", orgId).Pluck("id", &teamIds); err != nil {
+		return e.New(e.DBError, err)
+	}
+	if len(teamIds) == 0 {
+		return nil
+	}
+	if _, err := tx.Where("user_id = ? AND team_id IN (?)", userId, teamIds).Delete(&models.TeamUser{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete team user error: %v", err))
+	}
+	return nil
+}
+
+func GetTeamUserIds(query *db.Session, teamId models.Id) ([]models.Id, e.Error) {
+	userIds := make([]models.Id, 0)
+	if err := query.Model(models.TeamUser{}).Where("team_id = ?", teamId).Pluck("user_id", &userIds); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return userIds, nil
+}
+
+// IsTeamUser 判断用户是否为团队成员
+func IsTeamUser(query *db.Session, teamId models.Id, userId models.Id) (bool, e.Error) {
+	exists, err := query.Model(models.TeamUser{}).Where("team_id = ? AND user_id = ?", teamId, userId).Exists()
+	if err != nil {
+		return false, e.New(e.DBError, err)
+	}
+	return exists, nil
+}
+
+func CreateTeamProject(tx *db.Session, teamProject models.TeamProject) (*models.TeamProject, e.Error) {
+	if err := models.Create(tx, &teamProject); err != nil {
+		if e.IsDuplicate(err) {
+			return nil, e.New(e.TeamProjectAlreadyExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &teamProject, nil
+}
+
+func UpdateTeamProject(tx *db.Session, attrs models.Attrs) e.Error {
+	if _, err := models.UpdateAttr(tx, &models.TeamProject{}, attrs); err != nil {
+		return e.New(e.DBError, fmt.Errorf("update team project error: %v", err))
+	}
+	return nil
+}
+
+func DeleteTeamProject(tx *db.Session, id uint) e.Error {
+	if _, err := tx.Where("id = ?", id).Delete(&models.TeamProject{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete team project error: %v", err))
+	}
+	return nil
+}
+
+// QueryProjectTeams 项目下授权的团队
+func QueryProjectTeams(query *db.Session, projectId models.Id) *db.Session {
+	tpTable := models.TeamProject{}.TableName()
+	return query.Table(tpTable).
+		Joins(fmt.Sprintf("left join %s as t on t.id = %s.team_id", models.Team{}.TableName(), tpTable)).
+		Where(fmt.Sprintf("%s.project_id = ?", tpTable), projectId).
+		LazySelectAppend(fmt.Sprintf("%s.*, t.name as team_name", tpTable))
+}
+
+// GetUserTeamProjects 用户通过团队获得的项目角色，orgId 不为空时只查询该组织下团队的授权
+func GetUserTeamProjects(query *db.Session, userId models.Id, orgId models.Id) ([]models.TeamProject, e.Error) {
+	tpTable := models.TeamProject{}.TableName()
+	query = query.Table(tpTable).
+		Joins(fmt.Sprintf("join %s as tu on tu.team_id = %s.team_id", models.TeamUser{}.TableName(), tpTable)).
+		Where("tu.user_id = ?", userId)
+	if orgId != "" {
+		query = query.Joins(fmt.Sprintf("join %s as t on t.id = %s.team_id", models.Team{}.TableName(), tpTable)).
+			Where("t.org_id = ?", orgId)
+	}
+
+	teamProjects := make([]models.TeamProject, 0)
+	if err := query.LazySelectAppend(fmt.Sprintf("%s.*", tpTable)).
+		Find(&teamProjects); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	return teamProjects, nil
+}
+
+// GetProjectTeamUserRoles 项目下通过团队授权的用户及其角色
+// @return map[models.Id]string 返回 map[userId]role
+func GetProjectTeamUserRoles(query *db.Session, projectId models.Id) (map[models.Id]string, e.Error) {
+	rows := make([]struct {
+		UserId models.Id
+		Role   string
+	}, 0)
+	tpTable := models.TeamProject{}.TableName()
+	if err := query.Table(tpTable).
+		Joins(fmt.Sprintf("join %s as tu on tu.team_id = %s.team_id", models.TeamUser{}.TableName(), tpTable)).
+		Where(fmt.Sprintf("%s.project_id = ?", tpTable), projectId).
+		LazySelectAppend("tu.user_id", fmt.Sprintf("%s.role", tpTable)).
+		Scan(&rows); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+
+	roles := make(map[models.Id]string)
+	for _, r := range rows {
+		roles[r.UserId] = MaxProjectRole(roles[r.UserId], r.Role)
+	}
+	return roles, nil
+}
+
+// MaxProjectRole 返回权限最高的项目角色
+func MaxProjectRole(roles ...string) string {
+	maxRole := ""
+	for _, role := range roles {
+		if consts.ProjectRolePriority[role] > consts.ProjectRolePriority[maxRole] {
+			maxRole = role
+		}
+	}
+	return maxRole
+}
@@ -0,0 +1,26 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMaxProjectRole(t *testing.T) {
+	cases := []struct {
+		roles  []string
+		expect string
+	}{
+		{nil, ""},
+		{[]string{"", consts.ProjectRoleGuest}, consts.ProjectRoleGuest},
+		{[]string{consts.ProjectRoleOperator, consts.ProjectRoleGuest}, consts.ProjectRoleOperator},
+		{[]string{consts.ProjectRoleApprover, consts.ProjectRoleManager, consts.ProjectRoleOperator}, consts.ProjectRoleManager},
+		{[]string{consts.ProjectRoleGuest, "unknown"}, consts.ProjectRoleGuest},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expect, MaxProjectRole(c.roles...))
+	}
+}
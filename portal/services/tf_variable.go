@@ -0,0 +1,382 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"cloudiac/portal/services/vcsrv"
+	"cloudiac/utils"
+	"cloudiac/utils/logs"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// TfVariableDecl 模板中声明的 terraform 变量
+type TfVariableDecl struct {
+	Name        string
+	Type        cty.Type   // 未声明类型时为 cty.DynamicPseudoType
+	Default     *cty.Value // 未设置默认值时为 nil，即必填变量
+	Description string
+	Sensitive   bool
+	Validations []TfVariableValidation
+}
+
+type TfVariableValidation struct {
+	Condition    hcl.Expression
+	ErrorMessage string
+}
+
+func (d *TfVariableDecl) Required() bool {
+	return d.Default == nil
+}
+
+// IsHclValue 变量值是否需要以 hcl 表达式(而非字符串)的形式传入 terraform，
+// 复杂类型(list/map/object 等)的变量值需要按 hcl 语法解析
+func (d *TfVariableDecl) IsHclValue() bool {
+	return d.Type != cty.DynamicPseudoType && !d.Type.IsPrimitiveType()
+}
+
+// ParseTfVariableDecls 解析 tf 文件中的变量声明，类型或默认值解析失败时只记录日志，不影响其他变量的解析
+func ParseTfVariableDecls(filename string, content []byte) ([]TfVariableDecl, e.Error) {
+	logger := logs.Get().WithField("filename", filename)
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, e.New(e.HCLParseError, diags)
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, e.New(e.HCLParseError, fmt.Errorf("unexpected body type %T", file.Body))
+	}
+
+	decls := make([]TfVariableDecl, 0)
+	for _, block := range body.Blocks {
+		if block.Type != "variable" || len(block.Labels) != 1 {
+			continue
+		}
+		decl := TfVariableDecl{
+			Name: block.Labels[0],
+			Type: cty.DynamicPseudoType,
+		}
+		if attr, ok := block.Body.Attributes["type"]; ok {
+			ty, diags := typeexpr.TypeConstraint(attr.Expr)
+			if diags.HasErrors() {
+				logger.Warnf("variable %s: %v", decl.Name, diags)
+			} else {
+				decl.Type = ty
+			}
+		}
+		if attr, ok := block.Body.Attributes["default"]; ok {
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				// 无法解析的默认值不做检查，但变量仍视为非必填
+				logger.Warnf("variable %s: %v", decl.Name, diags)
+				val = cty.DynamicVal
+			}
+			decl.Default = &val
+		}
+		if attr, ok := block.Body.Attributes["description"]; ok {
+			if val, diags := attr.Expr.Value(nil); !diags.HasErrors() && val.Type() == cty.String && val.IsKnown() && !val.IsNull() {
+				decl.Description = val.AsString()
+			}
+		}
+		if attr, ok := block.Body.Attributes["sensitive"]; ok {
+			if val, diags := attr.Expr.Value(nil); !diags.HasErrors() && val.Type() == cty.Bool && val.IsKnown() && !val.IsNull() {
+				decl.Sensitive = val.True()
+			}
+		}
+		for _, b := range block.Body.Blocks {
+			if b.Type != "validation" {
+				continue
+			}
+			attr, ok := b.Body.Attributes["condition"]
+			if !ok {
+				continue
+			}
+			validation := TfVariableValidation{Condition: attr.Expr}
+			if attr, ok := b.Body.Attributes["error_message"]; ok {
+				if val, diags := attr.Expr.Value(nil); !diags.HasErrors() && val.Type() == cty.String && val.IsKnown() && !val.IsNull() {
+					validation.ErrorMessage = val.AsString()
+				}
+			}
+			decl.Validations = append(decl.Validations, validation)
+		}
+		decls = append(decls, decl)
+	}
+	return decls, nil
+}
+
+// ParseTfVarsNames 解析 tfvars 文件中赋值的变量名称
+func ParseTfVarsNames(filename string, content []byte) ([]string, e.Error) {
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, e.New(e.HCLParseError, diags)
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, e.New(e.HCLParseError, diags)
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ParseTfVariableValue 按变量声明的类型解析变量值。
+// 字符串及未声明类型的变量直接使用原值；数字、布尔类型按 terraform 的规则由字符串转换；
+// 复杂类型的值按 hcl 表达式(兼容 json)解析后再做类型转换
+func ParseTfVariableValue(decl TfVariableDecl, value string) (cty.Value, error) {
+	if decl.Type == cty.DynamicPseudoType || decl.Type == cty.String {
+		return cty.StringVal(value), nil
+	}
+
+	var val cty.Value
+	if decl.Type.IsPrimitiveType() {
+		val = cty.StringVal(strings.TrimSpace(value))
+	} else {
+		expr, diags := hclsyntax.ParseExpression([]byte(value), decl.Name, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return cty.NilVal, fmt.Errorf("invalid expression: %s", diags[0].Summary)
+		}
+		val, diags = expr.Value(nil)
+		if diags.HasErrors() {
+			return cty.NilVal, fmt.Errorf("invalid expression: %s", diags[0].Summary)
+		}
+	}
+
+	return convert.Convert(val, decl.Type)
+}
+
+// CheckTfVariables 使用模板中的变量声明检查变量值，values 为解密后的变量值，fileVars 为 tfvars 文件中已赋值的变量。
+// 类型不匹配、缺少必填变量、未通过 validation 条件时返回 errs，模板中未声明的变量返回 warnings
+func CheckTfVariables(decls []TfVariableDecl, values map[string]string, fileVars []string) (errs []string, warnings []string) {
+	declared := make(map[string]struct{}, len(decls))
+	for _, decl := range decls {
+		declared[decl.Name] = struct{}{}
+
+		value, ok := values[decl.Name]
+		if !ok {
+			if decl.Required() && !utils.StrInArray(decl.Name, fileVars...) {
+				errs = append(errs, fmt.Sprintf("variable '%s' is required", decl.Name))
+			}
+			continue
+		}
+
+		val, err := ParseTfVariableValue(decl, value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("variable '%s': %v", decl.Name, err))
+			continue
+		}
+		if msg, ok := checkTfVariableValidations(decl, val); !ok {
+			errs = append(errs, fmt.Sprintf("variable '%s': %s", decl.Name, msg))
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		warnings = append(warnings, fmt.Sprintf("variable '%s' is not declared in template", name))
+	}
+	return errs, warnings
+}
+
+// checkTfVariableValidations 检查变量的 validation 条件，条件无法计算(如使用了不支持的函数)时跳过
+func checkTfVariableValidations(decl TfVariableDecl, val cty.Value) (string, bool) {
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{decl.Name: val}),
+		},
+		Functions: tfValidationFunctions,
+	}
+	for _, v := range decl.Validations {
+		result, diags := v.Condition.Value(ctx)
+		if diags.HasErrors() || !result.IsKnown() || result.IsNull() {
+			continue
+		}
+		if result, err := convert.Convert(result, cty.Bool); err != nil || result.True() {
+			continue
+		}
+		return utils.FirstValueStr(v.ErrorMessage, "validation condition failed"), false
+	}
+	return "", true
+}
+
+// tfLengthFunc 与 terraform 的 length() 一致，同时支持字符串及集合类型
+var tfLengthFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "value", Type: cty.DynamicPseudoType, AllowDynamicType: true},
+	},
+	Type: function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		if args[0].Type() == cty.String {
+			return stdlib.Strlen(args[0])
+		}
+		return stdlib.Length(args[0])
+	},
+})
+
+// tfValidationFunctions validation 条件中可以使用的函数，只包含 terraform 内置函数中常用的部分
+var tfValidationFunctions = map[string]function.Function{
+	"abs":        stdlib.AbsoluteFunc,
+	"can":        tryfunc.CanFunc,
+	"ceil":       stdlib.CeilFunc,
+	"coalesce":   stdlib.CoalesceFunc,
+	"concat":     stdlib.ConcatFunc,
+	"contains":   stdlib.ContainsFunc,
+	"distinct":   stdlib.DistinctFunc,
+	"floor":      stdlib.FloorFunc,
+	"format":     stdlib.FormatFunc,
+	"join":       stdlib.JoinFunc,
+	"jsondecode": stdlib.JSONDecodeFunc,
+	"keys":       stdlib.KeysFunc,
+	"length":     tfLengthFunc,
+	"lookup":     stdlib.LookupFunc,
+	"lower":      stdlib.LowerFunc,
+	"max":        stdlib.MaxFunc,
+	"min":        stdlib.MinFunc,
+	"regex":      stdlib.RegexFunc,
+	"regexall":   stdlib.RegexAllFunc,
+	"split":      stdlib.SplitFunc,
+	"strlen":     stdlib.StrlenFunc,
+	"substr":     stdlib.SubstrFunc,
+	"trimspace":  stdlib.TrimSpaceFunc,
+	"try":        tryfunc.TryFunc,
+	"upper":      stdlib.UpperFunc,
+	"values":     stdlib.ValuesFunc,
+}
+
+// GetTfVariableValues 获取任务中 terraform 变量的明文值，TF_VAR_ 开头的环境变量同样视为 terraform 变量
+func GetTfVariableValues(vars []models.VariableBody) (map[string]string, e.Error) {
+	values := make(map[string]string)
+	envValues := make(map[string]string)
+	for _, v := range vars {
+		var name string
+		switch {
+		case v.Type == consts.VarTypeTerraform:
+			name = v.Name
+		case v.Type == consts.VarTypeEnv && strings.HasPrefix(v.Name, "TF_VAR_"):
+			name = strings.TrimPrefix(v.Name, "TF_VAR_")
+		default:
+			continue
+		}
+
+		value := v.Value
+		if v.Sensitive && value != "" {
+			var err error
+			if value, err = utils.AesDecrypt(value); err != nil {
+				return nil, e.New(e.InternalError, err)
+			}
+		}
+		if v.Type == consts.VarTypeTerraform {
+			values[name] = value
+		} else {
+			envValues[name] = value
+		}
+	}
+	// tfvars 文件中的值优先于 TF_VAR_ 环境变量
+	for name, value := range envValues {
+		if _, ok := values[name]; !ok {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// GetTemplateTfVariableDecls 读取模板工作目录下 tf 文件中的变量声明，tfVarsFile 不为空时同时返回该文件中赋值的变量名称。
+// 模板未关联 vcs 时无法读取文件，返回空列表
+func GetTemplateTfVariableDecls(sess *db.Session, tpl *models.Template, revision, workdir, tfVarsFile string) (
+	decls []TfVariableDecl, fileVars []string, er e.Error) {
+	if tpl.VcsId == "" {
+		return nil, nil, nil
+	}
+	vcs, er := QueryVcsByVcsId(tpl.VcsId, sess)
+	if er != nil {
+		if e.IsRecordNotFound(er) {
+			return nil, nil, e.New(e.VcsNotExists, er)
+		}
+		return nil, nil, e.New(e.DBError, er)
+	}
+	repo, err := vcsrv.GetRepo(vcs, tpl.RepoId)
+	if err != nil {
+		return nil, nil, e.New(e.VcsError, err)
+	}
+
+	workdir = strings.Trim(path.Clean("/"+workdir), "/")
+	files, err := repo.ListFiles(vcsrv.VcsIfaceOptions{
+		Ref:    revision,
+		Path:   workdir,
+		Search: ".tf",
+	})
+	if err != nil {
+		return nil, nil, e.New(e.VcsError, err)
+	}
+	for _, file := range files {
+		file = strings.TrimPrefix(file, "/")
+		dir := path.Dir(file)
+		if dir == "." {
+			dir = ""
+		}
+		if !strings.HasSuffix(file, ".tf") || dir != workdir {
+			continue
+		}
+		content, err := repo.ReadFileContent(revision, file)
+		if err != nil {
+			return nil, nil, e.New(e.VcsError, err)
+		}
+		fileDecls, er := ParseTfVariableDecls(file, content)
+		if er != nil {
+			return nil, nil, er
+		}
+		decls = append(decls, fileDecls...)
+	}
+
+	if tfVarsFile != "" {
+		content, err := repo.ReadFileContent(revision, path.Join(workdir, tfVarsFile))
+		if err != nil {
+			return nil, nil, e.New(e.VcsError, err)
+		}
+		if fileVars, er = ParseTfVarsNames(tfVarsFile, content); er != nil {
+			return nil, nil, er
+		}
+	}
+	return decls, fileVars, nil
+}
+
+// GetTaskHclTfVariables 获取任务模板中声明为复杂类型的 terraform 变量名称，这些变量的值需要以 hcl 表达式的形式写入 tfvars 文件
+func GetTaskHclTfVariables(sess *db.Session, task *models.Task) ([]string, e.Error) {
+	tpl, er := GetTemplateById(sess, task.TplId)
+	if er != nil {
+		return nil, er
+	}
+	decls, _, er := GetTemplateTfVariableDecls(sess, tpl, task.CommitId, task.Workdir, "")
+	if er != nil {
+		return nil, er
+	}
+	names := make([]string, 0)
+	for _, decl := range decls {
+		if decl.IsHclValue() {
+			names = append(names, decl.Name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const testTfVariables = `
+variable "name" {
+  description = "instance name"
+}
+
+variable "count" {
+  type    = number
+  default = 1
+}
+
+variable "enabled" {
+  type    = bool
+  default = false
+}
+
+variable "zones" {
+  type    = list(string)
+  default = ["a"]
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "image_id" {
+  type      = string
+  default   = "ami-00000000"
+  sensitive = true
+  validation {
+    condition     = length(var.image_id) > 4 && substr(var.image_id, 0, 4) == "ami-"
+    error_message = "The image_id value must be a valid AMI id."
+  }
+}
+
+variable "unsupported" {
+  type    = string
+  default = ""
+  validation {
+    condition     = unknownfunc(var.unsupported)
+    error_message = "never"
+  }
+}
+`
+
+func TestParseTfVariableDecls(t *testing.T) {
+	decls, err := ParseTfVariableDecls("variables.tf", []byte(testTfVariables))
+	assert.NoError(t, err)
+	assert.Len(t, decls, 7)
+
+	assert.Equal(t, "name", decls[0].Name)
+	assert.Equal(t, "instance name", decls[0].Description)
+	assert.Equal(t, cty.DynamicPseudoType, decls[0].Type)
+	assert.True(t, decls[0].Required())
+	assert.False(t, decls[0].IsHclValue())
+
+	assert.Equal(t, cty.Number, decls[1].Type)
+	assert.False(t, decls[1].Required())
+	assert.False(t, decls[1].IsHclValue())
+
+	assert.Equal(t, cty.List(cty.String), decls[3].Type)
+	assert.True(t, decls[3].IsHclValue())
+
+	assert.True(t, decls[5].Sensitive)
+	assert.Len(t, decls[5].Validations, 1)
+}
+
+func TestParseTfVariableValue(t *testing.T) {
+	cases := []struct {
+		ty      cty.Type
+		value   string
+		wantErr bool
+	}{
+		{cty.String, `["a"]`, false},
+		{cty.DynamicPseudoType, "any", false},
+		{cty.Number, "10", false},
+		{cty.Number, " 1.5 ", false},
+		{cty.Number, "ten", true},
+		{cty.Bool, "true", false},
+		{cty.Bool, "yes", true},
+		{cty.List(cty.String), `["a", "b"]`, false},
+		{cty.List(cty.Number), `["a"]`, true},
+		{cty.List(cty.String), `a, b`, true},
+		{cty.Map(cty.String), `{"k": "v"}`, false},
+		{cty.Map(cty.String), `{k = "v"}`, false},
+		{cty.Object(map[string]cty.Type{"port": cty.Number}), `{port = 80}`, false},
+		{cty.Object(map[string]cty.Type{"port": cty.Number}), `{host = "a"}`, true},
+		{cty.List(cty.String), `[var.a]`, true},
+	}
+
+	for _, c := range cases {
+		_, err := ParseTfVariableValue(TfVariableDecl{Name: "v", Type: c.ty}, c.value)
+		if c.wantErr {
+			assert.Error(t, err, c.value)
+		} else {
+			assert.NoError(t, err, c.value)
+		}
+	}
+}
+
+func TestCheckTfVariables(t *testing.T) {
+	decls, er := ParseTfVariableDecls("variables.tf", []byte(testTfVariables))
+	assert.NoError(t, er)
+
+	errs, warnings := CheckTfVariables(decls, map[string]string{
+		"name":  "web",
+		"zones": `["a", "b"]`,
+		"other": "x",
+	}, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{"variable 'other' is not declared in template"}, warnings)
+
+	errs, _ = CheckTfVariables(decls, map[string]string{
+		"count":       "many",
+		"tags":        `["a"]`,
+		"image_id":    "img-1",
+		"unsupported": "x",
+	}, nil)
+	assert.Equal(t, []string{
+		"variable 'name' is required",
+		"variable 'count': a number is required",
+		"variable 'tags': map of string required",
+		"variable 'image_id': The image_id value must be a valid AMI id.",
+	}, errs)
+
+	// tfvars 文件中已赋值的必填变量
+	errs, _ = CheckTfVariables(decls, map[string]string{}, []string{"name"})
+	assert.Empty(t, errs)
+}
+
+func TestGetTfVariableValues(t *testing.T) {
+	values, err := GetTfVariableValues([]models.VariableBody{
+		{Type: consts.VarTypeTerraform, Name: "a", Value: "1"},
+		{Type: consts.VarTypeEnv, Name: "TF_VAR_a", Value: "2"},
+		{Type: consts.VarTypeEnv, Name: "TF_VAR_b", Value: "3"},
+		{Type: consts.VarTypeEnv, Name: "HOME", Value: "/root"},
+		{Type: consts.VarTypeAnsible, Name: "c", Value: "4"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "3"}, values)
+}
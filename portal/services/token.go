@@ -123,3 +123,26 @@ func IsExistsTriggerToken(dbSess *db.Session, tokenTrigger string) (*models.Toke
 	}
 	return &token, nil
 }
+
+// GetApiTokenByKey 查询用于 api 访问的 token(服务账号及个人访问令牌)
+func GetApiTokenByKey(dbSess *db.Session, key string) (*models.Token, e.Error) {
+	token := models.Token{}
+	if err := dbSess.Model(&models.Token{}).
+		Where("`key` = ?", key).
+		Where("`type` IN (?)", []string{consts.TokenApi, consts.TokenPersonal}).
+		First(&token); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.TokenNotExists)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &token, nil
+}
+
+func UpdateTokenLastUsedAt(dbSess *db.Session, id models.Id) e.Error {
+	if _, err := dbSess.Model(&models.Token{}).Where("id = ?", id).
+		UpdateColumn("last_used_at", models.Time(time.Now())); err != nil {
+		return e.New(e.DBError, err)
+	}
+	return nil
+}
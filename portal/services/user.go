@@ -140,16 +140,29 @@ func GetUserRoleByOrg(dbSess *db.Session, userId, orgId models.Id, role string)
 	return isExists, nil
 }
 
+// GetUserRoleByProject 用户在项目中是否有指定角色的授权(直接授权或通过团队授权)
 func GetUserRoleByProject(dbSess *db.Session, userId, projectId models.Id, role string) (bool, e.Error) {
-	isExists, err := dbSess.Table(models.UserProject{}.TableName()).
+	roles := make([]string, 0)
+	if err := dbSess.Table(models.UserProject{}.TableName()).
 		Where("user_id = ?", userId).
-		Where("role = ?", role).
 		Where("project_id = ?", projectId).
-		Exists()
+		Pluck("role", &roles); err != nil {
+		return false, e.New(e.DBError, err)
+	}
+
+	teamRoles, err := GetProjectTeamUserRoles(dbSess, projectId)
 	if err != nil {
-		return isExists, e.New(e.DBError, err)
+		return false, err
 	}
-	return isExists, nil
+	if r, ok := teamRoles[userId]; ok {
+		roles = append(roles, r)
+	}
+	for _, r := range roles {
+		if r == role {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // ========================================================================
@@ -195,8 +208,53 @@ func UserHasProjectRole(userId models.Id, orgId models.Id, projectId models.Id,
 	if role == "" {
 		return true
 	} else {
-		return role == userProjects[orgId].Role
+		return role == userProjects[projectId].Role
+	}
+}
+
+// AccessOrgRole 访问者在组织下用于 rbac 鉴权的角色
+func AccessOrgRole(userId, orgId models.Id, isSuperAdmin bool, apiToken *models.Token) string {
+	switch {
+	case userId == "":
+		return consts.RoleAnonymous
+	case isSuperAdmin:
+		return consts.RoleRoot
+	case orgId == "":
+		return consts.RoleLogin
+	case IsServiceAccountOf(apiToken, orgId):
+		return apiToken.Role
+	}
+	if userOrg := UserOrgRoles(userId)[orgId]; userOrg != nil {
+		return userOrg.Role
 	}
+	return ""
+}
+
+// AccessProjectRole 访问者在项目下用于 rbac 鉴权的角色，平台管理员及组织管理员视为项目管理者
+func AccessProjectRole(userId, orgId, projectId models.Id, isSuperAdmin bool, apiToken *models.Token) string {
+	switch {
+	case isSuperAdmin:
+		return consts.ProjectRoleManager
+	case IsServiceAccountOf(apiToken, orgId):
+		if apiToken.Role == consts.OrgRoleAdmin {
+			return consts.ProjectRoleManager
+		} else if projectId != "" {
+			return apiToken.ProjectRole
+		}
+		return ""
+	case UserHasOrgRole(userId, orgId, consts.OrgRoleAdmin):
+		return consts.ProjectRoleManager
+	case projectId != "":
+		if userProject := UserProjectRoles(userId)[projectId]; userProject != nil {
+			return userProject.Role
+		}
+	}
+	return ""
+}
+
+// IsServiceAccountOf 是否为组织的服务账号
+func IsServiceAccountOf(apiToken *models.Token, orgId models.Id) bool {
+	return apiToken != nil && apiToken.Type == consts.TokenApi && apiToken.OrgId == orgId
 }
 
 // UserHasOrgRole 用户是否拥有组织的某个角色权限
@@ -254,7 +312,7 @@ func getUserOrgs(userId models.Id) map[models.Id]*models.UserOrg {
 	return userOrgsMap
 }
 
-// getUserProjects 获取用户项目关联列表
+// getUserProjects 获取用户项目关联列表(包含通过团队授权的项目)
 // @return map[models.Id]*models.UserProject 返回 map[projectId]UserProject
 func getUserProjects(userId models.Id) map[models.Id]*models.UserProject {
 	userProjects := make([]models.UserProject, 0)
@@ -263,8 +321,25 @@ func getUserProjects(userId models.Id) map[models.Id]*models.UserProject {
 		return nil
 	}
 	userProjectsMap := make(map[models.Id]*models.UserProject)
-	for _, userProject := range userProjects {
-		userProjectsMap[userProject.ProjectId] = &userProject
+	for index, userProject := range userProjects {
+		userProjectsMap[userProject.ProjectId] = &userProjects[index]
+	}
+
+	// 合并用户通过团队获得的项目角色，同一项目取最高角色
+	teamProjects, err := GetUserTeamProjects(query, userId, "")
+	if err != nil {
+		return userProjectsMap
+	}
+	for _, tp := range teamProjects {
+		if up := userProjectsMap[tp.ProjectId]; up != nil {
+			up.Role = MaxProjectRole(up.Role, tp.Role)
+		} else {
+			userProjectsMap[tp.ProjectId] = &models.UserProject{
+				UserId:    userId,
+				ProjectId: tp.ProjectId,
+				Role:      tp.Role,
+			}
+		}
 	}
 	return userProjectsMap
 }
@@ -0,0 +1,349 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"bytes"
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"gopkg.in/yaml.v2"
+)
+
+// 变量文件格式
+const (
+	VariableFileTfVars     = "tfvars"
+	VariableFileTfVarsJson = "json"
+	VariableFileYaml       = "yaml"
+)
+
+// 变量导入时每个变量的处理方式
+const (
+	VariableImportCreate    = "create"    // 新建变量
+	VariableImportOverwrite = "overwrite" // 覆盖已存在的同名变量
+	VariableImportUnchanged = "unchanged" // 同名变量的值相同，不做处理
+	VariableImportConflict  = "conflict"  // 已存在值不同的同名变量，未指定覆盖时不做处理
+	VariableImportInvalid   = "invalid"   // 变量名或值不合法，不做处理
+)
+
+// VariableFileEntry 变量文件中的一个变量，复杂类型的值转为 json 字符串
+type VariableFileEntry struct {
+	Name  string
+	Value string
+	Error string // 值无法解析时的错误信息
+}
+
+// VariableFileDefaultType 变量文件格式对应的默认变量类型
+func VariableFileDefaultType(format string) string {
+	if format == VariableFileYaml {
+		return consts.VarTypeAnsible
+	}
+	return consts.VarTypeTerraform
+}
+
+// VariableFileName 导出变量时使用的文件名
+func VariableFileName(format string) string {
+	switch format {
+	case VariableFileTfVarsJson:
+		return "variables.tfvars.json"
+	case VariableFileYaml:
+		return "variables.yml"
+	default:
+		return "variables.tfvars"
+	}
+}
+
+// ParseVariableFile 解析 tfvars、tfvars.json 或 ansible vars yaml 文件，返回按名称排序的变量列表
+func ParseVariableFile(format string, content []byte) ([]VariableFileEntry, e.Error) {
+	var (
+		entries []VariableFileEntry
+		er      e.Error
+	)
+	switch format {
+	case VariableFileTfVars:
+		entries, er = parseTfVarsFile(content)
+	case VariableFileTfVarsJson:
+		entries, er = parseTfVarsJsonFile(content)
+	case VariableFileYaml:
+		entries, er = parseYamlVarsFile(content)
+	default:
+		return nil, e.New(e.BadParam, fmt.Errorf("unsupported format '%s'", format), http.StatusBadRequest)
+	}
+	if er != nil {
+		return nil, er
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+func parseTfVarsFile(content []byte) ([]VariableFileEntry, e.Error) {
+	file, diags := hclsyntax.ParseConfig(content, "import.tfvars", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, e.New(e.HCLParseError, diags, http.StatusBadRequest)
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, e.New(e.HCLParseError, diags, http.StatusBadRequest)
+	}
+
+	entries := make([]VariableFileEntry, 0, len(attrs))
+	for name, attr := range attrs {
+		entry := VariableFileEntry{Name: name}
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			entry.Error = "only literal values are supported"
+		} else if value, err := ctyValueString(val); err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.Value = value
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseTfVarsJsonFile(content []byte) ([]VariableFileEntry, e.Error) {
+	values := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, e.New(e.BadParam, fmt.Errorf("invalid json: %v", err), http.StatusBadRequest)
+	}
+
+	entries := make([]VariableFileEntry, 0, len(values))
+	for name, raw := range values {
+		entry := VariableFileEntry{Name: name}
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			entry.Value = s
+		} else if string(raw) == "null" {
+			entry.Error = "null value is not supported"
+		} else {
+			buf := bytes.Buffer{}
+			_ = json.Compact(&buf, raw)
+			entry.Value = buf.String()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseYamlVarsFile(content []byte) ([]VariableFileEntry, e.Error) {
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, e.New(e.BadParam, fmt.Errorf("invalid yaml: %v", err), http.StatusBadRequest)
+	}
+
+	entries := make([]VariableFileEntry, 0, len(values))
+	for name, v := range values {
+		entry := VariableFileEntry{Name: name}
+		switch v := v.(type) {
+		case nil:
+			entry.Error = "null value is not supported"
+		case string:
+			entry.Value = v
+		case map[interface{}]interface{}, []interface{}:
+			bs, err := json.Marshal(yamlToJsonValue(v))
+			if err != nil {
+				entry.Error = err.Error()
+			} else {
+				entry.Value = string(bs)
+			}
+		default:
+			entry.Value = fmt.Sprint(v)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// yamlToJsonValue 将 yaml 解析出的 map[interface{}]interface{} 转为可以 json 序列化的 map[string]interface{}
+func yamlToJsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = yamlToJsonValue(val)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = yamlToJsonValue(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func ctyValueString(val cty.Value) (string, error) {
+	if val.IsNull() {
+		return "", fmt.Errorf("null value is not supported")
+	}
+	if val.Type() == cty.String {
+		return val.AsString(), nil
+	}
+	bs, err := ctyjson.Marshal(val, val.Type())
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// parseComplexVariableValue 将 list、map 等复杂类型的变量值(json 或 hcl 语法)解析为 cty.Value
+func parseComplexVariableValue(value string) (cty.Value, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "[") && !strings.HasPrefix(value, "{") {
+		return cty.NilVal, false
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(value), "value", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return cty.NilVal, false
+	}
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || !val.IsWhollyKnown() {
+		return cty.NilVal, false
+	}
+	return val, true
+}
+
+// RenderVariableFile 按指定格式生成变量文件，list、map 等复杂类型的值按原类型输出，其他值作为字符串输出
+func RenderVariableFile(format string, values map[string]string) ([]byte, e.Error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	switch format {
+	case VariableFileTfVars:
+		file := hclwrite.NewEmptyFile()
+		for _, name := range names {
+			val, ok := parseComplexVariableValue(values[name])
+			if !ok {
+				val = cty.StringVal(values[name])
+			}
+			file.Body().SetAttributeValue(name, val)
+		}
+		return file.Bytes(), nil
+	case VariableFileTfVarsJson, VariableFileYaml:
+		m := make(map[string]interface{}, len(values))
+		for _, name := range names {
+			m[name] = values[name]
+			if val, ok := parseComplexVariableValue(values[name]); ok {
+				if bs, err := ctyjson.Marshal(val, val.Type()); err == nil {
+					m[name] = json.RawMessage(bs)
+				}
+			}
+		}
+		bs, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return nil, e.New(e.InternalError, err)
+		}
+		if format == VariableFileTfVarsJson {
+			return append(bs, '\n'), nil
+		}
+		// 通过 json 转换，使复杂类型的值以 yaml 的结构输出
+		var v yaml.MapSlice
+		if err := yaml.Unmarshal(bs, &v); err != nil {
+			return nil, e.New(e.InternalError, err)
+		}
+		if bs, err = yaml.Marshal(v); err != nil {
+			return nil, e.New(e.InternalError, err)
+		}
+		return bs, nil
+	default:
+		return nil, e.New(e.BadParam, fmt.Errorf("unsupported format '%s'", format), http.StatusBadRequest)
+	}
+}
+
+// IsValidVariableName 检查变量名称，terraform 变量名称需要是合法的标识符
+func IsValidVariableName(typ, name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	if typ == consts.VarTypeTerraform {
+		return hclsyntax.ValidIdentifier(name)
+	}
+	return !strings.ContainsAny(name, " \t\r\n=")
+}
+
+// VariableImportItem 导入变量时每个变量的处理结果，敏感变量的值会被隐藏
+type VariableImportItem struct {
+	Name     string    `json:"name"`
+	Action   string    `json:"action"`             // 处理方式: create, overwrite, unchanged, conflict, invalid
+	Reason   string    `json:"reason,omitempty"`   // conflict 及 invalid 的原因
+	Value    string    `json:"value"`              // 导入的值
+	OldValue string    `json:"oldValue,omitempty"` // 已存在的同名变量的值
+	Id       models.Id `json:"-"`                  // 已存在的同名变量的ID
+}
+
+// PlanVariableImport 对比导入的变量与作用域下已存在的同类型变量，计算每个变量的处理方式。
+// 已存在的敏感变量无法比较值，总是视为值不同
+func PlanVariableImport(typ string, entries []VariableFileEntry, existing []models.Variable,
+	sensitive, overwrite bool) []VariableImportItem {
+	existingM := make(map[string]models.Variable, len(existing))
+	for _, v := range existing {
+		existingM[v.Name] = v
+	}
+
+	items := make([]VariableImportItem, 0, len(entries))
+	for _, entry := range entries {
+		item := VariableImportItem{Name: entry.Name, Value: entry.Value}
+		old, exists := existingM[entry.Name]
+		if sensitive || (exists && old.Sensitive) {
+			item.Value = models.MaskedVariableValue
+		}
+		if exists {
+			item.Id = old.Id
+			item.OldValue = maskedVariableValue(&old)
+		}
+
+		switch {
+		case !IsValidVariableName(typ, entry.Name):
+			item.Action = VariableImportInvalid
+			item.Reason = "invalid variable name"
+		case entry.Error != "":
+			item.Action = VariableImportInvalid
+			item.Reason = entry.Error
+		case !exists:
+			item.Action = VariableImportCreate
+		case !old.Sensitive && !sensitive && old.Value == entry.Value:
+			item.Action = VariableImportUnchanged
+		case overwrite:
+			item.Action = VariableImportOverwrite
+		default:
+			item.Action = VariableImportConflict
+			item.Reason = "variable already exists with a different value"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// QueryScopeVariables 查询在指定作用域下定义的变量(不包含继承的变量)
+func QueryScopeVariables(query *db.Session, scope string, orgId, projectId, tplId, envId models.Id) *db.Session {
+	query = query.Model(&models.Variable{}).Where("org_id = ? AND scope = ?", orgId, scope)
+	switch scope {
+	case consts.ScopeEnv:
+		return query.Where("env_id = ?", envId)
+	case consts.ScopeTemplate:
+		return query.Where("tpl_id = ? AND env_id = ''", tplId)
+	case consts.ScopeProject:
+		return query.Where("project_id = ? AND tpl_id = '' AND env_id = ''", projectId)
+	default:
+		return query.Where("project_id = '' AND tpl_id = '' AND env_id = ''")
+	}
+}
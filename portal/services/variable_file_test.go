@@ -0,0 +1,139 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVariableFile(t *testing.T) {
+	want := []VariableFileEntry{
+		{Name: "count", Value: "2"},
+		{Name: "enabled", Value: "true"},
+		{Name: "region", Value: "cn-beijing"},
+		{Name: "tags", Value: `{"env":"dev"}`},
+		{Name: "zones", Value: `["a","b"]`},
+	}
+
+	cases := []struct {
+		format  string
+		content string
+	}{
+		{VariableFileTfVars, `
+region  = "cn-beijing"
+count   = 2
+enabled = true
+zones   = ["a", "b"]
+tags    = { env = "dev" }
+`},
+		{VariableFileTfVarsJson, `{
+  "region": "cn-beijing",
+  "count": 2,
+  "enabled": true,
+  "zones": ["a", "b"],
+  "tags": {"env": "dev"}
+}`},
+		{VariableFileYaml, `
+region: cn-beijing
+count: 2
+enabled: true
+zones:
+  - a
+  - b
+tags:
+  env: dev
+`},
+	}
+	for _, c := range cases {
+		entries, err := ParseVariableFile(c.format, []byte(c.content))
+		assert.NoError(t, err, c.format)
+		assert.Equal(t, want, entries, c.format)
+	}
+
+	entries, err := ParseVariableFile(VariableFileTfVars, []byte("a = var.b\nc = null\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "only literal values are supported", entries[0].Error)
+	assert.Equal(t, "null value is not supported", entries[1].Error)
+
+	_, err = ParseVariableFile(VariableFileTfVars, []byte("a = "))
+	assert.Error(t, err)
+	_, err = ParseVariableFile(VariableFileTfVarsJson, []byte("[]"))
+	assert.Error(t, err)
+	_, err = ParseVariableFile("ini", []byte(""))
+	assert.Error(t, err)
+}
+
+func TestRenderVariableFile(t *testing.T) {
+	values := map[string]string{
+		"region": "cn-beijing",
+		"script": "echo \"${HOME}\"\n",
+		"zones":  `["a","b"]`,
+		"tags":   `{env = "dev"}`,
+	}
+
+	content, err := RenderVariableFile(VariableFileTfVars, values)
+	assert.NoError(t, err)
+	assert.Equal(t, `region = "cn-beijing"
+script = "echo \"$${HOME}\"\n"
+tags = {
+  env = "dev"
+}
+zones = ["a", "b"]
+`, string(content))
+
+	// 导出的文件可以重新导入
+	for _, format := range []string{VariableFileTfVars, VariableFileTfVarsJson, VariableFileYaml} {
+		content, err := RenderVariableFile(format, values)
+		assert.NoError(t, err, format)
+		entries, err := ParseVariableFile(format, content)
+		assert.NoError(t, err, format)
+		assert.Equal(t, []VariableFileEntry{
+			{Name: "region", Value: "cn-beijing"},
+			{Name: "script", Value: "echo \"${HOME}\"\n"},
+			{Name: "tags", Value: `{"env":"dev"}`},
+			{Name: "zones", Value: `["a","b"]`},
+		}, entries, format)
+	}
+}
+
+func TestPlanVariableImport(t *testing.T) {
+	existing := []models.Variable{
+		{BaseModel: models.BaseModel{Id: "v-1"}, VariableBody: models.VariableBody{Name: "same", Value: "1"}},
+		{BaseModel: models.BaseModel{Id: "v-2"}, VariableBody: models.VariableBody{Name: "changed", Value: "1"}},
+		{BaseModel: models.BaseModel{Id: "v-3"}, VariableBody: models.VariableBody{Name: "secret", Value: "cipher", Sensitive: true}},
+	}
+	entries := []VariableFileEntry{
+		{Name: "1invalid", Value: "1"},
+		{Name: "bad", Error: "only literal values are supported"},
+		{Name: "changed", Value: "2"},
+		{Name: "new", Value: "1"},
+		{Name: "same", Value: "1"},
+		{Name: "secret", Value: "1"},
+	}
+
+	items := PlanVariableImport(consts.VarTypeTerraform, entries, existing, false, false)
+	actions := make([]string, 0)
+	for _, item := range items {
+		actions = append(actions, item.Action)
+	}
+	assert.Equal(t, []string{VariableImportInvalid, VariableImportInvalid, VariableImportConflict,
+		VariableImportCreate, VariableImportUnchanged, VariableImportConflict}, actions)
+	assert.Equal(t, models.Id("v-2"), items[2].Id)
+	assert.Equal(t, "1", items[2].OldValue)
+	assert.Equal(t, models.MaskedVariableValue, items[5].Value)
+	assert.Equal(t, models.MaskedVariableValue, items[5].OldValue)
+
+	items = PlanVariableImport(consts.VarTypeTerraform, entries, existing, true, true)
+	assert.Equal(t, VariableImportOverwrite, items[2].Action)
+	assert.Equal(t, models.MaskedVariableValue, items[2].Value)
+	// 导入为敏感变量时无法判断值是否相同
+	assert.Equal(t, VariableImportOverwrite, items[4].Action)
+
+	// 非 terraform 变量名称不要求是标识符
+	items = PlanVariableImport(consts.VarTypeAnsible, entries[:1], nil, false, false)
+	assert.Equal(t, VariableImportCreate, items[0].Action)
+}
@@ -0,0 +1,190 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"cloudiac/utils"
+	"fmt"
+	"sort"
+)
+
+func newVariableHistory(action string, old, updated *models.Variable) models.VariableHistory {
+	v := updated
+	if v == nil {
+		v = old
+	}
+	return models.VariableHistory{
+		OrgId:      v.OrgId,
+		ProjectId:  v.ProjectId,
+		TplId:      v.TplId,
+		EnvId:      v.EnvId,
+		VariableId: v.Id,
+		Scope:      v.Scope,
+		Type:       v.Type,
+		Name:       v.Name,
+		Action:     action,
+		Sensitive:  v.Sensitive,
+		OldValue:   maskedVariableValue(old),
+		NewValue:   maskedVariableValue(updated),
+	}
+}
+
+func maskedVariableValue(v *models.Variable) string {
+	if v == nil {
+		return ""
+	}
+	if v.Sensitive {
+		return models.MaskedVariableValue
+	}
+	return v.Value
+}
+
+// isVariableChanged 变量的名称、值、描述等是否有变化，敏感变量传入新值时即视为有变化
+func isVariableChanged(old, updated models.VariableBody) bool {
+	return old.Name != updated.Name || old.Value != updated.Value || old.Sensitive != updated.Sensitive ||
+		old.Description != updated.Description || old.Path != updated.Path
+}
+
+func createVariableHistories(tx *db.Session, operatorId models.Id, histories []models.VariableHistory) e.Error {
+	for i := range histories {
+		histories[i].Id = models.NewId("vh")
+		histories[i].OperatorId = operatorId
+		if err := models.Create(tx, &histories[i]); err != nil {
+			return e.New(e.DBError, fmt.Errorf("create variable history: %v", err))
+		}
+	}
+	return nil
+}
+
+func QueryVariableHistory(query *db.Session) *db.Session {
+	return query.Model(&models.VariableHistory{}).
+		Joins("left join iac_user as u on u.id = iac_variable_history.operator_id").
+		LazySelectAppend("iac_variable_history.*", "u.name as operator")
+}
+
+// VariableDiff 两次部署使用的变量的差异
+type VariableDiff struct {
+	Type      string `json:"type"`                // 变量类型
+	Name      string `json:"name"`                // 变量名称
+	Action    string `json:"action"`              // 变化类型: create, update, delete
+	Sensitive bool   `json:"sensitive,omitempty"` // 是否为敏感变量
+	OldValue  string `json:"oldValue"`            // 上次部署使用的值，敏感变量为 ******
+	NewValue  string `json:"newValue"`            // 本次部署使用的值，敏感变量为 ******
+	OldScope  string `json:"oldScope,omitempty"`  // 上次部署时变量的来源
+	NewScope  string `json:"newScope,omitempty"`  // 本次部署时变量的来源
+}
+
+// DiffVariables 比较两组变量(如上次成功部署的变量与本次部署的变量)，按类型及名称排序返回有变化的变量。
+// 敏感变量会解密后比较，返回值中隐藏敏感变量的值
+func DiffVariables(olds, news []models.VariableBody) ([]VariableDiff, e.Error) {
+	key := func(v models.VariableBody) string {
+		return v.Type + "/" + v.Name
+	}
+	oldM := make(map[string]models.VariableBody, len(olds))
+	for _, v := range olds {
+		oldM[key(v)] = v
+	}
+	newM := make(map[string]models.VariableBody, len(news))
+	for _, v := range news {
+		newM[key(v)] = v
+	}
+
+	diffs := make([]VariableDiff, 0)
+	for k, nv := range newM {
+		ov, ok := oldM[k]
+		if !ok {
+			diffs = append(diffs, variableDiff(models.VariableActionCreate, nil, &nv))
+			continue
+		}
+		equal, err := isVariableValueEqual(ov, nv)
+		if err != nil {
+			return nil, err
+		}
+		if !equal {
+			diffs = append(diffs, variableDiff(models.VariableActionUpdate, &ov, &nv))
+		}
+	}
+	for k, ov := range oldM {
+		if _, ok := newM[k]; !ok {
+			diffs = append(diffs, variableDiff(models.VariableActionDelete, &ov, nil))
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Type != diffs[j].Type {
+			return diffs[i].Type < diffs[j].Type
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs, nil
+}
+
+func isVariableValueEqual(a, b models.VariableBody) (bool, e.Error) {
+	if a.Sensitive != b.Sensitive || a.Path != b.Path {
+		return false, nil
+	}
+	if !a.Sensitive || a.Value == b.Value {
+		return a.Value == b.Value, nil
+	}
+
+	// 敏感变量每次加密的结果不同，需要解密后比较
+	av, err := utils.AesDecrypt(a.Value)
+	if err != nil {
+		return false, e.New(e.InternalError, err)
+	}
+	bv, err := utils.AesDecrypt(b.Value)
+	if err != nil {
+		return false, e.New(e.InternalError, err)
+	}
+	return av == bv, nil
+}
+
+func variableDiff(action string, old, updated *models.VariableBody) VariableDiff {
+	v := updated
+	if v == nil {
+		v = old
+	}
+	diff := VariableDiff{
+		Type:      v.Type,
+		Name:      v.Name,
+		Action:    action,
+		Sensitive: v.Sensitive,
+	}
+	if old != nil {
+		diff.OldValue = maskedVariableBodyValue(*old)
+		diff.OldScope = old.Scope
+	}
+	if updated != nil {
+		diff.NewValue = maskedVariableBodyValue(*updated)
+		diff.NewScope = updated.Scope
+	}
+	return diff
+}
+
+func maskedVariableBodyValue(v models.VariableBody) string {
+	if v.Sensitive {
+		return models.MaskedVariableValue
+	}
+	return v.Value
+}
+
+// GetLastSucceededApplyTask 获取环境最后一次成功执行的部署任务，不存在时返回 nil
+func GetLastSucceededApplyTask(query *db.Session, envId models.Id, before *models.Time) (*models.Task, e.Error) {
+	query = query.Model(&models.Task{}).Where("env_id = ? AND type = ? AND status = ?",
+		envId, models.TaskTypeApply, models.TaskComplete)
+	if before != nil {
+		query = query.Where("created_at < ?", before)
+	}
+
+	task := models.Task{}
+	if err := query.Order("created_at DESC").First(&task); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, nil
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &task, nil
+}
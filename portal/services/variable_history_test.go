@@ -0,0 +1,69 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVariableHistory(t *testing.T) {
+	old := models.Variable{
+		BaseModel:    models.BaseModel{Id: "v-1"},
+		VariableBody: models.VariableBody{Scope: consts.ScopeEnv, Type: consts.VarTypeEnv, Name: "A", Value: "1"},
+		OrgId:        "org-1",
+		EnvId:        "env-1",
+	}
+	updated := old
+	updated.Value = "encrypted"
+	updated.Sensitive = true
+
+	assert.True(t, isVariableChanged(old.VariableBody, updated.VariableBody))
+	assert.False(t, isVariableChanged(old.VariableBody, old.VariableBody))
+
+	h := newVariableHistory(models.VariableActionUpdate, &old, &updated)
+	assert.Equal(t, models.Id("v-1"), h.VariableId)
+	assert.Equal(t, models.Id("env-1"), h.EnvId)
+	assert.Equal(t, "1", h.OldValue)
+	assert.Equal(t, models.MaskedVariableValue, h.NewValue)
+	assert.True(t, h.Sensitive)
+
+	h = newVariableHistory(models.VariableActionDelete, &updated, nil)
+	assert.Equal(t, models.MaskedVariableValue, h.OldValue)
+	assert.Equal(t, "", h.NewValue)
+}
+
+func TestDiffVariables(t *testing.T) {
+	olds := []models.VariableBody{
+		{Scope: consts.ScopeOrg, Type: consts.VarTypeTerraform, Name: "region", Value: "cn-beijing"},
+		{Scope: consts.ScopeEnv, Type: consts.VarTypeTerraform, Name: "size", Value: "1"},
+		{Scope: consts.ScopeEnv, Type: consts.VarTypeEnv, Name: "TOKEN", Value: "cipher", Sensitive: true},
+		{Scope: consts.ScopeEnv, Type: consts.VarTypeEnv, Name: "REMOVED", Value: "x"},
+	}
+	news := []models.VariableBody{
+		{Scope: consts.ScopeEnv, Type: consts.VarTypeTerraform, Name: "region", Value: "cn-shanghai"},
+		{Scope: consts.ScopeEnv, Type: consts.VarTypeTerraform, Name: "size", Value: "1"},
+		{Scope: consts.ScopeEnv, Type: consts.VarTypeEnv, Name: "TOKEN", Value: "cipher", Sensitive: true},
+		{Scope: consts.ScopeEnv, Type: consts.VarTypeEnv, Name: "SECRET", Value: "cipher", Sensitive: true},
+		// 同名不同类型的变量视为不同的变量
+		{Scope: consts.ScopeEnv, Type: consts.VarTypeAnsible, Name: "size", Value: "2"},
+	}
+
+	diffs, err := DiffVariables(olds, news)
+	assert.NoError(t, err)
+	assert.Equal(t, []VariableDiff{
+		{Type: consts.VarTypeAnsible, Name: "size", Action: models.VariableActionCreate, NewValue: "2", NewScope: consts.ScopeEnv},
+		{Type: consts.VarTypeEnv, Name: "REMOVED", Action: models.VariableActionDelete, OldValue: "x", OldScope: consts.ScopeEnv},
+		{Type: consts.VarTypeEnv, Name: "SECRET", Action: models.VariableActionCreate, Sensitive: true,
+			NewValue: models.MaskedVariableValue, NewScope: consts.ScopeEnv},
+		{Type: consts.VarTypeTerraform, Name: "region", Action: models.VariableActionUpdate,
+			OldValue: "cn-beijing", NewValue: "cn-shanghai", OldScope: consts.ScopeOrg, NewScope: consts.ScopeEnv},
+	}, diffs)
+
+	diffs, err = DiffVariables(nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+}
@@ -0,0 +1,210 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/libs/db"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"cloudiac/utils"
+	"fmt"
+	"net/http"
+)
+
+func CreateVariableSet(tx *db.Session, set models.VariableSet) (*models.VariableSet, e.Error) {
+	if set.Id == "" {
+		set.Id = models.NewId("vs")
+	}
+	if err := models.Create(tx, &set); err != nil {
+		if e.IsDuplicate(err) {
+			return nil, e.New(e.VariableSetAlreadyExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &set, nil
+}
+
+func UpdateVariableSet(tx *db.Session, id models.Id, attrs models.Attrs) (set *models.VariableSet, er e.Error) {
+	set = &models.VariableSet{}
+	if _, err := models.UpdateAttr(tx.Where("id = ?", id), &models.VariableSet{}, attrs); err != nil {
+		if e.IsDuplicate(err) {
+			return nil, e.New(e.VariableSetAlreadyExists, err)
+		}
+		return nil, e.New(e.DBError, fmt.Errorf("update variable set error: %v", err))
+	}
+	if err := tx.Where("id = ?", id).First(set); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.VariableSetNotExists)
+		}
+		return nil, e.New(e.DBError, fmt.Errorf("query variable set error: %v", err))
+	}
+	return
+}
+
+// DeleteVariableSet 删除变量集及其关联关系
+func DeleteVariableSet(tx *db.Session, id models.Id) e.Error {
+	if _, err := tx.Where("variable_set_id = ?", id).Delete(&models.VariableSetAttachment{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete variable set attachment error: %v", err))
+	}
+	if _, err := tx.Where("id = ?", id).Delete(&models.VariableSet{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete variable set error: %v", err))
+	}
+	return nil
+}
+
+func QueryVariableSet(query *db.Session) *db.Session {
+	return query.Model(&models.VariableSet{})
+}
+
+func GetVariableSetById(query *db.Session, id models.Id) (*models.VariableSet, e.Error) {
+	set := models.VariableSet{}
+	if err := query.Model(models.VariableSet{}).Where("id = ?", id).First(&set); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.VariableSetNotExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &set, nil
+}
+
+// BuildVariableSetVariables 根据表单生成变量集的变量列表，敏感变量加密保存，
+// 敏感变量的值为空时保留 olds 中同名同类型变量的值
+func BuildVariableSetVariables(vars []forms.Variables, olds models.VariableSetVariables) (models.VariableSetVariables, e.Error) {
+	oldValues := make(map[string]string)
+	for _, v := range olds {
+		oldValues[v.Name+v.Type] = v.Value
+	}
+
+	result := make(models.VariableSetVariables, 0, len(vars))
+	exists := make(map[string]bool)
+	for _, v := range vars {
+		if v.Name == "" || !utils.StrInArray(v.Type,
+			consts.VarTypeEnv, consts.VarTypeTerraform, consts.VarTypeAnsible, consts.VarTypeFile) {
+			return nil, e.New(e.BadParam, fmt.Errorf("invalid variable '%s'", v.Name), http.StatusBadRequest)
+		}
+		if exists[v.Name+v.Type] {
+			return nil, e.New(e.BadParam, fmt.Errorf("duplicate variable '%s'", v.Name), http.StatusBadRequest)
+		}
+		exists[v.Name+v.Type] = true
+
+		if v.Type != consts.VarTypeFile {
+			v.Path = ""
+		} else if v.Path != "" && !utils.IsSafeRelPath(v.Path) {
+			return nil, e.New(e.BadParam, fmt.Errorf("invalid path '%s' of file variable '%s'", v.Path, v.Name), http.StatusBadRequest)
+		}
+
+		value := v.Value
+		if v.Sensitive {
+			if value == "" {
+				value = oldValues[v.Name+v.Type]
+			} else {
+				encrypted, err := utils.AesEncrypt(value)
+				if err != nil {
+					return nil, e.New(e.InternalError, err)
+				}
+				value = encrypted
+			}
+		}
+		result = append(result, models.VariableBody{
+			Scope:       consts.ScopeVarSet,
+			Type:        v.Type,
+			Name:        v.Name,
+			Value:       value,
+			Sensitive:   v.Sensitive,
+			Description: v.Description,
+			Path:        v.Path,
+		})
+	}
+	return result, nil
+}
+
+func CreateVariableSetAttachment(tx *db.Session, attachment models.VariableSetAttachment) (*models.VariableSetAttachment, e.Error) {
+	if attachment.Id == "" {
+		attachment.Id = models.NewId("vsa")
+	}
+	if err := models.Create(tx, &attachment); err != nil {
+		if e.IsDuplicate(err) {
+			return nil, e.New(e.VariableSetAttachmentExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &attachment, nil
+}
+
+func GetVariableSetAttachmentById(query *db.Session, id models.Id) (*models.VariableSetAttachment, e.Error) {
+	attachment := models.VariableSetAttachment{}
+	if err := query.Model(models.VariableSetAttachment{}).Where("id = ?", id).First(&attachment); err != nil {
+		if e.IsRecordNotFound(err) {
+			return nil, e.New(e.VariableSetAttachmentNotExists, err)
+		}
+		return nil, e.New(e.DBError, err)
+	}
+	return &attachment, nil
+}
+
+func DeleteVariableSetAttachment(tx *db.Session, id models.Id) e.Error {
+	if _, err := tx.Where("id = ?", id).Delete(&models.VariableSetAttachment{}); err != nil {
+		return e.New(e.DBError, fmt.Errorf("delete variable set attachment error: %v", err))
+	}
+	return nil
+}
+
+// QueryVariableSetAttachment 查询变量集关联关系，同时返回变量集及环境名称
+func QueryVariableSetAttachment(query *db.Session) *db.Session {
+	return query.Model(&models.VariableSetAttachment{}).
+		Joins("left join iac_variable_set as vs on vs.id = iac_variable_set_attachment.variable_set_id").
+		Joins("left join iac_env as env on env.id = iac_variable_set_attachment.env_id").
+		LazySelectAppend("iac_variable_set_attachment.*", "vs.name as variable_set_name", "env.name as env_name")
+}
+
+// GetEnvVariableSetVariables 获取环境关联的变量集中的变量，先返回项目关联的变量集，再返回环境关联的变量集，
+// 同一级别按关联时间排序，后面的变量覆盖前面的同名同类型变量
+func GetEnvVariableSetVariables(query *db.Session, projectId, envId models.Id) ([]models.VariableBody, e.Error) {
+	attachments := make([]*models.VariableSetAttachment, 0)
+	if err := query.Model(&models.VariableSetAttachment{}).
+		Where("project_id = ? AND (env_id = '' OR env_id = ?)", projectId, envId).
+		Order("env_id != '', created_at").Find(&attachments); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+
+	vars := make([]models.VariableBody, 0)
+	for _, a := range attachments {
+		set, err := GetVariableSetById(query, a.VariableSetId)
+		if err != nil {
+			if err.Code() == e.VariableSetNotExists {
+				continue
+			}
+			return nil, err
+		}
+		for _, v := range set.Variables {
+			v.Scope = consts.ScopeVarSet
+			v.VarSetId = set.Id
+			v.VarSetName = set.Name
+			vars = append(vars, v)
+		}
+	}
+	return vars, nil
+}
+
+// mergeVariableSetVariables 将变量集中的变量合并到已计算继承关系的变量中，
+// 变量集的优先级高于组织、模板及项目变量，低于环境变量
+func mergeVariableSetVariables(variableM map[string]models.Variable, setVars []models.VariableBody,
+	orgId, projectId, envId models.Id, keepSensitive bool) {
+	for _, v := range setVars {
+		key := fmt.Sprintf("%s%s", v.Name, v.Type)
+		if old, ok := variableM[key]; ok && old.Scope == consts.ScopeEnv {
+			continue
+		}
+		if v.Sensitive && !keepSensitive {
+			v.Value = ""
+		}
+		variableM[key] = models.Variable{
+			VariableBody: v,
+			OrgId:        orgId,
+			ProjectId:    projectId,
+			EnvId:        envId,
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package services
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/models"
+	"cloudiac/portal/models/forms"
+	"testing"
+)
+
+func TestMergeVariableSetVariables(t *testing.T) {
+	variableM := map[string]models.Variable{
+		"regionterraform": {VariableBody: models.VariableBody{
+			Scope: consts.ScopeProject, Type: consts.VarTypeTerraform, Name: "region", Value: "cn-beijing"}},
+		"DD_API_KEYenvironment": {VariableBody: models.VariableBody{
+			Scope: consts.ScopeEnv, Type: consts.VarTypeEnv, Name: "DD_API_KEY", Value: "env-key"}},
+	}
+	setVars := []models.VariableBody{
+		{Type: consts.VarTypeTerraform, Name: "region", Value: "cn-hangzhou", VarSetId: "vs-1"},
+		{Type: consts.VarTypeEnv, Name: "DD_API_KEY", Value: "set-key", Sensitive: true, VarSetId: "vs-2"},
+		{Type: consts.VarTypeEnv, Name: "DD_SITE", Value: "set-site", Sensitive: true, VarSetId: "vs-2"},
+		{Type: consts.VarTypeTerraform, Name: "region", Value: "cn-shanghai", VarSetId: "vs-3"},
+	}
+
+	mergeVariableSetVariables(variableM, setVars, "org", "p", "env", false)
+
+	// 变量集覆盖项目变量，后关联的变量集覆盖先关联的
+	if v := variableM["regionterraform"]; v.Value != "cn-shanghai" || v.VarSetId != "vs-3" {
+		t.Errorf("region = %s from %s, want cn-shanghai from vs-3", v.Value, v.VarSetId)
+	}
+	// 环境变量优先级高于变量集
+	if v := variableM["DD_API_KEYenvironment"]; v.Value != "env-key" {
+		t.Errorf("DD_API_KEY = %s, want env-key", v.Value)
+	}
+	if v := variableM["DD_SITEenvironment"]; v.Value != "" || v.EnvId != "env" {
+		t.Errorf("sensitive value should be hidden, got %+v", v)
+	}
+}
+
+func TestBuildVariableSetVariables(t *testing.T) {
+	olds := models.VariableSetVariables{
+		{Type: consts.VarTypeEnv, Name: "DD_API_KEY", Value: "encrypted", Sensitive: true},
+	}
+	vars, err := BuildVariableSetVariables([]forms.Variables{
+		{Type: consts.VarTypeEnv, Name: "DD_API_KEY", Sensitive: true},
+		{Type: consts.VarTypeTerraform, Name: "region", Value: "cn-shanghai", Path: "ignored"},
+	}, olds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vars) != 2 || vars[0].Value != "encrypted" || vars[0].Scope != consts.ScopeVarSet {
+		t.Errorf("unexpected variables: %+v", vars)
+	}
+	if vars[1].Path != "" {
+		t.Errorf("path of non-file variable should be cleared")
+	}
+
+	for _, invalid := range [][]forms.Variables{
+		{{Type: consts.VarTypeEnv}},
+		{{Type: "unknown", Name: "a"}},
+		{{Type: consts.VarTypeEnv, Name: "a"}, {Type: consts.VarTypeEnv, Name: "a"}},
+		{{Type: consts.VarTypeFile, Name: "KUBECONFIG", Path: "../kubeconfig"}},
+	} {
+		if _, err := BuildVariableSetVariables(invalid, nil); err == nil {
+			t.Errorf("expect error for %+v", invalid)
+		}
+	}
+}
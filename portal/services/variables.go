@@ -48,19 +48,37 @@ func SearchVariableByTemplateId(tx *db.Session, tplId models.Id) ([]models.Varia
 	return variables, nil
 }
 
-func OperationVariables(tx *db.Session, orgId, projectId, tplId, envId models.Id,
+// OperationVariables 批量新增、修改、删除变量，并记录变量的变更历史
+func OperationVariables(tx *db.Session, operatorId, orgId, projectId, tplId, envId models.Id,
 	variables []forms.Variables, deleteVariablesId []string) e.Error {
+	olds, err := getVariablesByIds(tx, variables, deleteVariablesId)
+	if err != nil {
+		return err
+	}
+
+	histories := make([]models.VariableHistory, 0)
+	for _, id := range deleteVariablesId {
+		if old, ok := olds[models.Id(id)]; ok {
+			histories = append(histories, newVariableHistory(models.VariableActionDelete, &old, nil))
+		}
+	}
 	if err := DeleteVariables(tx, deleteVariablesId); err != nil {
 		return err
 	}
 
 	bq := utils.NewBatchSQL(1024, "INSERT INTO", models.Variable{}.TableName(),
-		"id", "scope", "type", "name", "value", "sensitive", "description", "org_id", "project_id", "tpl_id", "env_id")
+		"id", "scope", "type", "name", "value", "sensitive", "description", "path", "org_id", "project_id", "tpl_id", "env_id")
 	for _, v := range variables {
+		if v.Type != consts.VarTypeFile {
+			v.Path = ""
+		} else if v.Path != "" && !utils.IsSafeRelPath(v.Path) {
+			return e.New(e.BadParam, fmt.Errorf("invalid path '%s' of file variable '%s'", v.Path, v.Name), http.StatusBadRequest)
+		}
 		attrs := map[string]interface{}{
 			"name":        v.Name,
 			"sensitive":   v.Sensitive,
 			"description": v.Description,
+			"path":        v.Path,
 		}
 		var value string = v.Value
 		// 需要加密，数据不为空
@@ -84,19 +102,67 @@ func OperationVariables(tx *db.Session, orgId, projectId, tplId, envId models.Id
 			} else if err != nil {
 				return err
 			}
+			if old, ok := olds[v.Id]; ok {
+				updated := old
+				updated.Name, updated.Sensitive, updated.Description, updated.Path = v.Name, v.Sensitive, v.Description, v.Path
+				if _, ok := attrs["value"]; ok {
+					updated.Value = value
+				}
+				if isVariableChanged(old.VariableBody, updated.VariableBody) {
+					histories = append(histories, newVariableHistory(models.VariableActionUpdate, &old, &updated))
+				}
+			}
 			continue
 		} else {
 			vId := models.NewId("v")
-			if err := bq.AddRow(vId, v.Scope, v.Type, v.Name, value, v.Sensitive, v.Description,
+			if err := bq.AddRow(vId, v.Scope, v.Type, v.Name, value, v.Sensitive, v.Description, v.Path,
 				orgId, projectId, tplId, envId); err != nil {
 				return e.New(e.DBError, err)
 			}
+			histories = append(histories, newVariableHistory(models.VariableActionCreate, nil, &models.Variable{
+				BaseModel: models.BaseModel{Id: vId},
+				VariableBody: models.VariableBody{
+					Scope:     v.Scope,
+					Type:      v.Type,
+					Name:      v.Name,
+					Value:     value,
+					Sensitive: v.Sensitive,
+				},
+				OrgId:     orgId,
+				ProjectId: projectId,
+				TplId:     tplId,
+				EnvId:     envId,
+			}))
 		}
 	}
 	if err := CreateVariables(tx, bq); err != nil {
 		return err
 	}
-	return nil
+	return createVariableHistories(tx, operatorId, histories)
+}
+
+// getVariablesByIds 查询将被修改或删除的变量的当前值
+func getVariablesByIds(tx *db.Session, variables []forms.Variables, deleteIds []string) (map[models.Id]models.Variable, e.Error) {
+	ids := make([]string, 0, len(deleteIds))
+	ids = append(ids, deleteIds...)
+	for _, v := range variables {
+		if v.Id != "" {
+			ids = append(ids, string(v.Id))
+		}
+	}
+
+	olds := make(map[models.Id]models.Variable)
+	if len(ids) == 0 {
+		return olds, nil
+	}
+	vars := make([]models.Variable, 0)
+	if err := tx.Model(models.Variable{}).Where("id IN (?)", ids).Find(&vars); err != nil {
+		return nil, e.New(e.DBError, err)
+	}
+	for _, v := range vars {
+		olds[v.Id] = v
+	}
+	return olds, nil
 }
 
 func CreateVariables(tx *db.Session, bq *utils.BatchSQL) e.Error {
@@ -188,6 +254,14 @@ func GetValidVariables(dbSess *db.Session, scope string, orgId, projectId, tplId
 		}
 	}
 
+	if scope == consts.ScopeEnv {
+		setVars, err := GetEnvVariableSetVariables(dbSess, projectId, envId)
+		if err != nil {
+			return nil, err, scopes
+		}
+		mergeVariableSetVariables(variableM, setVars, orgId, projectId, envId, keepSensitive)
+	}
+
 	return variableM, nil, scopes
 }
 
@@ -237,3 +311,25 @@ func GetVariableParent(dbSess *db.Session, name, scope, variableType string, sco
 
 	return true, variable
 }
+
+// CopyEnvVariables 复制环境级变量到新环境，敏感变量直接复制密文。skip 返回 true 的变量不复制
+func CopyEnvVariables(tx *db.Session, srcEnvId models.Id, dst *models.Env, skip func(v models.Variable) bool) e.Error {
+	variables := make([]models.Variable, 0)
+	if err := tx.Model(models.Variable{}).Where("env_id = ? AND scope = ?", srcEnvId, consts.ScopeEnv).
+		Find(&variables); err != nil {
+		return e.New(e.DBError, err)
+	}
+
+	bq := utils.NewBatchSQL(1024, "INSERT INTO", models.Variable{}.TableName(),
+		"id", "scope", "type", "name", "value", "sensitive", "description", "path", "org_id", "project_id", "tpl_id", "env_id")
+	for _, v := range variables {
+		if skip != nil && skip(v) {
+			continue
+		}
+		if err := bq.AddRow(models.NewId("v"), v.Scope, v.Type, v.Name, v.Value, v.Sensitive, v.Description, v.Path,
+			dst.OrgId, dst.ProjectId, "", dst.Id); err != nil {
+			return e.New(e.DBError, err)
+		}
+	}
+	return CreateVariables(tx, bq)
+}
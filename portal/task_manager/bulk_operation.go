@@ -0,0 +1,188 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package task_manager
+
+import (
+	"cloudiac/portal/consts"
+	"cloudiac/portal/consts/e"
+	"cloudiac/portal/models"
+	"cloudiac/portal/services"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// processBulkOperations 推进执行中的批量操作：同步已创建任务的状态，并在并发数限制内为等待中的环境创建任务
+func (m *TaskManager) processBulkOperations() error {
+	logger := m.logger.WithField("func", "processBulkOperations")
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("panic: %v", r)
+			logger.Debugf("%s", debug.Stack())
+		}
+	}()
+
+	ops, err := services.GetRunningBulkOperations(m.db)
+	if err != nil {
+		return errors.Wrapf(err, "query bulk operations: %v", err)
+	}
+	for _, op := range ops {
+		if err := m.processBulkOperation(op); err != nil {
+			logger.WithField("bulkId", op.Id).Errorf("process bulk operation error: %v", err)
+		}
+	}
+	return nil
+}
+
+func (m *TaskManager) processBulkOperation(op *models.BulkOperation) error {
+	logger := m.logger.WithField("bulkId", op.Id)
+
+	items, err := services.GetBulkOperationItems(m.db, op.Id)
+	if err != nil {
+		return err
+	}
+
+	setItemStatus := func(item *models.BulkOperationItem, status, message string) error {
+		attrs := models.Attrs{"status": status, "message": message}
+		if item.TaskId != "" {
+			attrs["task_id"] = item.TaskId
+		}
+		if err := services.UpdateBulkOperationItem(m.db, item, attrs); err != nil {
+			return err
+		}
+		item.Status, item.Message = status, message
+		return nil
+	}
+
+	count := func(status string) int {
+		n := 0
+		for _, item := range items {
+			if item.Status == status {
+				n += 1
+			}
+		}
+		return n
+	}
+
+	// 同步已创建任务的执行结果
+	for _, item := range items {
+		if item.Status != models.BulkItemRunning {
+			continue
+		}
+		task, err := services.GetTaskById(m.db, item.TaskId)
+		if err != nil && err.Code() == e.TaskNotExists {
+			if err := setItemStatus(item, models.BulkItemFailed, "task not exists"); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		if !task.Exited() {
+			continue
+		}
+		status := models.BulkItemComplete
+		if task.Status == models.TaskFailed {
+			status = models.BulkItemFailed
+		}
+		if err := setItemStatus(item, status, task.Message); err != nil {
+			return err
+		}
+	}
+
+	aborted := op.MaxFailures > 0 && count(models.BulkItemFailed) >= op.MaxFailures
+	running := count(models.BulkItemRunning)
+	for _, item := range items {
+		if item.Status != models.BulkItemPending {
+			continue
+		}
+		if aborted {
+			// 失败数量达到上限，不再执行剩余的环境
+			msg := fmt.Sprintf("stopped after %d failures", count(models.BulkItemFailed))
+			if err := setItemStatus(item, models.BulkItemSkipped, msg); err != nil {
+				return err
+			}
+			continue
+		}
+		if running >= op.Parallelism {
+			break
+		}
+
+		status, message, err := m.startBulkOperationItem(op, item)
+		if err != nil {
+			return err
+		}
+		if status == models.BulkItemPending {
+			continue
+		}
+		if err := setItemStatus(item, status, message); err != nil {
+			return err
+		}
+		if status == models.BulkItemRunning {
+			logger.Infof("created %s task %s for env %s", op.Action, item.TaskId, item.EnvId)
+			running += 1
+		} else if status == models.BulkItemFailed {
+			aborted = op.MaxFailures > 0 && count(models.BulkItemFailed) >= op.MaxFailures
+		}
+	}
+
+	attrs := models.Attrs{
+		"succeeded": count(models.BulkItemComplete),
+		"failed":    count(models.BulkItemFailed),
+		"skipped":   count(models.BulkItemSkipped),
+	}
+	if count(models.BulkItemRunning) == 0 && count(models.BulkItemPending) == 0 {
+		switch {
+		case aborted:
+			attrs["status"] = models.BulkOperationAborted
+		case count(models.BulkItemFailed) > 0:
+			attrs["status"] = models.BulkOperationFailed
+		default:
+			attrs["status"] = models.BulkOperationComplete
+		}
+		attrs["end_at"] = models.Time(time.Now())
+		logger.Infof("bulk operation done, status: %s", attrs["status"])
+	}
+	return services.UpdateBulkOperation(m.db, op.Id, attrs)
+}
+
+// startBulkOperationItem 为批量操作中的环境创建任务，返回执行记录的新状态。
+// 环境有未结束的任务时保持 pending 状态，等待任务结束后再执行，保证同一环境同时只有一个任务
+func (m *TaskManager) startBulkOperationItem(op *models.BulkOperation, item *models.BulkOperationItem) (
+	status string, message string, err error) {
+	if _, ok := m.envRunningTask.Load(item.EnvId); ok {
+		return models.BulkItemPending, "", nil
+	}
+	if active, err := services.EnvHasActiveTask(m.db, item.EnvId); err != nil {
+		return "", "", err
+	} else if active {
+		return models.BulkItemPending, "", nil
+	}
+
+	env, er := services.GetEnvById(m.db, item.EnvId)
+	if er != nil {
+		return models.BulkItemFailed, er.Error(), nil
+	}
+	switch {
+	case env.Archived:
+		return models.BulkItemSkipped, "env is archived", nil
+	case op.Action == models.BulkActionDestroy && env.Status == models.EnvStatusInactive:
+		return models.BulkItemSkipped, "env is inactive", nil
+	}
+
+	task, err := m.createEnvTask(env, models.Task{
+		Name:        models.Task{}.GetTaskNameByType(op.Action),
+		Type:        op.Action,
+		CreatorId:   op.CreatorId,
+		AutoApprove: env.AutoApproval,
+		Extra:       models.TaskExtra{Source: consts.BulkOperation},
+	})
+	if err != nil {
+		return models.BulkItemFailed, err.Error(), nil
+	}
+	item.TaskId = task.Id
+	return models.BulkItemRunning, "", nil
+}
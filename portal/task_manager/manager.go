@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"os"
+	"path"
 	"runtime/debug"
 	"sync"
 	"time"
@@ -43,6 +44,8 @@ type TaskManager struct {
 	wg sync.WaitGroup // 等待执行任务协程退出的 wait group
 
 	maxTasksPerRunner int // 每个 runner 并发任务数量限制
+
+	auditLogCleanAt time.Time // 最近一次清理过期审计日志的时间
 }
 
 func Start(serviceId string) {
@@ -141,9 +144,17 @@ func (m *TaskManager) start() {
 		if err := m.processAutoDestroy(); err != nil {
 			m.logger.Errorf("process auto destroy error: %v", err)
 		}
+		if err := m.processEnvSchedules(); err != nil {
+			m.logger.Errorf("process env schedules error: %v", err)
+		}
+		if err := m.processBulkOperations(); err != nil {
+			m.logger.Errorf("process bulk operations error: %v", err)
+		}
 
 		m.processPendingTask(ctx)
 
+		m.processAuditLogRetention()
+
 		select {
 		case <-ticker.C:
 			continue
@@ -412,7 +423,11 @@ func (m *TaskManager) processTaskDone(task *models.Task) {
 			if err != nil {
 				return fmt.Errorf("unmarshal plan json: %v", err)
 			}
-			if err = services.SaveTaskChanges(dbSess, task, tfPlan.ResourceChanges); err != nil {
+			if task.Type == models.TaskTypeRefresh {
+				if err = services.SaveTaskDrift(dbSess, task, tfPlan.ResourceDrift); err != nil {
+					return fmt.Errorf("save task drift: %v", err)
+				}
+			} else if err = services.SaveTaskChanges(dbSess, task, tfPlan.ResourceChanges); err != nil {
 				return fmt.Errorf("save task changes: %v", err)
 			}
 		}
@@ -494,9 +509,68 @@ func (m *TaskManager) processTaskDone(task *models.Task) {
 				logger.Errorf("process auto destroy: %v", err)
 			}
 		}
+
+		if task.Type == models.TaskTypeApply && lastStep.Status == models.TaskStepComplete {
+			m.processDependentEnvs(task)
+		}
+
+		// 只通知部署相关的任务，output、refresh 等只读的任务不发送通知
+		if utils.StrInArray(task.Type, models.TaskTypePlan, models.TaskTypeApply, models.TaskTypeDestroy) {
+			m.processTaskNotify(task)
+		}
 	}
 }
 
+// processTaskNotify 任务结束后给标签选择器匹配环境的邮件通知配置发送任务结果
+func (m *TaskManager) processTaskNotify(task *models.Task) {
+	logger := m.logger.WithField("func", "processTaskNotify").WithField("taskId", task.Id)
+
+	env, err := services.GetEnvById(m.db, task.EnvId)
+	if err != nil {
+		logger.Errorf("get env error: %v", err)
+		return
+	}
+	cfgs, err := services.GetEnvNotificationCfgs(m.db, env, task.Status == models.TaskFailed)
+	if err != nil {
+		logger.Errorf("get env notification cfgs error: %v", err)
+		return
+	}
+
+	tos := make([]string, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		// webhook 类型的通知暂未实现
+		if cfg.NotificationType != "email" || cfg.UserId == "" {
+			continue
+		}
+		user, err := services.GetUserById(m.db, cfg.UserId)
+		if err != nil {
+			logger.Warnf("get user %s error: %v", cfg.UserId, err)
+			continue
+		}
+		if !utils.StrInArray(user.Email, tos...) {
+			tos = append(tos, user.Email)
+		}
+	}
+	if len(tos) == 0 {
+		return
+	}
+
+	tpl, err := services.GetTemplateById(m.db, task.TplId)
+	if err != nil {
+		logger.Errorf("get template error: %v", err)
+		return
+	}
+	sm := services.GetMail(tos, *task, *tpl)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorf("send task mail panic: %v", r)
+			}
+		}()
+		sm.SendMail()
+	}()
+}
+
 func (m *TaskManager) runTaskStep(ctx context.Context, taskReq runner.RunTaskReq,
 	task *models.Task, step *models.TaskStep) (err error) {
 	logger := m.logger.WithField("taskId", taskReq.TaskId)
@@ -524,6 +598,17 @@ func (m *TaskManager) runTaskStep(ctx context.Context, taskReq runner.RunTaskReq
 		logger.Infof("waitting task step approve")
 		changeStepStatus(models.TaskStepApproving, "")
 
+		// 按审批策略设置审批超时时间，已设置过的步骤(如服务重启后恢复执行)不重复设置
+		if step.ApprovalExpireAt == nil {
+			if policy, er := services.GetEnvApprovalPolicy(m.db, task.ProjectId, task.EnvId); er != nil {
+				logger.Errorf("get approval policy error: %v", er)
+			} else if expireAt := policy.ExpireAt(time.Now()); expireAt != nil {
+				if er := services.UpdateTaskStepApprovalExpireAt(m.db, step, expireAt); er != nil {
+					logger.Errorf("update step approval expire time error: %v", er)
+				}
+			}
+		}
+
 		var newStep *models.TaskStep
 		if newStep, err = WaitTaskStepApprove(ctx, m.db, step.TaskId, step.Index); err != nil {
 			if err == context.Canceled {
@@ -532,7 +617,7 @@ func (m *TaskManager) runTaskStep(ctx context.Context, taskReq runner.RunTaskReq
 
 			logger.Errorf("wait task step approve error: %v", err)
 			status := models.TaskStepFailed
-			if err == ErrTaskStepRejected {
+			if err == ErrTaskStepRejected || err == ErrTaskStepApprovalExpired {
 				status = models.TaskStepRejected
 			}
 			changeStepStatus(status, err.Error())
@@ -541,6 +626,8 @@ func (m *TaskManager) runTaskStep(ctx context.Context, taskReq runner.RunTaskReq
 		step = newStep
 	}
 
+	// 上次自动重试时的日志长度，只检查之后新增的日志
+	retryLogOffset := 0
 loop:
 	for {
 		select {
@@ -555,6 +642,12 @@ loop:
 			// 先将步骤置为 running 状态，然后再发起调用，保证步骤不会重复执行
 			changeStepStatus(models.TaskStepRunning, "")
 			logger.Infof("start task step %d(%s)", step.Index, step.Type)
+			taskReq.Retry = step.RetryCount
+			if taskReq, err = withResourceAccountVars(ctx, m.db, taskReq, task); err != nil {
+				logger.Errorf("get resource account vars error: %v", err)
+				changeStepStatus(models.TaskStepFailed, err.Error())
+				return err
+			}
 			if err = StartTaskStep(taskReq, *step); err != nil {
 				logger.Errorf("start task step error: %s", err.Error())
 				changeStepStatus(models.TaskStepFailed, err.Error())
@@ -566,6 +659,22 @@ loop:
 				changeStepStatus(models.TaskStepFailed, err.Error())
 				return err
 			}
+		case models.TaskStepFailed:
+			delay, ok := stepRetryDelay(step, &retryLogOffset)
+			if !ok {
+				break loop
+			}
+			// 临时性错误导致的失败，等待后自动重试
+			logger.Infof("step failed with transient error, retry(%d) after %s", step.RetryCount+1, delay)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			if er := services.ResetTaskStepForRetry(m.db, step); er != nil {
+				logger.Errorf("reset step for retry error: %v", er)
+				break loop
+			}
 		default:
 			break loop
 		}
@@ -618,11 +727,26 @@ func buildRunTaskReq(dbSess *db.Session, task models.Task) (taskReq *runner.RunT
 			runnerEnv.TerraformVars[v.Name] = value
 		case consts.VarTypeAnsible:
 			runnerEnv.AnsibleVars[v.Name] = value
+		case consts.VarTypeFile:
+			runnerEnv.Files = append(runnerEnv.Files, runner.TaskFile{
+				Name:    v.Name,
+				Path:    utils.FirstValueStr(v.Path, path.Join("files", v.Name)),
+				Content: value,
+			})
 		default:
 			return nil, fmt.Errorf("unknown variable type: %s", v.Type)
 		}
 	}
 
+	if len(runnerEnv.TerraformVars) > 0 {
+		// 获取失败时所有变量按字符串传入，与未声明类型时的行为一致
+		names, er := services.GetTaskHclTfVariables(dbSess, &task)
+		if er != nil {
+			logs.Get().WithField("taskId", task.Id).Warnf("get hcl terraform variables error: %v", er)
+		}
+		runnerEnv.HclTerraformVars = names
+	}
+
 	stateStore := runner.StateStore{
 		Backend: "consul",
 		Scheme:  "http",
@@ -652,7 +776,41 @@ func buildRunTaskReq(dbSess *db.Session, task models.Task) (taskReq *runner.RunT
 	if pk != "" {
 		taskReq.PrivateKey = utils.EncodeSecretVar(pk, true)
 	}
+	if task.RetryOf != "" && task.Extra.ReuseWorkspace {
+		taskReq.SourceTaskId = string(task.RetryOf)
+	}
+
+	return taskReq, nil
+}
+
+// 临时凭证的有效期在步骤超时时间的基础上增加的余量
+const stsDurationMargin = 5 * time.Minute
 
+// withResourceAccountVars 注入项目及环境绑定的资源账号变量，同名变量以任务的环境变量为准。
+// 每个步骤启动前重新获取，sts 类型账号的临时凭证有效期覆盖步骤的超时时间
+func withResourceAccountVars(ctx context.Context, dbSess *db.Session, taskReq runner.RunTaskReq,
+	task *models.Task) (runner.RunTaskReq, error) {
+	accounts, err := services.GetEnvResourceAccounts(dbSess, task.ProjectId, task.EnvId, task.RunnerId)
+	if err != nil {
+		return taskReq, err
+	}
+	if len(accounts) == 0 {
+		return taskReq, nil
+	}
+
+	duration := time.Duration(task.StepTimeout)*time.Second + stsDurationMargin
+	accountVars, err := services.GetResourceAccountEnvVars(ctx, accounts, fmt.Sprintf("iac-%s", task.Id), duration)
+	if err != nil {
+		return taskReq, err
+	}
+	vars := make(map[string]string, len(accountVars)+len(taskReq.Env.EnvironmentVars))
+	for k, v := range accountVars {
+		vars[k] = v
+	}
+	for k, v := range taskReq.Env.EnvironmentVars {
+		vars[k] = v
+	}
+	taskReq.Env.EnvironmentVars = vars
 	return taskReq, nil
 }
 
@@ -683,6 +841,20 @@ func (m *TaskManager) processAutoDestroy() error {
 		err = func() error {
 			logger := logger.WithField("envId", env.Id)
 
+			// 冻结期内不执行自动销毁，冻结结束后再处理
+			if window, err := services.GetActiveFreezeWindow(dbSess, env, time.Now()); err != nil {
+				logger.Errorf("get freeze window error: %v", err)
+				return nil
+			} else if window != nil {
+				logger.Debugf("env is frozen by %s, skip auto destroy", window.Id)
+				return nil
+			}
+			// 有活跃的下游环境依赖时不能销毁
+			if err := services.CheckEnvDependents(dbSess, env); err != nil {
+				logger.Debugf("skip auto destroy: %v", err)
+				return nil
+			}
+
 			tx := dbSess.Begin()
 			defer func() {
 				if r := recover(); r != nil {
@@ -755,3 +927,236 @@ func (m *TaskManager) processAutoDestroy() error {
 
 	return nil
 }
+
+// processEnvSchedules 处理到期的环境定时任务，为环境创建部署或销毁任务
+func (m *TaskManager) processEnvSchedules() error {
+	logger := m.logger.WithField("func", "processEnvSchedules")
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("panic: %v", r)
+			logger.Debugf("%s", debug.Stack())
+		}
+	}()
+
+	now := time.Now()
+	schedules, err := services.GetDueEnvSchedules(m.db, now, 64)
+	if err != nil {
+		return errors.Wrapf(err, "query env schedules: %v", err)
+	}
+
+	for _, schedule := range schedules {
+		logger := logger.WithField("scheduleId", schedule.Id).WithField("envId", schedule.EnvId)
+
+		// 错过的执行时间不补偿，直接计算当前时间之后的下次执行时间
+		next, nextErr := schedule.NextRunTime(now)
+		if nextErr != nil {
+			logger.Errorf("compute next run time error: %v", nextErr)
+		}
+		if ok, err := services.MoveEnvScheduleNextRun(m.db, schedule, schedule.NextRunAt, next); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if nextErr != nil {
+			// 无法计算下次执行时间(如 cron 或时区无效)时暂停定时任务，本次不再执行
+			if _, err := services.UpdateEnvSchedule(m.db, schedule.Id, models.Attrs{"paused": true}); err != nil {
+				logger.Errorf("pause env schedule error: %v", err)
+			}
+		}
+
+		run := envScheduleRun(schedule, nextErr, func() (*models.Task, string, error) {
+			return m.runEnvSchedule(schedule)
+		})
+		switch run.Status {
+		case models.EnvScheduleRunFailed:
+			logger.Errorf("run env schedule error: %s", run.Message)
+		case models.EnvScheduleRunSkipped:
+			logger.Infof("skip env schedule: %s", run.Message)
+		default:
+			logger.Infof("created scheduled %s task: %s", schedule.Action, run.TaskId)
+		}
+		if _, err := services.CreateEnvScheduleRun(m.db, run); err != nil {
+			logger.Errorf("create env schedule run error: %v", err)
+		}
+	}
+	return nil
+}
+
+// envScheduleRun 生成定时任务本次执行的记录，每次执行只生成一条记录。
+// 无法计算下次执行时间(nextErr 不为空)时定时任务会被暂停，不再执行 runSchedule
+func envScheduleRun(schedule *models.EnvSchedule, nextErr error,
+	runSchedule func() (*models.Task, string, error)) models.EnvScheduleRun {
+	run := models.EnvScheduleRun{
+		ScheduleId:  schedule.Id,
+		EnvId:       schedule.EnvId,
+		Action:      schedule.Action,
+		ScheduledAt: *schedule.NextRunAt,
+	}
+	if nextErr != nil {
+		run.Status = models.EnvScheduleRunFailed
+		run.Message = fmt.Sprintf("schedule paused, compute next run time error: %v", nextErr)
+		return run
+	}
+
+	task, skip, err := runSchedule()
+	switch {
+	case err != nil:
+		run.Status, run.Message = models.EnvScheduleRunFailed, err.Error()
+	case skip != "":
+		run.Status, run.Message = models.EnvScheduleRunSkipped, skip
+	default:
+		run.Status, run.TaskId = models.EnvScheduleRunCreated, task.Id
+	}
+	return run
+}
+
+// runEnvSchedule 执行环境定时任务，环境当前状态不需要或不能执行任务时返回跳过原因
+func (m *TaskManager) runEnvSchedule(schedule *models.EnvSchedule) (task *models.Task, skip string, err error) {
+	env, er := services.GetEnvById(m.db, schedule.EnvId)
+	if er != nil {
+		return nil, "", er
+	}
+
+	switch {
+	case env.Archived:
+		return nil, "env is archived", nil
+	case env.Deploying:
+		return nil, "env is deploying", nil
+	case schedule.Action == models.TaskTypeDestroy && env.Status == models.EnvStatusInactive:
+		return nil, "env is inactive", nil
+	}
+	if window, er := services.GetActiveFreezeWindow(m.db, env, time.Now()); er != nil {
+		return nil, "", er
+	} else if window != nil {
+		return nil, fmt.Sprintf("env is frozen by %s", window.Name), nil
+	}
+	if schedule.Action == models.TaskTypeDestroy {
+		if er := services.CheckEnvDependents(m.db, env); er != nil {
+			return nil, er.Error(), nil
+		}
+	}
+
+	// 与自动销毁一致，定时销毁默认无需审批，但项目或环境配置了审批策略时按策略审批
+	autoApprove := env.AutoApproval
+	if schedule.Action == models.TaskTypeDestroy && !autoApprove {
+		policy, er := services.GetEnvApprovalPolicy(m.db, env.ProjectId, env.Id)
+		if er != nil {
+			return nil, "", er
+		}
+		autoApprove = policy.Id == ""
+	}
+
+	task, err = m.createEnvTask(env, models.Task{
+		Name:        models.Task{}.GetTaskNameByType(schedule.Action),
+		Type:        schedule.Action,
+		CreatorId:   schedule.CreatorId,
+		AutoApprove: autoApprove,
+		Extra:       models.TaskExtra{Source: consts.EnvSchedule},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return task, "", nil
+}
+
+// createEnvTask 使用环境当前的模板和变量为环境创建任务
+func (m *TaskManager) createEnvTask(env *models.Env, pt models.Task) (*models.Task, error) {
+	tx := m.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	tpl, er := services.GetTemplateById(tx, env.TplId)
+	if er != nil {
+		_ = tx.Rollback()
+		return nil, er
+	}
+	vars, er, _ := services.GetValidVariables(tx, consts.ScopeEnv, env.OrgId, env.ProjectId, env.TplId, env.Id, true)
+	if er != nil {
+		_ = tx.Rollback()
+		return nil, er
+	}
+	pt.Variables = make([]models.VariableBody, 0, len(vars))
+	for _, v := range vars {
+		pt.Variables = append(pt.Variables, v.VariableBody)
+	}
+	pt.StepTimeout = env.Timeout
+
+	task, er := services.CreateTask(tx, tpl, env, pt)
+	if er != nil {
+		_ = tx.Rollback()
+		return nil, er
+	}
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return task, nil
+}
+
+// isDependencyTriggered 下游环境是否需要在上游环境部署成功后自动创建任务，
+// 未部署或部署失败的环境不自动触发，避免上游部署时意外创建下游资源
+func isDependencyTriggered(env *models.Env) bool {
+	return env.DependencyTrigger != "" && env.Status == models.EnvStatusActive
+}
+
+// processDependentEnvs 上游环境部署成功后，为设置了依赖触发的下游环境创建任务
+func (m *TaskManager) processDependentEnvs(task *models.Task) {
+	logger := m.logger.WithField("func", "processDependentEnvs").WithField("taskId", task.Id)
+
+	envs, err := services.GetDependentEnvs(m.db, task.EnvId)
+	if err != nil {
+		logger.Errorf("get dependent envs error: %v", err)
+		return
+	}
+
+	for _, env := range envs {
+		if !isDependencyTriggered(env) {
+			continue
+		}
+
+		logger := logger.WithField("envId", env.Id)
+		newTask, err := m.createEnvTask(env, models.Task{
+			Name:        models.Task{}.GetTaskNameByType(env.DependencyTrigger),
+			Type:        env.DependencyTrigger,
+			CreatorId:   consts.SysUserId,
+			AutoApprove: env.AutoApproval,
+			Extra:       models.TaskExtra{Source: consts.DependencyTrigger},
+		})
+		if err != nil {
+			logger.Errorf("create dependency triggered task error: %v", err)
+			continue
+		}
+		logger.Infof("created %s task %s triggered by env %s", newTask.Type, newTask.Id, task.EnvId)
+	}
+}
+
+// processAuditLogRetention 按系统配置的保存天数清理过期审计日志，每小时执行一次
+func (m *TaskManager) processAuditLogRetention() {
+	if time.Since(m.auditLogCleanAt) < time.Hour {
+		return
+	}
+	m.auditLogCleanAt = time.Now()
+	logger := m.logger.WithField("func", "processAuditLogRetention")
+
+	days, err := services.GetAuditLogRetention(m.db)
+	if err != nil {
+		logger.Errorf("get audit log retention error: %v", err)
+		return
+	} else if days == 0 {
+		return
+	}
+
+	n, err := services.DeleteOperationLogsBefore(m.db, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		logger.Errorf("delete expired audit logs error: %v", err)
+		return
+	}
+	if n > 0 {
+		logger.Infof("deleted %d expired audit logs", n)
+	}
+}
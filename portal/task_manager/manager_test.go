@@ -0,0 +1,79 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package task_manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"cloudiac/portal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvScheduleRun(t *testing.T) {
+	now := time.Now()
+	scheduledAt := models.Time(now)
+
+	// cron 或时区无效时只记录一条失败的执行记录，不执行定时任务
+	for _, schedule := range []*models.EnvSchedule{
+		{Action: "apply", Cron: "61 * * * *", Timezone: "Local", NextRunAt: &scheduledAt},
+		{Action: "destroy", Cron: "0 8 * * 1-5", Timezone: "Invalid/Zone", NextRunAt: &scheduledAt},
+	} {
+		_, nextErr := schedule.NextRunTime(now)
+		if !assert.Error(t, nextErr, schedule.Cron, schedule.Timezone) {
+			continue
+		}
+		run := envScheduleRun(schedule, nextErr, func() (*models.Task, string, error) {
+			t.Errorf("schedule %s %s should not run", schedule.Cron, schedule.Timezone)
+			return nil, "", nil
+		})
+		assert.Equal(t, models.EnvScheduleRunFailed, run.Status)
+		assert.Contains(t, run.Message, "schedule paused")
+		assert.Equal(t, scheduledAt, run.ScheduledAt)
+		assert.Empty(t, run.TaskId)
+	}
+
+	schedule := &models.EnvSchedule{Action: "apply", Cron: "0 8 * * 1-5", Timezone: "Local", NextRunAt: &scheduledAt}
+	_, nextErr := schedule.NextRunTime(now)
+	assert.NoError(t, nextErr)
+
+	task := &models.Task{}
+	task.Id = "run-1"
+	run := envScheduleRun(schedule, nextErr, func() (*models.Task, string, error) {
+		return task, "", nil
+	})
+	assert.Equal(t, models.EnvScheduleRunCreated, run.Status)
+	assert.Equal(t, models.Id("run-1"), run.TaskId)
+
+	run = envScheduleRun(schedule, nextErr, func() (*models.Task, string, error) {
+		return nil, "env is not active", nil
+	})
+	assert.Equal(t, models.EnvScheduleRunSkipped, run.Status)
+	assert.Equal(t, "env is not active", run.Message)
+
+	run = envScheduleRun(schedule, nextErr, func() (*models.Task, string, error) {
+		return nil, "", fmt.Errorf("create task error")
+	})
+	assert.Equal(t, models.EnvScheduleRunFailed, run.Status)
+	assert.Equal(t, "create task error", run.Message)
+}
+
+func TestIsDependencyTriggered(t *testing.T) {
+	cases := []struct {
+		trigger string
+		status  string
+		expect  bool
+	}{
+		{models.TaskTypeApply, models.EnvStatusActive, true},
+		{models.TaskTypePlan, models.EnvStatusActive, true},
+		{"", models.EnvStatusActive, false},
+		{models.TaskTypeApply, models.EnvStatusInactive, false},
+		{models.TaskTypeApply, models.EnvStatusFailed, false},
+	}
+	for _, c := range cases {
+		env := &models.Env{DependencyTrigger: c.trigger, Status: c.status}
+		assert.Equal(t, c.expect, isDependencyTriggered(env), "%s %s", c.trigger, c.status)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package task_manager
+
+import (
+	"cloudiac/configs"
+	"cloudiac/portal/models"
+	"cloudiac/portal/services/logstorage"
+	"cloudiac/utils/logs"
+	"regexp"
+	"time"
+)
+
+// stepRetryPolicy 步骤失败后的自动重试策略，只有日志匹配到临时性错误时才会重试
+type stepRetryPolicy struct {
+	MaxRetries int
+	Backoff    time.Duration // 首次重试的等待时间，之后每次翻倍
+	Patterns   []*regexp.Regexp
+}
+
+const maxStepRetryBackoff = 5 * time.Minute
+
+// 网络相关的临时性错误
+var networkErrorPatterns = []string{
+	`(?i)connection reset by peer`,
+	`(?i)connection refused`,
+	`(?i)i/o timeout`,
+	`(?i)tls handshake timeout`,
+	`(?i)temporary failure in name resolution`,
+	`(?i)unexpected eof`,
+	`(?i)could not resolve host`,
+}
+
+// 默认只对不会修改资源的步骤进行自动重试
+var defaultStepRetryPolicies = map[string]stepRetryPolicy{
+	models.TaskStepInit: {
+		MaxRetries: 3,
+		Backoff:    15 * time.Second,
+		Patterns: compileRetryPatterns(append([]string{
+			`(?i)failed to (install|query available) provider`,
+			`(?i)error while installing`,
+			`(?i)failed to download module`,
+			`(?i)error downloading`,
+		}, networkErrorPatterns...)),
+	},
+	models.TaskStepPlan: {
+		MaxRetries: 2,
+		Backoff:    15 * time.Second,
+		Patterns:   compileRetryPatterns(networkErrorPatterns),
+	},
+}
+
+func compileRetryPatterns(patterns []string) []*regexp.Regexp {
+	rs := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		rs = append(rs, regexp.MustCompile(p))
+	}
+	return rs
+}
+
+// getStepRetryPolicy 获取步骤类型的自动重试策略，配置文件中的策略会覆盖默认策略
+func getStepRetryPolicy(stepType string) *stepRetryPolicy {
+	policy, ok := defaultStepRetryPolicies[stepType]
+	if conf := configs.Get(); conf != nil {
+		if c, exists := conf.Portal.StepRetry[stepType]; exists {
+			policy = stepRetryPolicy{
+				MaxRetries: c.MaxRetries,
+				Backoff:    time.Duration(c.Backoff) * time.Second,
+				Patterns:   policy.Patterns,
+			}
+			for _, p := range c.Patterns {
+				if r, err := regexp.Compile(p); err == nil {
+					policy.Patterns = append(policy.Patterns, r)
+				}
+			}
+			ok = true
+		}
+	}
+	if !ok || policy.MaxRetries <= 0 {
+		return nil
+	}
+	return &policy
+}
+
+// RetryDelay 判断第 retried+1 次重试是否可以执行，返回重试前的等待时间
+func (p *stepRetryPolicy) RetryDelay(retried int, log []byte) (time.Duration, bool) {
+	if retried >= p.MaxRetries || !p.isTransientError(log) {
+		return 0, false
+	}
+	delay := p.Backoff
+	for i := 0; i < retried && delay < maxStepRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxStepRetryBackoff {
+		delay = maxStepRetryBackoff
+	}
+	return delay, true
+}
+
+func (p *stepRetryPolicy) isTransientError(log []byte) bool {
+	for _, r := range p.Patterns {
+		if r.Match(log) {
+			return true
+		}
+	}
+	return false
+}
+
+// stepRetryDelay 判断失败的步骤是否需要自动重试，只检查 logOffset 之后(即本次执行)的日志，
+// 需要重试时会更新 logOffset 为当前的日志长度
+func stepRetryDelay(step *models.TaskStep, logOffset *int) (time.Duration, bool) {
+	policy := getStepRetryPolicy(step.Type)
+	if policy == nil {
+		return 0, false
+	}
+
+	content, err := logstorage.Get().Read(step.LogPath)
+	if err != nil {
+		logs.Get().WithField("taskId", step.TaskId).Errorf("read step log error: %v", err)
+		return 0, false
+	}
+	size := len(content)
+	if *logOffset <= size {
+		content = content[*logOffset:]
+	}
+
+	delay, ok := policy.RetryDelay(step.RetryCount, content)
+	if ok {
+		*logOffset = size
+	}
+	return delay, ok
+}
@@ -259,7 +259,8 @@ func pullTaskStepStatus(ctx context.Context, task *models.Task, step *models.Tas
 }
 
 var (
-	ErrTaskStepRejected = fmt.Errorf("rejected")
+	ErrTaskStepRejected        = fmt.Errorf("rejected")
+	ErrTaskStepApprovalExpired = fmt.Errorf("approval expired")
 )
 
 // WaitTaskStepApprove
@@ -284,6 +285,8 @@ func WaitTaskStepApprove(ctx context.Context, dbSess *db.Session, taskId models.
 				return nil, ErrTaskStepRejected
 			} else if taskStep.IsApproved() {
 				return taskStep, nil
+			} else if taskStep.ApprovalExpireAt != nil && time.Now().After(time.Time(*taskStep.ApprovalExpireAt)) {
+				return nil, ErrTaskStepApprovalExpired
 			}
 		}
 	}
@@ -0,0 +1,111 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package handlers
+
+import (
+	"cloudiac/portal/apps"
+	"cloudiac/portal/libs/ctrl"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models/forms"
+)
+
+type ApprovalPolicy struct {
+	ctrl.GinController
+}
+
+// Create 创建审批策略
+// @Summary 创建审批策略
+// @Description 传入环境ID时创建环境级审批策略，否则创建项目级审批策略。环境级策略优先于项目级策略
+// @Tags 审批策略
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param json body forms.CreateApprovalPolicyForm true "审批策略信息"
+// @Router /approval-policies [post]
+// @Success 200 {object} ctx.JSONResult{result=models.ApprovalPolicy}
+func (ApprovalPolicy) Create(c *ctx.GinRequest) {
+	form := &forms.CreateApprovalPolicyForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreateApprovalPolicy(c.Service(), form))
+}
+
+// Search 查询审批策略
+// @Summary 查询审批策略
+// @Tags 审批策略
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data query forms.SearchApprovalPolicyForm true "查询参数"
+// @Router /approval-policies [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.ApprovalPolicy}}
+func (ApprovalPolicy) Search(c *ctx.GinRequest) {
+	form := &forms.SearchApprovalPolicyForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchApprovalPolicy(c.Service(), form))
+}
+
+// Detail 审批策略详情
+// @Summary 审批策略详情
+// @Tags 审批策略
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param policyId path string true "审批策略ID"
+// @Router /approval-policies/{policyId} [get]
+// @Success 200 {object} ctx.JSONResult{result=models.ApprovalPolicy}
+func (ApprovalPolicy) Detail(c *ctx.GinRequest) {
+	form := &forms.DetailApprovalPolicyForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.ApprovalPolicyDetail(c.Service(), form))
+}
+
+// Update 修改审批策略
+// @Summary 修改审批策略
+// @Tags 审批策略
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param policyId path string true "审批策略ID"
+// @Param json body forms.UpdateApprovalPolicyForm true "审批策略信息"
+// @Router /approval-policies/{policyId} [put]
+// @Success 200 {object} ctx.JSONResult{result=models.ApprovalPolicy}
+func (ApprovalPolicy) Update(c *ctx.GinRequest) {
+	form := &forms.UpdateApprovalPolicyForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.UpdateApprovalPolicy(c.Service(), form))
+}
+
+// Delete 删除审批策略
+// @Summary 删除审批策略
+// @Tags 审批策略
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param policyId path string true "审批策略ID"
+// @Router /approval-policies/{policyId} [delete]
+// @Success 200
+func (ApprovalPolicy) Delete(c *ctx.GinRequest) {
+	form := &forms.DeleteApprovalPolicyForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeleteApprovalPolicy(c.Service(), form))
+}
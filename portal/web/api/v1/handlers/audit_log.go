@@ -0,0 +1,34 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package handlers
+
+import (
+	"cloudiac/portal/apps"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models/forms"
+)
+
+// SearchAuditLog 查询审计日志
+// @Summary 查询审计日志
+// @Description 查询审计日志，export=true 时按 format 导出 csv 或 jsonl 文件
+// @Tags 审计日志
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string false "组织ID"
+// @Param data query forms.SearchAuditLogForm true "查询参数"
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.OperationLog}}
+// @Router /audit-logs [get]
+func SearchAuditLog(c *ctx.GinRequest) {
+	form := &forms.SearchAuditLogForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	if form.Export() {
+		if err := apps.ExportAuditLog(c, form); err != nil {
+			c.JSONError(err)
+		}
+		return
+	}
+	c.JSONResult(apps.SearchAuditLog(c.Service(), form))
+}
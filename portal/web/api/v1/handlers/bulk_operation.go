@@ -0,0 +1,73 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package handlers
+
+import (
+	"cloudiac/portal/apps"
+	"cloudiac/portal/libs/ctrl"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models/forms"
+)
+
+type BulkOperation struct {
+	ctrl.GinController
+}
+
+// Create 创建批量操作
+// @Summary 创建批量操作
+// @Description 对按ID或筛选条件选择的多个环境批量执行 plan、部署、销毁或更新变量，可限制并发数和失败数上限
+// @Tags 批量操作
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param json body forms.CreateBulkOperationForm true "批量操作信息"
+// @Router /bulk-operations [post]
+// @Success 200 {object} ctx.JSONResult{result=apps.bulkOperationDetail}
+func (BulkOperation) Create(c *ctx.GinRequest) {
+	form := &forms.CreateBulkOperationForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreateBulkOperation(c.Service(), form))
+}
+
+// Search 查询批量操作
+// @Summary 查询批量操作
+// @Tags 批量操作
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data query forms.SearchBulkOperationForm true "查询参数"
+// @Router /bulk-operations [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.BulkOperation}}
+func (BulkOperation) Search(c *ctx.GinRequest) {
+	form := &forms.SearchBulkOperationForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchBulkOperation(c.Service(), form))
+}
+
+// Detail 批量操作详情
+// @Summary 批量操作详情
+// @Description 返回批量操作的汇总状态及每个环境的执行状态和任务
+// @Tags 批量操作
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param bulkId path string true "批量操作ID"
+// @Router /bulk-operations/{bulkId} [get]
+// @Success 200 {object} ctx.JSONResult{result=apps.bulkOperationDetail}
+func (BulkOperation) Detail(c *ctx.GinRequest) {
+	form := &forms.DetailBulkOperationForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.BulkOperationDetail(c.Service(), form))
+}
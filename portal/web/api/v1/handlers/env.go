@@ -141,15 +141,192 @@ func (Env) Deploy(c *ctx.GinRequest) {
 // @Param IaC-Org-Id header string true "组织ID"
 // @Param IaC-Project-Id header string true "项目ID"
 // @Param envId path string true "环境ID"
+// @Param json body forms.DestroyEnvForm false "parameter"
 // @router /envs/{envId}/destroy [post]
 // @Success 200 {object} ctx.JSONResult{result=models.EnvDetail}
 func (Env) Destroy(c *ctx.GinRequest) {
 	form := forms.DeployEnvForm{}
 	form.Id = models.Id(c.Param("id"))
 	form.TaskType = models.TaskTypeDestroy
+
+	// 销毁请求可以不带请求体，只有紧急变更时需要传入参数
+	if c.Request.ContentLength != 0 {
+		destroyForm := forms.DestroyEnvForm{}
+		if err := c.Bind(&destroyForm); err != nil {
+			return
+		}
+		form.FreezeOverride = destroyForm.FreezeOverride
+		form.FreezeOverrideReason = destroyForm.FreezeOverrideReason
+	}
 	c.JSONResult(apps.EnvDeploy(c.Service(), &form))
 }
 
+// Dependencies 环境依赖关系
+// @Tags 环境
+// @Summary 环境依赖关系
+// @Description 环境变量通过 env://<envId>/outputs/<name> 引用其他环境的输出时形成依赖关系
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param envId path string true "环境ID"
+// @router /envs/{envId}/dependencies [get]
+// @Success 200 {object} ctx.JSONResult{result=apps.envDependenciesResp}
+func (Env) Dependencies(c *ctx.GinRequest) {
+	form := forms.DetailEnvForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.EnvDependencies(c.Service(), &form))
+}
+
+// VariableDiff 环境变量变化
+// @Tags 环境
+// @Summary 环境变量变化
+// @Description 比较环境下次部署将使用的变量与最后一次成功部署时使用的变量，敏感变量的值会被隐藏
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param envId path string true "环境ID"
+// @router /envs/{envId}/variables/diff [get]
+// @Success 200 {object} ctx.JSONResult{result=apps.variableChangesResp}
+func (Env) VariableDiff(c *ctx.GinRequest) {
+	form := forms.DetailEnvForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.EnvVariableDiff(c.Service(), &form))
+}
+
+// Clone 克隆环境
+// @Tags 环境
+// @Summary 克隆环境
+// @Description 复制源环境的模板、分支、变量、触发器、存活时间及密钥等配置创建新环境，可指定目标项目及覆盖的变量
+// @Accept json
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data body forms.CloneEnvForm true "克隆参数"
+// @Param envId path string true "源环境ID"
+// @router /envs/{envId}/clone [post]
+// @Success 200 {object} ctx.JSONResult{result=models.EnvDetail}
+func (Env) Clone(c *ctx.GinRequest) {
+	form := forms.CloneEnvForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CloneEnv(c.Service(), &form))
+}
+
+// Promote 环境晋级
+// @Tags 环境
+// @Summary 环境晋级
+// @Description 将源环境最后一次部署成功的 commit 部署到目标环境，目标环境使用自身的变量
+// @Accept json
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data body forms.PromoteEnvForm true "晋级参数"
+// @Param envId path string true "源环境ID"
+// @router /envs/{envId}/promote [post]
+// @Success 200 {object} ctx.JSONResult{result=models.EnvDetail}
+func (Env) Promote(c *ctx.GinRequest) {
+	form := forms.PromoteEnvForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.PromoteEnv(c.Service(), &form))
+}
+
+// Rollback 环境回滚
+// @Tags 环境
+// @Summary 环境回滚
+// @Description 使用历史部署任务的 commit 和变量重新部署环境，任务需要审批，审批前可以通过 /tasks/{taskId}/plan 查看资源变更
+// @Accept json
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data body forms.RollbackEnvForm true "回滚参数"
+// @Param envId path string true "环境ID"
+// @router /envs/{envId}/rollback [post]
+// @Success 200 {object} ctx.JSONResult{result=models.EnvDetail}
+func (Env) Rollback(c *ctx.GinRequest) {
+	form := forms.RollbackEnvForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.EnvRollback(c.Service(), &form))
+}
+
+// Import 导入已有资源
+// @Tags 环境
+// @Summary 导入已有资源
+// @Description 创建导入任务，通过 terraform import 将已有的云资源导入到环境
+// @Accept json
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data body forms.ImportEnvForm true "导入参数"
+// @Param envId path string true "环境ID"
+// @router /envs/{envId}/import [post]
+// @Success 200 {object} ctx.JSONResult{result=models.EnvDetail}
+func (Env) Import(c *ctx.GinRequest) {
+	form := forms.ImportEnvForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.EnvImport(c.Service(), &form))
+}
+
+// UploadState 上传 terraform state
+// @Tags 环境
+// @Summary 上传 terraform state
+// @Description 使用已有的 terraform.tfstate 初始化未部署的环境，上传后自动执行导入任务同步环境资源
+// @Accept json
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data body forms.UploadEnvStateForm true "state 内容"
+// @Param envId path string true "环境ID"
+// @router /envs/{envId}/state [post]
+// @Success 200 {object} ctx.JSONResult{result=models.EnvDetail}
+func (Env) UploadState(c *ctx.GinRequest) {
+	form := forms.UploadEnvStateForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.EnvUploadState(c.Service(), &form))
+}
+
+// StateOperation 资源状态操作
+// @Tags 环境
+// @Summary 资源状态操作
+// @Description 对环境资源执行 replace(terraform apply -replace)、taint、state rm、state mv 操作，与部署任务一样需要审批
+// @Accept json
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data body forms.EnvStateOperationForm true "操作参数"
+// @Param envId path string true "环境ID"
+// @router /envs/{envId}/state/operations [post]
+// @Success 200 {object} ctx.JSONResult{result=models.EnvDetail}
+func (Env) StateOperation(c *ctx.GinRequest) {
+	form := forms.EnvStateOperationForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.EnvStateOperation(c.Service(), &form))
+}
+
 // SearchResources 获取环境资源列表
 // @Tags 环境
 // @Summary 获取环境资源列表
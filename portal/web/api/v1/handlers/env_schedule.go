@@ -0,0 +1,132 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package handlers
+
+import (
+	"cloudiac/portal/apps"
+	"cloudiac/portal/libs/ctrl"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models/forms"
+)
+
+type EnvSchedule struct {
+	ctrl.GinController
+}
+
+// Create 创建环境定时任务
+// @Summary 创建环境定时任务
+// @Description 按 cron 表达式周期性地部署或销毁环境，如工作日 08:00 部署、20:00 销毁
+// @Tags 环境定时任务
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param json body forms.CreateEnvScheduleForm true "定时任务信息"
+// @Router /env-schedules [post]
+// @Success 200 {object} ctx.JSONResult{result=models.EnvSchedule}
+func (EnvSchedule) Create(c *ctx.GinRequest) {
+	form := &forms.CreateEnvScheduleForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreateEnvSchedule(c.Service(), form))
+}
+
+// Search 查询环境定时任务
+// @Summary 查询环境定时任务
+// @Tags 环境定时任务
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data query forms.SearchEnvScheduleForm true "查询参数"
+// @Router /env-schedules [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.EnvSchedule}}
+func (EnvSchedule) Search(c *ctx.GinRequest) {
+	form := &forms.SearchEnvScheduleForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchEnvSchedule(c.Service(), form))
+}
+
+// Detail 环境定时任务详情
+// @Summary 环境定时任务详情
+// @Tags 环境定时任务
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param scheduleId path string true "定时任务ID"
+// @Router /env-schedules/{scheduleId} [get]
+// @Success 200 {object} ctx.JSONResult{result=models.EnvSchedule}
+func (EnvSchedule) Detail(c *ctx.GinRequest) {
+	form := &forms.DetailEnvScheduleForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.EnvScheduleDetail(c.Service(), form))
+}
+
+// Update 修改环境定时任务
+// @Summary 修改环境定时任务
+// @Description 通过 paused 字段暂停或恢复定时任务
+// @Tags 环境定时任务
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param scheduleId path string true "定时任务ID"
+// @Param json body forms.UpdateEnvScheduleForm true "定时任务信息"
+// @Router /env-schedules/{scheduleId} [put]
+// @Success 200 {object} ctx.JSONResult{result=models.EnvSchedule}
+func (EnvSchedule) Update(c *ctx.GinRequest) {
+	form := &forms.UpdateEnvScheduleForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.UpdateEnvSchedule(c.Service(), form))
+}
+
+// Delete 删除环境定时任务
+// @Summary 删除环境定时任务
+// @Tags 环境定时任务
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param scheduleId path string true "定时任务ID"
+// @Router /env-schedules/{scheduleId} [delete]
+// @Success 200
+func (EnvSchedule) Delete(c *ctx.GinRequest) {
+	form := &forms.DeleteEnvScheduleForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeleteEnvSchedule(c.Service(), form))
+}
+
+// SearchRuns 环境定时任务执行记录
+// @Summary 环境定时任务执行记录
+// @Tags 环境定时任务
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param scheduleId path string true "定时任务ID"
+// @Param data query forms.SearchEnvScheduleRunForm true "查询参数"
+// @Router /env-schedules/{scheduleId}/runs [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.EnvScheduleRun}}
+func (EnvSchedule) SearchRuns(c *ctx.GinRequest) {
+	form := &forms.SearchEnvScheduleRunForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchEnvScheduleRun(c.Service(), form))
+}
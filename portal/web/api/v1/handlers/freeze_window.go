@@ -0,0 +1,112 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package handlers
+
+import (
+	"cloudiac/portal/apps"
+	"cloudiac/portal/libs/ctrl"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models/forms"
+)
+
+type FreezeWindow struct {
+	ctrl.GinController
+}
+
+// Create 创建冻结窗口
+// @Summary 创建冻结窗口
+// @Description 传入项目ID时创建项目级冻结窗口，否则创建组织级冻结窗口。冻结期内禁止执行部署和销毁任务
+// @Tags 冻结窗口
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string false "项目ID"
+// @Param json body forms.CreateFreezeWindowForm true "冻结窗口信息"
+// @Router /freeze-windows [post]
+// @Success 200 {object} ctx.JSONResult{result=models.FreezeWindow}
+func (FreezeWindow) Create(c *ctx.GinRequest) {
+	form := &forms.CreateFreezeWindowForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreateFreezeWindow(c.Service(), form))
+}
+
+// Search 查询冻结窗口
+// @Summary 查询冻结窗口
+// @Description 传入项目ID时返回项目及所属组织的冻结窗口
+// @Tags 冻结窗口
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string false "项目ID"
+// @Param data query forms.SearchFreezeWindowForm true "查询参数"
+// @Router /freeze-windows [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.FreezeWindow}}
+func (FreezeWindow) Search(c *ctx.GinRequest) {
+	form := &forms.SearchFreezeWindowForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchFreezeWindow(c.Service(), form))
+}
+
+// Detail 冻结窗口详情
+// @Summary 冻结窗口详情
+// @Tags 冻结窗口
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string false "项目ID"
+// @Param windowId path string true "冻结窗口ID"
+// @Router /freeze-windows/{windowId} [get]
+// @Success 200 {object} ctx.JSONResult{result=models.FreezeWindow}
+func (FreezeWindow) Detail(c *ctx.GinRequest) {
+	form := &forms.DetailFreezeWindowForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.FreezeWindowDetail(c.Service(), form))
+}
+
+// Update 修改冻结窗口
+// @Summary 修改冻结窗口
+// @Tags 冻结窗口
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string false "项目ID"
+// @Param windowId path string true "冻结窗口ID"
+// @Param json body forms.UpdateFreezeWindowForm true "冻结窗口信息"
+// @Router /freeze-windows/{windowId} [put]
+// @Success 200 {object} ctx.JSONResult{result=models.FreezeWindow}
+func (FreezeWindow) Update(c *ctx.GinRequest) {
+	form := &forms.UpdateFreezeWindowForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.UpdateFreezeWindow(c.Service(), form))
+}
+
+// Delete 删除冻结窗口
+// @Summary 删除冻结窗口
+// @Tags 冻结窗口
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string false "项目ID"
+// @Param windowId path string true "冻结窗口ID"
+// @Router /freeze-windows/{windowId} [delete]
+// @Success 200
+func (FreezeWindow) Delete(c *ctx.GinRequest) {
+	form := &forms.DeleteFreezeWindowForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeleteFreezeWindow(c.Service(), form))
+}
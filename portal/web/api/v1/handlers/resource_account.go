@@ -44,3 +44,36 @@ func (ResourceAccount) Update(c *ctx.GinRequest) {
 	}
 	c.JSONResult(apps.UpdateResourceAccount(c.Service(), form))
 }
+
+// Schemas 云厂商资源账号参数格式
+// @Summary 云厂商资源账号参数格式
+// @Description 返回各云厂商资源账号的参数列表，包括是否必填、是否敏感及注入任务时使用的环境变量名
+// @Tags 资源账号
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Router /resource/account/schemas [get]
+// @Success 200 {object} ctx.JSONResult{result=[]credential.Schema}
+func (ResourceAccount) Schemas(c *ctx.GinRequest) {
+	c.JSONResult(apps.ResourceAccountSchemas(c.Service()))
+}
+
+// Test 测试资源账号连接
+// @Summary 测试资源账号连接
+// @Description 使用账号凭证调用云厂商接口校验凭证是否有效，sts 类型账号校验能否扮演角色
+// @Tags 资源账号
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param id path string true "资源账号ID"
+// @Router /resource/account/{id}/test [post]
+// @Success 200 {object} ctx.JSONResult{result=apps.TestResourceAccountResp}
+func (ResourceAccount) Test(c *ctx.GinRequest) {
+	form := &forms.TestResourceAccountForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.TestResourceAccount(c.Service(), form))
+}
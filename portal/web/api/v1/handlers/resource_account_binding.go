@@ -0,0 +1,72 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package handlers
+
+import (
+	"cloudiac/portal/apps"
+	"cloudiac/portal/libs/ctrl"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models/forms"
+)
+
+type ResourceAccountBinding struct {
+	ctrl.GinController
+}
+
+// Create 绑定资源账号
+// @Summary 绑定资源账号
+// @Description 将资源账号绑定到当前项目或指定环境，任务执行时账号变量作为环境变量注入。同名变量优先级：环境/模板变量 > 环境绑定的账号 > 项目绑定的账号
+// @Tags 资源账号
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param json body forms.CreateResourceAccountBindingForm true "绑定信息"
+// @Router /resource-account-bindings [post]
+// @Success 200 {object} ctx.JSONResult{result=models.ResourceAccountBinding}
+func (ResourceAccountBinding) Create(c *ctx.GinRequest) {
+	form := &forms.CreateResourceAccountBindingForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreateResourceAccountBinding(c.Service(), form))
+}
+
+// Search 查询资源账号绑定
+// @Summary 查询资源账号绑定
+// @Tags 资源账号
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data query forms.SearchResourceAccountBindingForm true "查询参数"
+// @Router /resource-account-bindings [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.ResourceAccountBindingResp}}
+func (ResourceAccountBinding) Search(c *ctx.GinRequest) {
+	form := &forms.SearchResourceAccountBindingForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchResourceAccountBinding(c.Service(), form))
+}
+
+// Delete 解除资源账号绑定
+// @Summary 解除资源账号绑定
+// @Tags 资源账号
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param bindingId path string true "绑定关系ID"
+// @Router /resource-account-bindings/{bindingId} [delete]
+// @Success 200 {object} ctx.JSONResult
+func (ResourceAccountBinding) Delete(c *ctx.GinRequest) {
+	form := &forms.DeleteResourceAccountBindingForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeleteResourceAccountBinding(c.Service(), form))
+}
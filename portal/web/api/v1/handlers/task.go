@@ -87,7 +87,7 @@ func (Task) FollowLogSse(c *ctx.GinRequest) {
 // @Param taskId path string true "任务ID"
 // @Param form formData forms.ApproveTaskForm true "parameter"
 // @router /tasks/{taskId}/approve [post]
-// @Success 200 {object} ctx.JSONResult{result=apps.taskDetailResp}
+// @Success 200 {object} ctx.JSONResult{result=models.TaskApprovalStatus}
 func (Task) TaskApprove(c *ctx.GinRequest) {
 	form := &forms.ApproveTaskForm{}
 	if err := c.Bind(form); err != nil {
@@ -96,6 +96,65 @@ func (Task) TaskApprove(c *ctx.GinRequest) {
 	c.JSONResult(apps.ApproveTask(c.Service(), form))
 }
 
+// Retry 重试任务
+// @Tags 环境
+// @Summary 从失败的步骤开始重试任务
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param taskId path string true "任务ID"
+// @router /tasks/{taskId}/retry [post]
+// @Success 200 {object} ctx.JSONResult{result=models.Task}
+func (Task) Retry(c *ctx.GinRequest) {
+	form := &forms.RetryTaskForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.RetryTask(c.Service(), form))
+}
+
+// Plan 任务执行计划
+// @Tags 环境
+// @Summary 任务执行计划
+// @Description 查询任务 plan 步骤生成的资源变更及与环境最后一次成功部署相比变化的变量，plan 步骤未完成时资源变更列表为空
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param taskId path string true "任务ID"
+// @router /tasks/{taskId}/plan [get]
+// @Success 200 {object} ctx.JSONResult{result=apps.taskPlanResp}
+func (Task) Plan(c *ctx.GinRequest) {
+	form := &forms.DetailTaskForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.TaskPlan(c.Service(), form))
+}
+
+// SearchApproval 任务审批记录
+// @Tags 环境
+// @Summary 任务审批记录
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param taskId path string true "任务ID"
+// @Param data query forms.SearchTaskApprovalForm true "parameter"
+// @router /tasks/{taskId}/approvals [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.TaskApprovalResp}}
+func (Task) SearchApproval(c *ctx.GinRequest) {
+	form := &forms.SearchTaskApprovalForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchTaskApproval(c.Service(), form))
+}
+
 // Log 任务日志
 // @Tags 环境
 // @Summary 任务日志
@@ -0,0 +1,243 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package handlers
+
+import (
+	"cloudiac/portal/apps"
+	"cloudiac/portal/libs/ctrl"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models/forms"
+)
+
+type Team struct {
+	ctrl.GinController
+}
+
+// Create 创建团队
+// @Summary 创建团队
+// @Tags 团队
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param json body forms.CreateTeamForm true "团队信息"
+// @Router /teams [post]
+// @Success 200 {object} ctx.JSONResult{result=models.Team}
+func (Team) Create(c *ctx.GinRequest) {
+	form := &forms.CreateTeamForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreateTeam(c.Service(), form))
+}
+
+// Search 查询团队
+// @Summary 查询团队
+// @Tags 团队
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param data query forms.SearchTeamForm true "团队查询参数"
+// @Router /teams [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.TeamResp}}
+func (Team) Search(c *ctx.GinRequest) {
+	form := &forms.SearchTeamForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchTeam(c.Service(), form))
+}
+
+// Detail 团队详情
+// @Summary 团队详情
+// @Tags 团队
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param teamId path string true "团队ID"
+// @Router /teams/{teamId} [get]
+// @Success 200 {object} ctx.JSONResult{result=models.Team}
+func (Team) Detail(c *ctx.GinRequest) {
+	form := &forms.DetailTeamForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.TeamDetail(c.Service(), form))
+}
+
+// Update 修改团队信息
+// @Summary 修改团队信息
+// @Tags 团队
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param teamId path string true "团队ID"
+// @Param json body forms.UpdateTeamForm true "团队信息"
+// @Router /teams/{teamId} [put]
+// @Success 200 {object} ctx.JSONResult{result=models.Team}
+func (Team) Update(c *ctx.GinRequest) {
+	form := &forms.UpdateTeamForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.UpdateTeam(c.Service(), form))
+}
+
+// Delete 删除团队
+// @Summary 删除团队
+// @Tags 团队
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param teamId path string true "团队ID"
+// @Router /teams/{teamId} [delete]
+// @Success 200
+func (Team) Delete(c *ctx.GinRequest) {
+	form := &forms.DeleteTeamForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeleteTeam(c.Service(), form))
+}
+
+// SearchUser 查询团队成员
+// @Summary 查询团队成员
+// @Tags 团队
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param teamId path string true "团队ID"
+// @Param data query forms.SearchTeamUserForm true "查询参数"
+// @Router /teams/{teamId}/users [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.User}}
+func (Team) SearchUser(c *ctx.GinRequest) {
+	form := &forms.SearchTeamUserForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchTeamUser(c.Service(), form))
+}
+
+// AddUser 添加团队成员
+// @Summary 添加团队成员
+// @Tags 团队
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param teamId path string true "团队ID"
+// @Param json body forms.AddTeamUserForm true "用户id列表"
+// @Router /teams/{teamId}/users [post]
+// @Success 200
+func (Team) AddUser(c *ctx.GinRequest) {
+	form := &forms.AddTeamUserForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.AddTeamUser(c.Service(), form))
+}
+
+// RemoveUser 移除团队成员
+// @Summary 移除团队成员
+// @Tags 团队
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param teamId path string true "团队ID"
+// @Param userId path string true "用户ID"
+// @Router /teams/{teamId}/users/{userId} [delete]
+// @Success 200
+func (Team) RemoveUser(c *ctx.GinRequest) {
+	form := &forms.DeleteTeamUserForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeleteTeamUser(c.Service(), form))
+}
+
+type ProjectTeam struct {
+	ctrl.GinController
+}
+
+// Create 授权团队访问项目
+// @Summary 授权团队访问项目
+// @Tags 项目
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织id"
+// @Param IaC-Project-Id header string true "项目id"
+// @Param json body forms.CreateProjectTeamForm true "parameter"
+// @Success 200 {object} ctx.JSONResult{result=models.TeamProject}
+// @Router /projects/teams [post]
+func (ProjectTeam) Create(c *ctx.GinRequest) {
+	form := &forms.CreateProjectTeamForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreateProjectTeam(c.Service(), form))
+}
+
+// Search 项目下授权的团队
+// @Summary 项目下授权的团队
+// @Tags 项目
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织id"
+// @Param IaC-Project-Id header string true "项目id"
+// @Param data query forms.SearchProjectTeamForm true "parameter"
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.TeamProjectResp}}
+// @Router /projects/teams [get]
+func (ProjectTeam) Search(c *ctx.GinRequest) {
+	form := &forms.SearchProjectTeamForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchProjectTeam(c.Service(), form))
+}
+
+// Update 修改团队在项目下的角色
+// @Summary 修改团队在项目下的角色
+// @Tags 项目
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织id"
+// @Param IaC-Project-Id header string true "项目id"
+// @Param id path string true "项目团队授权id"
+// @Param json body forms.UpdateProjectTeamForm true "parameter"
+// @Success 200
+// @Router /projects/teams/{id} [put]
+func (ProjectTeam) Update(c *ctx.GinRequest) {
+	form := &forms.UpdateProjectTeamForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.UpdateProjectTeam(c.Service(), form))
+}
+
+// Delete 取消团队的项目授权
+// @Summary 取消团队的项目授权
+// @Tags 项目
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织id"
+// @Param IaC-Project-Id header string true "项目id"
+// @Param id path string true "项目团队授权id"
+// @Success 200
+// @Router /projects/teams/{id} [delete]
+func (ProjectTeam) Delete(c *ctx.GinRequest) {
+	form := &forms.DeleteProjectTeamForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeleteProjectTeam(c.Service(), form))
+}
@@ -110,6 +110,57 @@ func (Token) DetailTriggerToken(c *ctx.GinRequest) {
 	c.JSONResult(apps.DetailTriggerToken(c.Service(), form))
 }
 
+// SearchPersonal 查询个人访问令牌
+// @Summary 查询个人访问令牌
+// @Tags Token
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param data query forms.SearchPersonalTokenForm true "查询参数"
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.Token}}
+// @Router /users/self/tokens [get]
+func (Token) SearchPersonal(c *ctx.GinRequest) {
+	form := &forms.SearchPersonalTokenForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchPersonalToken(c.Service(), form))
+}
+
+// CreatePersonal 创建个人访问令牌
+// @Summary 创建个人访问令牌
+// @Tags Token
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param data body forms.CreatePersonalTokenForm true "令牌信息"
+// @Success 200 {object} ctx.JSONResult{result=models.Token}
+// @Router /users/self/tokens [post]
+func (Token) CreatePersonal(c *ctx.GinRequest) {
+	form := &forms.CreatePersonalTokenForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreatePersonalToken(c.Service(), form))
+}
+
+// DeletePersonal 删除个人访问令牌
+// @Summary 删除个人访问令牌
+// @Tags Token
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param tokenId path string true "TokenID"
+// @Success 200
+// @Router /users/self/tokens/{tokenId} [delete]
+func (Token) DeletePersonal(c *ctx.GinRequest) {
+	form := &forms.DeletePersonalTokenForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeletePersonalToken(c.Service(), form))
+}
+
 func ApiTriggerHandler(c *ctx.GinRequest) {
 	form := forms.ApiTriggerHandler{}
 	if err := c.Bind(&form); err != nil {
@@ -50,3 +50,63 @@ func (Variable) Search(c *ctx.GinRequest) {
 	}
 	c.JSONResult(apps.SearchVariable(c.Service(), &form))
 }
+
+// SearchHistory 查询变量变更历史
+// @Tags 变量
+// @Summary 查询变量变更历史
+// @Description 返回指定应用范围下变量的新增、修改、删除记录，敏感变量的值会被隐藏
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string false "项目ID"
+// @Param form query forms.SearchVariableHistoryForm true "parameter"
+// @router /variables/history [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.VariableHistoryResp}}
+func (Variable) SearchHistory(c *ctx.GinRequest) {
+	form := forms.SearchVariableHistoryForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchVariableHistory(c.Service(), &form))
+}
+
+// Import 导入变量
+// @Tags 变量
+// @Summary 导入变量
+// @Description 从 tfvars、tfvars.json 或 ansible vars yaml 文件导入变量，dryRun 为 true 时只返回新建、覆盖及冲突的预览
+// @Accept json
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string false "项目ID"
+// @Param form body forms.ImportVariableForm true "parameter"
+// @router /variables/import [post]
+// @Success 200 {object} ctx.JSONResult{result=apps.importVariableResp}
+func (Variable) Import(c *ctx.GinRequest) {
+	form := forms.ImportVariableForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.ImportVariable(c.Service(), &form))
+}
+
+// Export 导出变量
+// @Tags 变量
+// @Summary 导出变量
+// @Description 导出作用域下定义的变量，敏感变量的值默认不导出
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string false "项目ID"
+// @Param form query forms.ExportVariableForm true "parameter"
+// @router /variables/export [get]
+// @Success 200 {object} ctx.JSONResult{result=apps.exportVariableResp}
+func (Variable) Export(c *ctx.GinRequest) {
+	form := forms.ExportVariableForm{}
+	if err := c.Bind(&form); err != nil {
+		return
+	}
+	c.JSONResult(apps.ExportVariable(c.Service(), &form))
+}
@@ -0,0 +1,168 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package handlers
+
+import (
+	"cloudiac/portal/apps"
+	"cloudiac/portal/libs/ctrl"
+	"cloudiac/portal/libs/ctx"
+	"cloudiac/portal/models/forms"
+)
+
+type VariableSet struct {
+	ctrl.GinController
+}
+
+// Create 创建变量集
+// @Summary 创建变量集
+// @Description 变量集是组织下一组命名的变量，可以关联到任意项目或环境
+// @Tags 变量集
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param json body forms.CreateVariableSetForm true "变量集信息"
+// @Router /variable-sets [post]
+// @Success 200 {object} ctx.JSONResult{result=models.VariableSet}
+func (VariableSet) Create(c *ctx.GinRequest) {
+	form := &forms.CreateVariableSetForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreateVariableSet(c.Service(), form))
+}
+
+// Search 查询变量集
+// @Summary 查询变量集
+// @Tags 变量集
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param data query forms.SearchVariableSetForm true "查询参数"
+// @Router /variable-sets [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.VariableSet}}
+func (VariableSet) Search(c *ctx.GinRequest) {
+	form := &forms.SearchVariableSetForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchVariableSet(c.Service(), form))
+}
+
+// Detail 变量集详情
+// @Summary 变量集详情
+// @Tags 变量集
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param variableSetId path string true "变量集ID"
+// @Router /variable-sets/{variableSetId} [get]
+// @Success 200 {object} ctx.JSONResult{result=models.VariableSet}
+func (VariableSet) Detail(c *ctx.GinRequest) {
+	form := &forms.DetailVariableSetForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.VariableSetDetail(c.Service(), form))
+}
+
+// Update 修改变量集
+// @Summary 修改变量集
+// @Tags 变量集
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param variableSetId path string true "变量集ID"
+// @Param json body forms.UpdateVariableSetForm true "变量集信息"
+// @Router /variable-sets/{variableSetId} [put]
+// @Success 200 {object} ctx.JSONResult{result=models.VariableSet}
+func (VariableSet) Update(c *ctx.GinRequest) {
+	form := &forms.UpdateVariableSetForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.UpdateVariableSet(c.Service(), form))
+}
+
+// Delete 删除变量集
+// @Summary 删除变量集
+// @Tags 变量集
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param variableSetId path string true "变量集ID"
+// @Router /variable-sets/{variableSetId} [delete]
+// @Success 200 {object} ctx.JSONResult
+func (VariableSet) Delete(c *ctx.GinRequest) {
+	form := &forms.DeleteVariableSetForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeleteVariableSet(c.Service(), form))
+}
+
+type VariableSetAttachment struct {
+	ctrl.GinController
+}
+
+// Create 关联变量集
+// @Summary 关联变量集
+// @Description 将变量集关联到当前项目或指定环境。同名同类型变量优先级：环境变量 > 环境关联的变量集 > 项目关联的变量集 > 项目变量 > 模板变量 > 组织变量，同一级别后关联的变量集优先
+// @Tags 变量集
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param json body forms.CreateVariableSetAttachmentForm true "关联信息"
+// @Router /variable-set-attachments [post]
+// @Success 200 {object} ctx.JSONResult{result=models.VariableSetAttachment}
+func (VariableSetAttachment) Create(c *ctx.GinRequest) {
+	form := &forms.CreateVariableSetAttachmentForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.CreateVariableSetAttachment(c.Service(), form))
+}
+
+// Search 查询变量集关联
+// @Summary 查询变量集关联
+// @Tags 变量集
+// @Accept application/x-www-form-urlencoded
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param data query forms.SearchVariableSetAttachmentForm true "查询参数"
+// @Router /variable-set-attachments [get]
+// @Success 200 {object} ctx.JSONResult{result=page.PageResp{list=[]models.VariableSetAttachmentResp}}
+func (VariableSetAttachment) Search(c *ctx.GinRequest) {
+	form := &forms.SearchVariableSetAttachmentForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.SearchVariableSetAttachment(c.Service(), form))
+}
+
+// Delete 解除变量集关联
+// @Summary 解除变量集关联
+// @Tags 变量集
+// @Accept  json
+// @Produce  json
+// @Security AuthToken
+// @Param IaC-Org-Id header string true "组织ID"
+// @Param IaC-Project-Id header string true "项目ID"
+// @Param attachmentId path string true "关联关系ID"
+// @Router /variable-set-attachments/{attachmentId} [delete]
+// @Success 200 {object} ctx.JSONResult
+func (VariableSetAttachment) Delete(c *ctx.GinRequest) {
+	form := &forms.DeleteVariableSetAttachmentForm{}
+	if err := c.Bind(form); err != nil {
+		return
+	}
+	c.JSONResult(apps.DeleteVariableSetAttachment(c.Service(), form))
+}
@@ -43,11 +43,15 @@ func Register(g *gin.RouterGroup) {
 	g.POST("/auth/login", w(handlers.Auth{}.Login))
 
 	// Authorization Header 鉴权
-	g.Use(w(middleware.Auth)) // 解析 header token
+	g.Use(w(middleware.Auth))      // 解析 header token
+	g.Use(w(middleware.Operation)) // 记录审计日志
 
 	ctrl.Register(g.Group("token", ac()), &handlers.Auth{})
 	g.GET("/auth/me", ac("self", "read"), w(handlers.Auth{}.GetUserByToken))
 	g.PUT("/users/self", ac("self", "update"), w(handlers.User{}.UpdateSelf))
+	g.GET("/users/self/tokens", ac("self", "read"), w(handlers.Token{}.SearchPersonal))
+	g.POST("/users/self/tokens", ac("self", "update"), w(handlers.Token{}.CreatePersonal))
+	g.DELETE("/users/self/tokens/:id", ac("self", "update"), w(handlers.Token{}.DeletePersonal))
 	//todo runner list权限怎么划分
 	g.GET("/runners", ac(), w(handlers.RunnerSearch))
 	g.PUT("/consul/tags/update", ac(), w(handlers.ConsulTagUpdate))
@@ -64,6 +68,8 @@ func Register(g *gin.RouterGroup) {
 	g.GET("/systems", ac(), w(handlers.SystemConfig{}.Search))
 	// 系统状态
 	g.GET("/systems/status", w(handlers.PortalSystemStatusSearch))
+	// 审计日志
+	g.GET("/audit-logs", ac(), w(handlers.SearchAuditLog))
 
 	// 要求组织 header
 	g.Use(w(middleware.AuthOrgId))
@@ -81,10 +87,27 @@ func Register(g *gin.RouterGroup) {
 	g.PUT("/projects/users/:id", ac(), w(handlers.ProjectUser{}.Update))
 	g.DELETE("/projects/users/:id", ac(), w(handlers.ProjectUser{}.Delete))
 
+	// 团队管理
+	ctrl.Register(g.Group("teams", ac()), &handlers.Team{})
+	g.GET("/teams/:id/users", ac(), w(handlers.Team{}.SearchUser))
+	g.POST("/teams/:id/users", ac("teams", "update"), w(handlers.Team{}.AddUser))
+	g.DELETE("/teams/:id/users/:userId", ac("teams", "update"), w(handlers.Team{}.RemoveUser))
+
+	g.GET("/projects/teams", ac(), w(handlers.ProjectTeam{}.Search))
+	g.POST("/projects/teams", ac(), w(handlers.ProjectTeam{}.Create))
+	g.PUT("/projects/teams/:id", ac(), w(handlers.ProjectTeam{}.Update))
+	g.DELETE("/projects/teams/:id", ac(), w(handlers.ProjectTeam{}.Delete))
+
+	// 变更冻结窗口，传入项目 header 时为项目级冻结窗口
+	ctrl.Register(g.Group("freeze-windows", ac()), &handlers.FreezeWindow{})
+
 	//项目管理
 	ctrl.Register(g.Group("projects", ac()), &handlers.Project{})
 	//变量管理
 	g.PUT("/variables/batch", ac(), w(handlers.Variable{}.BatchUpdate))
+	g.GET("/variables/history", ac(), w(handlers.Variable{}.SearchHistory))
+	g.POST("/variables/import", ac(), w(handlers.Variable{}.Import))
+	g.GET("/variables/export", ac(), w(handlers.Variable{}.Export))
 	ctrl.Register(g.Group("variables", ac()), &handlers.Variable{})
 	//token管理
 	ctrl.Register(g.Group("tokens", ac()), &handlers.Token{})
@@ -113,6 +136,21 @@ func Register(g *gin.RouterGroup) {
 	g.POST("/envs/:id/deploy", ac("envs", "deploy"), w(handlers.Env{}.Deploy))
 	g.POST("/envs/:id/destroy", ac("envs", "destroy"), w(handlers.Env{}.Destroy))
 	g.GET("/envs/:id/resources", ac(), w(handlers.Env{}.SearchResources))
+	g.GET("/envs/:id/dependencies", ac(), w(handlers.Env{}.Dependencies))
+	g.GET("/envs/:id/variables/diff", ac(), w(handlers.Env{}.VariableDiff))
+	g.POST("/envs/:id/clone", ac("envs", "create"), w(handlers.Env{}.Clone))
+	g.POST("/envs/:id/promote", ac("envs", "deploy"), w(handlers.Env{}.Promote))
+	g.POST("/envs/:id/rollback", ac("envs", "deploy"), w(handlers.Env{}.Rollback))
+	g.POST("/envs/:id/import", ac("envs", "deploy"), w(handlers.Env{}.Import))
+	g.POST("/envs/:id/state", ac("envs", "deploy"), w(handlers.Env{}.UploadState))
+	g.POST("/envs/:id/state/operations", ac("envs", "deploy"), w(handlers.Env{}.StateOperation))
+
+	// 环境定时任务
+	ctrl.Register(g.Group("env-schedules", ac()), &handlers.EnvSchedule{})
+	g.GET("/env-schedules/:id/runs", ac(), w(handlers.EnvSchedule{}.SearchRuns))
+
+	// 批量操作
+	ctrl.Register(g.Group("bulk-operations", ac()), &handlers.BulkOperation{})
 
 	// 任务管理
 	g.GET("/tasks", ac(), w(handlers.Task{}.Search))
@@ -121,10 +159,23 @@ func Register(g *gin.RouterGroup) {
 	g.GET("/tasks/:id/log/sse", ac(), w(handlers.Task{}.FollowLogSse))
 	g.GET("/tasks/:id/output", ac(), w(handlers.Task{}.Output))
 	g.GET("/tasks/:id/resources", ac(), w(handlers.Task{}.Resource))
+	g.GET("/tasks/:id/plan", ac(), w(handlers.Task{}.Plan))
 	g.POST("/tasks/:id/approve", ac("tasks", "approve"), w(handlers.Task{}.TaskApprove))
+	g.POST("/tasks/:id/retry", ac("envs", "deploy"), w(handlers.Task{}.Retry))
 	g.POST("/tasks/:id/comment", ac(), w(handlers.TaskComment{}.Create))
 	g.GET("/tasks/:id/comment", ac(), w(handlers.TaskComment{}.Search))
+	g.GET("/tasks/:id/approvals", ac(), w(handlers.Task{}.SearchApproval))
+
+	// 审批策略
+	ctrl.Register(g.Group("approval-policies", ac()), &handlers.ApprovalPolicy{})
 
 	g.GET("/tokens/trigger", ac(), w(handlers.Token{}.DetailTriggerToken))
+	g.GET("/resource/account/schemas", ac(), w(handlers.ResourceAccount{}.Schemas))
+	g.POST("/resource/account/:id/test", ac(), w(handlers.ResourceAccount{}.Test))
 	ctrl.Register(g.Group("resource/account", ac()), &handlers.ResourceAccount{})
+	ctrl.Register(g.Group("resource-account-bindings", ac()), &handlers.ResourceAccountBinding{})
+
+	// 变量集
+	ctrl.Register(g.Group("variable-sets", ac()), &handlers.VariableSet{})
+	ctrl.Register(g.Group("variable-set-attachments", ac()), &handlers.VariableSetAttachment{})
 }
@@ -32,7 +32,6 @@ func GetRouter() *gin.Engine {
 
 	// 允许跨域
 	e.Use(w(middleware.Cors))
-	e.Use(w(middleware.Operation))
 	e.GET("/swagger/*any", gs.WrapHandler(swaggerFiles.Handler))
 
 	e.GET("/system/info", w(func(c *ctx.GinRequest) {
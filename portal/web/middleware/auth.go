@@ -27,11 +27,11 @@ func Auth(c *ctx.GinRequest) {
 		return []byte(configs.Get().JwtSecretKey), nil
 	})
 	if err != nil {
-		c.JSONError(e.New(e.InvalidToken), http.StatusUnauthorized)
-		return
-	}
-
-	if claims, ok := token.Claims.(*services.Claims); ok && token.Valid {
+		// 非登陆令牌，尝试作为个人访问令牌或服务账号 token 认证
+		if !authApiToken(c, tokenStr) {
+			return
+		}
+	} else if claims, ok := token.Claims.(*services.Claims); ok && token.Valid {
 		c.Service().UserId = claims.UserId
 		c.Service().Username = claims.Username
 		c.Service().IsSuperAdmin = claims.IsAdmin
@@ -51,15 +51,25 @@ func Auth(c *ctx.GinRequest) {
 			c.JSONError(e.New(e.PermissionDeny, fmt.Errorf("org disabled")), http.StatusForbidden)
 		}
 		if c.Service().IsSuperAdmin ||
+			services.IsServiceAccountOf(c.Service().ApiToken, orgId) ||
 			services.UserHasOrgRole(c.Service().UserId, c.Service().OrgId, "") {
 		} else {
 			c.JSONError(e.New(e.PermissionDeny, fmt.Errorf("not allow to access org")), http.StatusForbidden)
 			return
 		}
 	}
+
+	if !checkTokenEnvScope(c) {
+		return
+	}
+
 	projectId := models.Id(c.GetHeader("IaC-Project-Id"))
 	if projectId != "" {
 		c.Service().ProjectId = projectId
+		if apiToken := c.Service().ApiToken; apiToken != nil && !apiToken.Scopes.AllowProject(projectId) {
+			c.JSONError(e.New(e.InvalidTokenScope, fmt.Errorf("project not in token scopes")), http.StatusForbidden)
+			return
+		}
 		if project, err := services.GetProjectsById(c.Service().DB(), projectId); err != nil {
 			c.JSONError(e.New(e.ProjectNotExists, fmt.Errorf("not allow to access project")), http.StatusBadRequest)
 			return
@@ -71,6 +81,7 @@ func Auth(c *ctx.GinRequest) {
 			c.JSONError(e.New(e.PermissionDeny, fmt.Errorf("project disabled")), http.StatusForbidden)
 		}
 		if c.Service().IsSuperAdmin ||
+			services.IsServiceAccountOf(c.Service().ApiToken, c.Service().OrgId) ||
 			services.UserHasOrgRole(c.Service().UserId, c.Service().OrgId, consts.OrgRoleAdmin) ||
 			services.UserHasProjectRole(c.Service().UserId, c.Service().OrgId, c.Service().ProjectId, "") {
 			c.Next()
@@ -81,6 +92,80 @@ func Auth(c *ctx.GinRequest) {
 	}
 }
 
+// authApiToken 通过个人访问令牌或服务账号 token 认证，认证失败时返回 false
+func authApiToken(c *ctx.GinRequest, key string) bool {
+	s := c.Service()
+	apiToken, err := services.GetApiTokenByKey(s.DB(), key)
+	if err != nil {
+		if err.Code() != e.TokenNotExists {
+			c.Logger().Errorf("error get api token, err %s", err)
+		}
+		c.JSONError(e.New(e.InvalidToken), http.StatusUnauthorized)
+		return false
+	}
+	if apiToken.Status != models.Enable {
+		c.JSONError(e.New(e.InvalidToken, fmt.Errorf("token disabled")), http.StatusUnauthorized)
+		return false
+	}
+	if apiToken.IsExpired() {
+		c.JSONError(e.New(e.TokenExpired), http.StatusUnauthorized)
+		return false
+	}
+
+	if apiToken.Type == consts.TokenPersonal {
+		// 个人访问令牌以所属用户身份访问，权限受 token scopes 限制
+		user, err := services.GetUserById(s.DB(), apiToken.UserId)
+		if err != nil || user.Status != models.Enable {
+			c.JSONError(e.New(e.InvalidToken, fmt.Errorf("token user invalid")), http.StatusUnauthorized)
+			return false
+		}
+		s.UserId = user.Id
+		s.Username = user.Name
+		s.IsSuperAdmin = user.IsAdmin
+	} else {
+		// 服务账号没有对应的用户，直接使用 token id 作为访问者身份
+		s.UserId = apiToken.Id
+		s.Username = fmt.Sprintf("token:%s", apiToken.Id)
+	}
+	s.ApiToken = apiToken
+	s.UserIpAddr = c.ClientIP()
+
+	if err := services.UpdateTokenLastUsedAt(s.DB(), apiToken.Id); err != nil {
+		c.Logger().Warnf("error update token last used time, err %s", err)
+	}
+	return true
+}
+
+// checkTokenEnvScope 检查路由中访问的环境(或任务所属环境)是否在 token 授权范围内，
+// 从请求参数中获取环境 id 的接口在 apps 中通过 checkTokenEnvScope 检查
+func checkTokenEnvScope(c *ctx.GinRequest) bool {
+	apiToken := c.Service().ApiToken
+	if apiToken == nil || len(apiToken.Scopes.Envs) == 0 || c.Param("id") == "" {
+		return true
+	}
+
+	envId := models.Id("")
+	switch requestResource(c.Request.RequestURI) {
+	case "envs":
+		envId = models.Id(c.Param("id"))
+	case "tasks":
+		task, err := services.GetTaskById(c.Service().DB(), models.Id(c.Param("id")))
+		if err != nil {
+			c.JSONError(e.New(e.TaskNotExists), http.StatusNotFound)
+			return false
+		}
+		envId = task.EnvId
+	default:
+		return true
+	}
+
+	if !apiToken.Scopes.AllowEnv(envId) {
+		c.JSONError(e.New(e.InvalidTokenScope, fmt.Errorf("env not in token scopes")), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 // AuthOrgId 验证组织ID是否有效
 func AuthOrgId(c *ctx.GinRequest) {
 	if c.Service().OrgId == "" {
@@ -6,118 +6,159 @@ import (
 	"bytes"
 	"cloudiac/portal/libs/ctx"
 	"cloudiac/portal/models"
+	"cloudiac/portal/services"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"regexp"
 	"time"
-
-	//"github.com/gin-gonic/gin"
-	"strings"
 )
 
-type HandleTableAndDesc map[string]string
+const auditMask = "******"
+
+// 请求参数中需要脱敏的字段
+var auditSensitiveKeys = map[string]bool{
+	"password":    true,
+	"oldPassword": true,
+	"newPassword": true,
+	"key":         true,
+	"content":     true,
+	"params":      true,
+	"vcsToken":    true,
+	"repoToken":   true,
+	"token":       true,
+}
 
-func Operation(c *ctx.GinRequest) {
-	if c.Request.Method == "" {
-		c.Next() // 注意 next()方法的作用是跳过该调用链去直接后面的中间件以及api路由
-	}
+// 记录审计日志时查询变更前后数据的资源，key 为路由中的资源名称
+var auditResourceModels = map[string]func() interface{}{
+	"orgs":                      func() interface{} { return &models.Organization{} },
+	"users":                     func() interface{} { return &models.User{} },
+	"teams":                     func() interface{} { return &models.Team{} },
+	"projects":                  func() interface{} { return &models.Project{} },
+	"freeze-windows":            func() interface{} { return &models.FreezeWindow{} },
+	"variables":                 func() interface{} { return &models.Variable{} },
+	"tokens":                    func() interface{} { return &models.Token{} },
+	"keys":                      func() interface{} { return &models.Key{} },
+	"vcs":                       func() interface{} { return &models.Vcs{} },
+	"templates":                 func() interface{} { return &models.Template{} },
+	"notifications":             func() interface{} { return &models.NotificationCfg{} },
+	"envs":                      func() interface{} { return &models.Env{} },
+	"env-schedules":             func() interface{} { return &models.EnvSchedule{} },
+	"approval-policies":         func() interface{} { return &models.ApprovalPolicy{} },
+	"resource-account-bindings": func() interface{} { return &models.ResourceAccountBinding{} },
+	"variable-sets":             func() interface{} { return &models.VariableSet{} },
+	"variable-set-attachments":  func() interface{} { return &models.VariableSetAttachment{} },
+}
 
-	opMethod := &OperationMethod{C: c}
-	// 根据method方法名确定操作行为
+// 路由为 /api/v1/:resource/:id 或 /api/v1/:resource/:id/xxx 时 :id 为资源 id
+var resourceIdRouteRegex = regexp.MustCompile("^/[^/]+/[^/]+/[^/]+/:id(/|$)")
+
+// Operation 记录变更类请求的审计日志，修改及删除资源时记录变更前后的数据
+// 业务代码已单独记录审计日志(如审批、部署、变量修改)的请求不再重复记录
+func Operation(c *ctx.GinRequest) {
+	action := ""
 	switch c.Request.Method {
-	case "PUT":
-		err := opMethod.putOperation()
-		if err != nil {
-			c.Next()
-		}
 	case "POST":
-		err := opMethod.postOperation()
-		if err != nil {
-			c.Next()
-		}
+		action = models.OperationCreate
+	case "PUT", "PATCH":
+		action = models.OperationUpdate
 	case "DELETE":
-		err := opMethod.deleteOperation()
-		if err != nil {
-			c.Next()
-		}
+		action = models.OperationDelete
 	default:
 		return
 	}
 
-}
-
-type OperationMethod struct {
-	C *ctx.GinRequest
-}
+	bodyBytes, _ := ioutil.ReadAll(c.Request.Body)
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
 
-func (o *OperationMethod) putOperation() (err error) {
-	bodyBytes, err := ioutil.ReadAll(o.C.Request.Body)
-	o.C.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
-	cxt := o.C.Service()
-	url := o.C.Request.URL.String()
-	operationLog := models.OperationLog{
-		UserID:        cxt.UserId,
-		Username:      cxt.Username,
-		UserAddr:      cxt.UserIpAddr,
-		OperationAt:   models.Time(time.Now()),
-		OperationType: "PUT",
-		OperationInfo: fmt.Sprintf("修改了%s中的数据", strings.Split(url, "/")[len(strings.Split(url, "/"))-2]),
-		Desc:          models.JSON(bodyBytes),
+	resource := requestResource(c.Request.RequestURI)
+	var before interface{}
+	if action != models.OperationCreate {
+		before = loadAuditResource(c, resource)
 	}
 
-	err = operationLog.InsertLog()
-	if err != nil {
-		return err
-	}
+	c.Next()
 
-	return
-}
+	s := c.Service()
+	if s.AuditLogged || s.UserId == "" || c.Writer.Status() >= http.StatusBadRequest {
+		return
+	}
 
-func (o *OperationMethod) postOperation() (err error) {
-	// 新建操作 需要记录新插入的数据
-	name := o.C.PostForm("name")
-	bodyBytes, err := ioutil.ReadAll(o.C.Request.Body)
-	o.C.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
-	cxt := o.C.Service()
-	url := o.C.Request.URL.String()
-	operationLog := models.OperationLog{
-		UserID:        cxt.UserId,
-		Username:      cxt.Username,
-		UserAddr:      cxt.UserIpAddr,
-		OperationAt:   models.Time(time.Now()),
-		OperationType: "Create",
-		OperationInfo: fmt.Sprintf("创建了%s中的名称为%s数据", strings.Split(url, "/")[len(strings.Split(url, "/"))-2], name),
-		Desc:          models.JSON(bodyBytes),
+	var after interface{}
+	if len(bodyBytes) > 0 && json.Unmarshal(bodyBytes, &after) == nil {
+		after = redactAuditData(after)
+	} else {
+		after = nil
+	}
+	switch {
+	case action == models.OperationDelete && before != nil:
+		after = nil
+	case action == models.OperationUpdate && before != nil:
+		if data := loadAuditResource(c, resource); data != nil {
+			after = data
+		}
 	}
 
-	err = operationLog.InsertLog()
+	err := services.CreateOperationLog(s.DB(), models.OperationLog{
+		OrgId:         s.OrgId,
+		ProjectId:     s.ProjectId,
+		UserID:        s.UserId,
+		Username:      s.Username,
+		UserAddr:      s.UserIpAddr,
+		OperationAt:   models.Time(time.Now()),
+		OperationType: action,
+		ResourceType:  resource,
+		ResourceId:    models.Id(c.Param("id")),
+		OperationInfo: fmt.Sprintf("%s %s", c.Request.Method, c.Request.URL.Path),
+		Desc:          models.NewOperationDesc(before, after),
+	})
 	if err != nil {
-		return
+		c.Logger().Errorf("error create operation log, err %s", err)
 	}
-
-	return
 }
 
-func (o *OperationMethod) deleteOperation() (err error) {
-	// 删除操作
-	cxt := o.C.Service()
-	id := o.C.PostForm("id")
-	bodyBytes, err := ioutil.ReadAll(o.C.Request.Body)
-	o.C.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
-	url := o.C.Request.URL.String()
-	operationLog := models.OperationLog{
-		UserID:        cxt.UserId,
-		Username:      cxt.Username,
-		UserAddr:      cxt.UserIpAddr,
-		OperationAt:   models.Time(time.Now()),
-		OperationType: "delete",
-		OperationInfo: fmt.Sprintf("删除%s中了id为%s的数据", strings.Split(url, "/")[len(strings.Split(url, "/"))-2], id),
-		Desc:          models.JSON(bodyBytes),
+// loadAuditResource 查询请求操作的资源数据(已脱敏)，资源不支持或不存在时返回 nil
+func loadAuditResource(c *ctx.GinRequest, resource string) interface{} {
+	newModel, ok := auditResourceModels[resource]
+	if !ok || c.Param("id") == "" || !resourceIdRouteRegex.MatchString(c.FullPath()) {
+		return nil
 	}
 
-	err = operationLog.InsertLog()
+	m := newModel()
+	if err := c.Service().DB().Where("id = ?", c.Param("id")).First(m); err != nil {
+		return nil
+	}
+	bs, err := json.Marshal(m)
 	if err != nil {
-		return
+		return nil
 	}
+	var data interface{}
+	if err := json.Unmarshal(bs, &data); err != nil {
+		return nil
+	}
+	return redactAuditData(data)
+}
 
-	return nil
+// redactAuditData 对请求参数中的敏感数据脱敏，敏感变量的值同样会被隐藏
+func redactAuditData(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		sensitive, _ := v["sensitive"].(bool)
+		for key, val := range v {
+			if auditSensitiveKeys[key] || (sensitive && key == "value") {
+				v[key] = auditMask
+			} else {
+				v[key] = redactAuditData(val)
+			}
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redactAuditData(v[i])
+		}
+		return v
+	default:
+		return v
+	}
 }
@@ -40,16 +40,7 @@ func AccessControl(args ...string) gin.HandlerFunc {
 		s := c.Service()
 
 		// 通过 RequestURI 解析资源名称
-		res := ""
-		// 请求 /api/v1/users/:userId，
-		// 匹配第三段的  ^^^^^^ users
-		regex := regexp.MustCompile("^/[^/]+/[^/]+/([^/?#]+)")
-		match := regex.FindStringSubmatch(c.Request.RequestURI)
-		if len(match) == 2 {
-			res = match[1]
-		} else {
-			res = "other"
-		}
+		res := requestResource(c.Request.RequestURI)
 
 		// 通过 HTTP method 解析资源动作
 		op := "read"
@@ -77,41 +68,9 @@ func AccessControl(args ...string) gin.HandlerFunc {
 			return
 		}
 
-		// 组织角色
-		role := ""
-		switch {
-		case s.UserId == "":
-			role = consts.RoleAnonymous
-		case s.IsSuperAdmin:
-			role = consts.RoleRoot
-		case s.UserId != "" && s.OrgId == "":
-			role = consts.RoleLogin
-		case s.OrgId != "":
-			userOrgs := services.UserOrgRoles(s.UserId)
-			userOrg := userOrgs[s.OrgId]
-			if userOrg != nil {
-				role = userOrg.Role
-			}
-		default:
-		}
-		//s.Role = role
-
-		// 项目角色
-		proj := ""
-		switch {
-		case s.IsSuperAdmin:
-			proj = consts.ProjectRoleManager
-		case services.UserHasOrgRole(s.UserId, s.OrgId, consts.OrgRoleAdmin):
-			proj = consts.ProjectRoleManager
-		case s.ProjectId != "":
-			userProjects := services.UserProjectRoles(s.UserId)
-			userProject := userProjects[s.ProjectId]
-			if userProject != nil {
-				proj = userProject.Role
-			}
-		default:
-		}
-		//s.ProjectRole = proj
+		// 组织角色及项目角色
+		role := services.AccessOrgRole(s.UserId, s.OrgId, s.IsSuperAdmin, s.ApiToken)
+		proj := services.AccessProjectRole(s.UserId, s.OrgId, s.ProjectId, s.IsSuperAdmin, s.ApiToken)
 
 		// 参数重写
 		action := op
@@ -132,6 +91,12 @@ func AccessControl(args ...string) gin.HandlerFunc {
 			proj = consts.RoleDemo
 		}
 
+		// 通过 token 访问时，操作必须在 token 授权范围内
+		if s.ApiToken != nil && !s.ApiToken.Scopes.AllowAction(object, action) {
+			c.JSONError(e.New(e.InvalidTokenScope, fmt.Errorf("%s %s not in token scopes", action, object)), http.StatusForbidden)
+			return
+		}
+
 		// 根据 角色 和 项目角色 判断资源访问许可
 		logger.Debugf("enforcing %s,%s %s:%s", role, proj, object, action)
 		allow, err := enforcer.Enforce(role, proj, object, action)
@@ -146,3 +111,15 @@ func AccessControl(args ...string) gin.HandlerFunc {
 		}
 	}
 }
+
+// requestResource 通过 RequestURI 解析资源名称
+func requestResource(uri string) string {
+	// 请求 /api/v1/users/:userId，
+	// 匹配第三段的  ^^^^^^ users
+	regex := regexp.MustCompile("^/[^/]+/[^/]+/([^/?#]+)")
+	match := regex.FindStringSubmatch(uri)
+	if len(match) == 2 {
+		return match[1]
+	}
+	return "other"
+}
@@ -25,3 +25,25 @@ func RunTask(c *ctx.Context) {
 		c.Result(gin.H{"cid": cid})
 	}
 }
+
+// TaskWorkspace 查询任务的工作目录是否存在
+func TaskWorkspace(c *ctx.Context) {
+	req := runner.TaskWorkspaceReq{}
+	if err := c.BindQuery(&req); err != nil {
+		c.Error(err, http.StatusBadRequest)
+		return
+	}
+	for _, name := range []string{req.EnvId, req.TaskId} {
+		if err := runner.CheckPathName(name); err != nil {
+			c.Error(err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	exists, err := runner.PathExists(runner.GetTaskWorkspace(req.EnvId, req.TaskId))
+	if err != nil {
+		c.Error(err, http.StatusInternalServerError)
+		return
+	}
+	c.Result(gin.H{"exists": exists})
+}
@@ -24,4 +24,5 @@ func RegisterRoute(apiV1 *gin.RouterGroup) {
 	apiV1.POST("/task/run", w(handler.RunTask))
 	apiV1.GET("/task/status", w(handler.TaskStatus))
 	apiV1.GET("/task/log/follow", w(handler.TaskLogFollow))
+	apiV1.GET("/task/workspace", w(handler.TaskWorkspace))
 }
@@ -163,6 +163,10 @@ func (task *CommittedTaskStep) Wait(ctx context.Context) (int64, error) {
 			return resp.StatusCode, fmt.Errorf(resp.Error.Message)
 		} else {
 			{ // 执行结束后的处理
+				if err := removeSecretsFile(task.TaskStepDir()); err != nil {
+					logger.Warnf("remove secrets file error: %v", err)
+				}
+
 				// 调用 Status() 获取一次任务最新状态，并保存状态到文件
 				if info, err := task.Status(); err != nil {
 					logger.Warnf("get task status error: %v", err)
@@ -194,6 +198,9 @@ func (task *CommittedTaskStep) Wait(ctx context.Context) (int64, error) {
 	case err := <-errCh:
 		if errdefs.IsNotFound(err) {
 			logger.Infof("container not found, Id: %s", task.ContainerId)
+			if err := removeSecretsFile(task.TaskStepDir()); err != nil {
+				logger.Warnf("remove secrets file error: %v", err)
+			}
 			return 0, nil
 		}
 		logger.Warnf("wait container error: %v", err)
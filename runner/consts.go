@@ -32,6 +32,9 @@ const (
 	TaskStepLogName      = "output.log"
 	TaskStepInfoFileName = "info.json"
 
+	TaskStepRedactScriptName = "redact.awk" // 过滤步骤日志中敏感信息的脚本
+	TaskStepSecretsName      = ".secrets"   // 需要过滤的敏感值，过滤脚本读取后删除
+
 	CloudIacTfFile   = "_cloudiac.tf"
 	CloudIacTfVars   = "_cloudiac.tfvars"
 	CloudIacPlayVars = "_cloudiac_play_vars.yml"
@@ -43,3 +46,10 @@ const (
 
 	FollowLogDelay = time.Second // follow 文件时读到 EOF 后进行下次读取的等待时长
 )
+
+// state 步骤支持的资源状态操作
+const (
+	StateOpTaint = "taint"
+	StateOpRm    = "rm"
+	StateOpMv    = "mv"
+)
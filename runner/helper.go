@@ -5,10 +5,13 @@ package runner
 import (
 	"cloudiac/common"
 	"cloudiac/configs"
+	"cloudiac/utils"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 )
 
 type IaCTemplate struct {
@@ -47,6 +50,14 @@ func PathExists(path string) (bool, error) {
 	return false, err
 }
 
+// CheckPathName 检查用于拼接路径的 id 等参数，不允许包含路径分隔符或 ".."
+func CheckPathName(name string) error {
+	if name == "" || name == "." || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid path name '%s'", name)
+	}
+	return nil
+}
+
 func GetTaskWorkspace(envId string, taskId string) string {
 	conf := configs.Get()
 	return filepath.Join(conf.Runner.AbsStoragePath(), envId, taskId)
@@ -91,3 +102,46 @@ func FetchPlanJson(envId string, taskId string) ([]byte, error) {
 	}
 	return content, nil
 }
+
+// CopyTaskWorkspace 复制任务的工作目录(用于重试任务)，不复制原任务的步骤目录及 plan、state 等执行结果
+func CopyTaskWorkspace(envId string, srcTaskId string, dstTaskId string) error {
+	src := GetTaskWorkspace(envId, srcTaskId)
+	dst := GetTaskWorkspace(envId, dstTaskId)
+	skipNames := []string{TFPlanJsonFile, TFStateJsonFile, GetTaskStepDirName(common.CollectTaskStepIndex)}
+	stepDirRegex := regexp.MustCompile(`^step\d+$`)
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		// 只忽略工作目录根下的文件
+		if filepath.Dir(rel) == "." && (utils.StrInArray(rel, skipNames...) || (info.IsDir() && stepDirRegex.MatchString(rel))) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		default:
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(target, content, info.Mode().Perm())
+		}
+	})
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	RedactedValue = "******"
+
+	// 长度小于该值的敏感变量值不做替换，避免日志中大量的误替换
+	redactMinLength = 4
+)
+
+// alnum 生成匹配 n 个字母或数字的正则(部分 awk 实现不支持 {n} 语法)
+func alnum(chars string, n int) string {
+	return strings.Repeat(chars, n)
+}
+
+// redactAwkScript 在容器中过滤步骤输出的 awk 脚本，日志写入文件前替换其中的敏感变量值及常见的密钥格式。
+// 敏感变量值从 secrets 文件读取(每行一个)，读取后即删除该文件
+var redactAwkScript = fmt.Sprintf(`
+function replace(s, v,   out, i) {
+	out = ""
+	while ((i = index(s, v)) > 0) {
+		out = out substr(s, 1, i - 1) MASK
+		s = substr(s, i + length(v))
+	}
+	return out s
+}
+
+# 替换 password=xxx、token: xxx 等形式的值，保留 key 部分
+function maskkv(s,   out, lower, start, len, m, i) {
+	out = ""
+	lower = tolower(s)
+	while (match(lower, /%s/)) {
+		start = RSTART; len = RLENGTH
+		m = substr(lower, start, len)
+		match(m, /[:=][ \t]*["']?/)
+		i = RSTART + RLENGTH - 1
+		out = out substr(s, 1, start + i - 1) MASK
+		s = substr(s, start + len)
+		lower = substr(lower, start + len)
+	}
+	return out s
+}
+
+BEGIN {
+	MASK = "%s"
+	n = 0
+	while ((getline line < secrets) > 0) {
+		if (line != "") {
+			values[n++] = line
+		}
+	}
+	close(secrets)
+	system("rm -f '" secrets "'")
+}
+
+inkey && /-----END [A-Z ]*PRIVATE KEY-----/ {
+	inkey = 0
+}
+inkey {
+	print MASK
+	fflush()
+	next
+}
+/-----BEGIN [A-Z ]*PRIVATE KEY-----$/ {
+	inkey = 1
+}
+
+{
+	for (i = 0; i < n; i++) {
+		$0 = replace($0, values[i])
+	}
+	gsub(/PRIVATE KEY-----[^-]+-----END/, "PRIVATE KEY-----" MASK "-----END")
+	gsub(/(AKIA|ASIA)%s/, MASK)
+	gsub(/LTAI%s[0-9A-Za-z]*/, MASK)
+	gsub(/gh[pousr]_%s/, MASK)
+	gsub(/[Bb]earer [A-Za-z0-9._~+\/-]+=*/, "Bearer " MASK)
+	gsub(/:\/\/[^\/:@ \t]+:[^\/@ \t]+@/, "://" MASK "@")
+	$0 = maskkv($0)
+	print
+	fflush()
+}
+`,
+	`(password|passwd|secret|token|access_key|secret_key|api_key|apikey)["']?[ \t]*[:=][ \t]*["']?[^ \t"',()]+`,
+	RedactedValue,
+	alnum("[0-9A-Z]", 16),
+	alnum("[0-9A-Za-z]", 12),
+	alnum("[0-9A-Za-z]", 36),
+)
+
+// addSecret 记录需要在日志中替换的敏感值，多行的值按行替换
+func (t *Task) addSecret(value string) {
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) < redactMinLength {
+			continue
+		}
+		t.secrets = append(t.secrets, line)
+		// 值被转义后输出的情况(如 terraform plan 中的字符串)
+		if quoted := strconv.Quote(line); quoted[1:len(quoted)-1] != line {
+			t.secrets = append(t.secrets, quoted[1:len(quoted)-1])
+		}
+	}
+}
+
+// genRedactFiles 生成日志过滤脚本及敏感值文件，返回容器中执行步骤脚本并过滤输出的命令
+func (t *Task) genRedactFiles() (string, error) {
+	stepDir := GetTaskStepDir(t.req.Env.Id, t.req.TaskId, t.req.Step)
+	return writeRedactFiles(stepDir, t.stepDirName(t.req.Step), t.secrets)
+}
+
+// writeRedactFiles 在 stepDir 中写入过滤脚本及敏感值文件，返回的命令中的路径相对于工作目录(stepDirName 为步骤目录名)
+func writeRedactFiles(stepDir string, stepDirName string, values []string) (string, error) {
+	if err := os.WriteFile(filepath.Join(stepDir, TaskStepRedactScriptName), []byte(redactAwkScript), 0644); err != nil {
+		return "", err
+	}
+
+	// 先替换较长的值，避免包含关系的值只被部分替换
+	secrets := uniqueStrings(values)
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	content := strings.Join(secrets, "\n")
+	if err := os.WriteFile(filepath.Join(stepDir, TaskStepSecretsName), []byte(content), 0600); err != nil {
+		return "", err
+	}
+
+	logPath := filepath.Join(stepDirName, TaskStepLogName)
+	// 通过 fd 3 传出步骤脚本的退出码，管道的退出码为 awk 的退出码
+	return fmt.Sprintf("command -v awk >/dev/null || { echo 'awk is required to filter task log' >>%s; exit 127; }; "+
+		"code=$( { { sh %s 2>&1 3>&-; echo $? >&3; } | awk -v secrets=%s -f %s >>%s; } 3>&1 ); exit ${code:-1}",
+		logPath,
+		filepath.Join(stepDirName, TaskStepScriptName),
+		filepath.Join(stepDirName, TaskStepSecretsName),
+		filepath.Join(stepDirName, TaskStepRedactScriptName),
+		logPath,
+	), nil
+}
+
+// removeSecretsFile 删除步骤目录中的敏感值文件。
+// 过滤脚本读取后会自行删除，但容器中没有 awk 或步骤启动失败时该文件会残留
+func removeSecretsFile(stepDir string) error {
+	if err := os.Remove(filepath.Join(stepDir, TaskStepSecretsName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func uniqueStrings(ss []string) []string {
+	seen := make(map[string]struct{}, len(ss))
+	rs := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		rs = append(rs, s)
+	}
+	return rs
+}
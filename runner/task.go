@@ -9,10 +9,13 @@ import (
 	"cloudiac/utils"
 	"cloudiac/utils/logs"
 	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
 )
@@ -22,6 +25,7 @@ type Task struct {
 	logger    logs.Logger
 	config    configs.RunnerConfig
 	workspace string
+	secrets   []string // 需要在日志中替换的敏感值
 }
 
 func NewTask(req RunTaskReq, logger logs.Logger) *Task {
@@ -39,9 +43,14 @@ func (t *Task) Run() (cid string, err error) {
 			return "", errors.Wrap(err, "decrypt variables")
 		}
 	}
+	for i, f := range t.req.Env.Files {
+		if t.req.Env.Files[i].Content, err = t.decryptSecret(f.Content); err != nil {
+			return "", errors.Wrapf(err, "decrypt file variable '%s'", f.Name)
+		}
+	}
 
 	if t.req.PrivateKey != "" {
-		t.req.PrivateKey, err = utils.DecryptSecretVar(t.req.PrivateKey)
+		t.req.PrivateKey, err = t.decryptSecret(t.req.PrivateKey)
 		if err != nil {
 			return "", errors.Wrap(err, "decrypt private key")
 		}
@@ -78,17 +87,25 @@ func (t *Task) Run() (cid string, err error) {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	for _, f := range t.req.Env.Files {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", f.Name, filepath.Join(ContainerWorkspace, f.Path)))
+	}
+
 	if tfPluginCacheDir == "" {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("TF_PLUGIN_CACHE_DIR=%s", ContainerPluginCachePath))
 	}
 
-	cmd.Commands = []string{"sh", "-c", fmt.Sprintf("sh %s >>%s 2>&1",
-		filepath.Join(t.stepDirName(t.req.Step), TaskStepScriptName),
-		filepath.Join(t.stepDirName(t.req.Step), TaskStepLogName),
-	)}
+	command, err := t.genRedactFiles()
+	if err != nil {
+		return cid, errors.Wrap(err, "generate log redact files")
+	}
+	cmd.Commands = []string{"sh", "-c", command}
 
 	t.logger.Infof("start task step, workdir: %s", cmd.HostWorkdir)
 	if cid, err = cmd.Start(); err != nil {
+		if er := removeSecretsFile(GetTaskStepDir(t.req.Env.Id, t.req.TaskId, t.req.Step)); er != nil {
+			t.logger.Warnf("remove secrets file error: %v", er)
+		}
 		return cid, err
 	}
 
@@ -109,7 +126,7 @@ func (t *Task) Run() (cid string, err error) {
 func (t *Task) decryptVariables(vars map[string]string) error {
 	var err error
 	for k, v := range vars {
-		vars[k], err = utils.DecryptSecretVar(v)
+		vars[k], err = t.decryptSecret(v)
 		if err != nil {
 			return err
 		}
@@ -117,6 +134,20 @@ func (t *Task) decryptVariables(vars map[string]string) error {
 	return nil
 }
 
+// decryptSecret 解密敏感变量的值，并记录解密后的值以便在日志中替换
+func (t *Task) decryptSecret(value string) (string, error) {
+	val, isSecret := utils.DecodeSecretVar(value)
+	if !isSecret {
+		return val, nil
+	}
+	val, err := utils.AesDecrypt(val)
+	if err != nil {
+		return "", err
+	}
+	t.addSecret(val)
+	return val, nil
+}
+
 func (t *Task) initWorkspace() (workspace string, err error) {
 	if strings.HasPrefix(t.req.Env.Workdir, "..") {
 		// 不允许访问上层目录
@@ -128,10 +159,25 @@ func (t *Task) initWorkspace() (workspace string, err error) {
 		return workspace, nil
 	}
 
-	if ok, err := PathExists(workspace); err != nil {
+	ok, err := PathExists(workspace)
+	if err != nil {
 		return workspace, err
-	} else if ok && t.req.StepType == common.TaskStepInit {
-		return workspace, fmt.Errorf("workspace '%s' is already exists", workspace)
+	}
+	if t.req.SourceTaskId != "" && !ok {
+		// 重试任务直接复用原任务的工作目录，不需要重新执行 init
+		if err = CopyTaskWorkspace(t.req.Env.Id, t.req.SourceTaskId, t.req.TaskId); err != nil {
+			return workspace, errors.Wrap(err, "copy source task workspace")
+		}
+		return workspace, nil
+	}
+	if ok && t.req.StepType == common.TaskStepInit {
+		if t.req.Retry == 0 {
+			return workspace, fmt.Errorf("workspace '%s' is already exists", workspace)
+		}
+		// init 步骤自动重试时需要清理上次 clone 的代码
+		if err = os.RemoveAll(filepath.Join(workspace, "code")); err != nil {
+			return workspace, err
+		}
 	}
 
 	if err = os.MkdirAll(workspace, 0755); err != nil {
@@ -153,10 +199,40 @@ func (t *Task) initWorkspace() (workspace string, err error) {
 	if err = t.genPlayVarsFile(workspace); err != nil {
 		return workspace, errors.Wrap(err, "generate play vars file")
 	}
+	if err = t.writeVarFiles(workspace); err != nil {
+		return workspace, errors.Wrap(err, "write file variables")
+	}
 
 	return workspace, nil
 }
 
+// writeVarFiles 将文件变量写入工作目录，文件可能包含凭证，只允许属主读写
+func (t *Task) writeVarFiles(workspace string) error {
+	for _, f := range t.req.Env.Files {
+		if !utils.IsSafeRelPath(f.Path) || isReservedWorkspacePath(f.Path) {
+			return fmt.Errorf("invalid path '%s' of file variable '%s'", f.Path, f.Name)
+		}
+		p := filepath.Join(workspace, f.Path)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, []byte(f.Content), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var stepDirNameRegex = regexp.MustCompile(`^step\d+$`)
+
+// isReservedWorkspacePath 判断路径是否与代码目录、步骤目录(见 GetTaskStepDirName)或 runner 生成的文件冲突
+func isReservedWorkspacePath(p string) bool {
+	top := strings.SplitN(filepath.ToSlash(filepath.Clean(p)), "/", 2)[0]
+	return utils.StrInArray(top, "code", "ssh_key", TFStateJsonFile, TFPlanJsonFile,
+		GetTaskStepDirName(common.CollectTaskStepIndex)) ||
+		strings.HasPrefix(top, "_cloudiac") || stepDirNameRegex.MatchString(top)
+}
+
 var iacTerraformTpl = template.Must(template.New("").Parse(` terraform {
   backend "{{.State.Backend}}" {
     address = "{{.State.Address}}"
@@ -199,13 +275,42 @@ func (t *Task) genIacTfFile(workspace string) error {
 }
 
 var iacTfVarsTpl = template.Must(template.New("").Parse(`
-{{- range $k,$v := .Env.TerraformVars -}}
-{{$k}} = "{{$v}}"
+{{- range $k,$v := . -}}
+{{$k}} = {{$v}}
 {{ end -}}
 `))
 
 func (t *Task) genIacTfVarsFile(workspace string) error {
-	return execTpl2File(iacTfVarsTpl, t.req, filepath.Join(workspace, CloudIacTfVars))
+	vars := make(map[string]string, len(t.req.Env.TerraformVars))
+	for k, v := range t.req.Env.TerraformVars {
+		if utils.StrInArray(k, t.req.Env.HclTerraformVars...) && isHclExpression(v) {
+			vars[k] = v
+		} else {
+			vars[k] = quoteHclString(v)
+		}
+	}
+	return execTpl2File(iacTfVarsTpl, vars, filepath.Join(workspace, CloudIacTfVars))
+}
+
+// isHclExpression 判断值是否为单个合法的 hcl 表达式，不合法的值(如包含多行赋值)按字符串写入 tfvars 文件
+func isHclExpression(s string) bool {
+	_, diags := hclsyntax.ParseExpression([]byte(s), CloudIacTfVars, hcl.Pos{Line: 1, Column: 1})
+	return !diags.HasErrors()
+}
+
+var hclStringReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"${", "$${",
+	"%{", "%%{",
+)
+
+// quoteHclString 将变量值转为 hcl 字符串字面量，转义引号、换行及模板插值标记
+func quoteHclString(s string) string {
+	return `"` + hclStringReplacer.Replace(s) + `"`
 }
 
 var iacPlayVarsTpl = template.Must(template.New("").Parse(`
@@ -250,6 +355,12 @@ func (t *Task) genStepScript() error {
 		command, err = t.stepApply()
 	case common.TaskStepDestroy:
 		command, err = t.stepDestroy()
+	case common.TaskStepImport:
+		command, err = t.stepImport()
+	case common.TaskStepState:
+		command, err = t.stepState()
+	case common.TaskStepOutput:
+		command, err = t.stepOutput()
 	case common.TaskStepPlay:
 		command, err = t.stepPlay()
 	case common.TaskStepCommand:
@@ -356,6 +467,129 @@ func (t *Task) stepDestroy() (command string, err error) {
 	})
 }
 
+var importCommandTpl = template.Must(template.New("").Parse(`#/bin/sh
+cd 'code/{{.Req.Env.Workdir}}' && \
+{{ range $r := .Resources -}}
+terraform import -input=false \
+{{if $.TfVars}}-var-file={{$.TfVars}}{{end}} -var-file={{$.IacTfVars}} \
+{{$r.Address}} {{$r.Id}} && \
+{{ end -}}
+sleep 0
+`))
+
+// ParseImportArg 解析 address=id 格式的导入参数，address 中可以包含带 = 的索引，如 aws_instance.web["k=v"]=i-123。
+// 使用 address 中第一个不在索引(方括号及其中的字符串)内的 = 分隔，id 中可以包含 = 和方括号
+func ParseImportArg(arg string) (address string, id string, err error) {
+	invalid := fmt.Errorf("invalid import argument '%s', expected 'address=id'", arg)
+	if strings.ContainsAny(arg, "\r\n") {
+		return "", "", invalid
+	}
+
+	var (
+		depth   = 0
+		inQuote = false
+		sep     = -1
+	)
+	for i := 0; i < len(arg) && sep < 0; i++ {
+		switch c := arg[i]; {
+		case inQuote && c == '\\':
+			i++ // 跳过转义的字符
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '=' && depth == 0:
+			sep = i
+		}
+	}
+	if sep < 0 {
+		return "", "", invalid
+	}
+	address, id = strings.TrimSpace(arg[:sep]), strings.TrimSpace(arg[sep+1:])
+	if address == "" || id == "" {
+		return "", "", invalid
+	}
+	return address, id, nil
+}
+
+// ShellQuote 使用单引号转义 shell 参数
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (t *Task) stepImport() (command string, err error) {
+	resources := make([]map[string]string, 0, len(t.req.StepArgs))
+	for _, arg := range t.req.StepArgs {
+		address, id, err := ParseImportArg(arg)
+		if err != nil {
+			return "", err
+		}
+		resources = append(resources, map[string]string{
+			"Address": ShellQuote(address),
+			"Id":      ShellQuote(id),
+		})
+	}
+
+	return t.executeTpl(importCommandTpl, map[string]interface{}{
+		"Req":       t.req,
+		"Resources": resources,
+		"TfVars":    t.req.Env.TfVarsFile,
+		"IacTfVars": CloudIacTfVars,
+	})
+}
+
+var stateCommandTpl = template.Must(template.New("").Parse(`#/bin/sh
+cd 'code/{{.Req.Env.Workdir}}' && \
+terraform {{.Command}} {{ range $arg := .Args }}{{$arg}} {{ end }}
+`))
+
+// stepState 执行资源状态操作，参数格式为 [taint|rm|mv, address...]
+func (t *Task) stepState() (command string, err error) {
+	if len(t.req.StepArgs) < 2 {
+		return "", fmt.Errorf("invalid state step args: %v", t.req.StepArgs)
+	}
+
+	op, addresses := t.req.StepArgs[0], t.req.StepArgs[1:]
+	cmd := ""
+	switch op {
+	case StateOpTaint:
+		cmd = "taint"
+	case StateOpRm:
+		cmd = "state rm"
+	case StateOpMv:
+		if len(addresses) != 2 {
+			return "", fmt.Errorf("state mv requires source and destination address")
+		}
+		cmd = "state mv"
+	default:
+		return "", fmt.Errorf("unknown state operation '%s'", op)
+	}
+
+	args := make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		args = append(args, ShellQuote(addr))
+	}
+	return t.executeTpl(stateCommandTpl, map[string]interface{}{
+		"Req":     t.req,
+		"Command": cmd,
+		"Args":    args,
+	})
+}
+
+var outputCommandTpl = template.Must(template.New("").Parse(`#/bin/sh
+cd 'code/{{.Req.Env.Workdir}}' && \
+terraform output -no-color {{ range $arg := .Req.StepArgs }}{{$arg}} {{ end }}
+`))
+
+func (t *Task) stepOutput() (command string, err error) {
+	return t.executeTpl(outputCommandTpl, map[string]interface{}{
+		"Req": t.req,
+	})
+}
+
 var playCommandTpl = template.Must(template.New("").Parse(`#/bin/sh
 export ANSIBLE_HOST_KEY_CHECKING="False"
 export ANSIBLE_TF_DIR="."
@@ -0,0 +1,75 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImportArg(t *testing.T) {
+	cases := []struct {
+		arg     string
+		address string
+		id      string
+		invalid bool
+	}{
+		{arg: "aws_instance.web=i-123", address: "aws_instance.web", id: "i-123"},
+		{arg: " aws_instance.web = i-123 ", address: "aws_instance.web", id: "i-123"},
+		{arg: "aws_instance.web[0]=i-123", address: "aws_instance.web[0]", id: "i-123"},
+		{arg: `aws_instance.web["k=v"]=i-123`, address: `aws_instance.web["k=v"]`, id: "i-123"},
+		{arg: `aws_instance.web["a]=b"]=i-123`, address: `aws_instance.web["a]=b"]`, id: "i-123"},
+		{arg: `aws_instance.web["a\"]=b"]=i-123`, address: `aws_instance.web["a\"]=b"]`, id: "i-123"},
+		{arg: `module.vpc["prod"].aws_vpc.this[0]=vpc-1`, address: `module.vpc["prod"].aws_vpc.this[0]`, id: "vpc-1"},
+		// id 中可以包含 = 和方括号
+		{arg: "aws_iam_role_policy.p=role:policy=a", address: "aws_iam_role_policy.p", id: "role:policy=a"},
+		{arg: "alicloud_instance.web=i-[1]", address: "alicloud_instance.web", id: "i-[1]"},
+		{arg: `aws_instance.web["k"]=id[0]=x`, address: `aws_instance.web["k"]`, id: "id[0]=x"},
+
+		{arg: "aws_instance.web", invalid: true},
+		{arg: "=i-123", invalid: true},
+		{arg: "aws_instance.web=", invalid: true},
+		{arg: `aws_instance.web["k=v"]`, invalid: true},
+		{arg: "aws_instance.web=i-123\nrm -rf /", invalid: true},
+	}
+
+	for _, c := range cases {
+		address, id, err := ParseImportArg(c.arg)
+		if c.invalid {
+			assert.Error(t, err, c.arg)
+			continue
+		}
+		assert.NoError(t, err, c.arg)
+		assert.Equal(t, c.address, address, c.arg)
+		assert.Equal(t, c.id, id, c.arg)
+	}
+}
+
+func TestCheckPathName(t *testing.T) {
+	for _, name := range []string{"env-c3ek0u0j2h8ke1bslb7g", "run-c3ek0u0j2h8ke1bslb80"} {
+		assert.NoError(t, CheckPathName(name), name)
+	}
+	for _, name := range []string{"", ".", "..", "../env", "env/../..", "env/task", `env\task`, "/etc"} {
+		assert.Error(t, CheckPathName(name), name)
+	}
+}
+
+func TestIsReservedWorkspacePath(t *testing.T) {
+	for _, p := range []string{"code", "code/main.tf", "ssh_key", "_cloudiac.tf", "step0", "step12/run.sh",
+		"./step1", ".step-collect", ".step-collect/output.log", "tfstate.json", "tfplan.json"} {
+		assert.True(t, isReservedWorkspacePath(p), p)
+	}
+	for _, p := range []string{"files/kubeconfig", "kubeconfig", "steps", "step", "step1a", "mystep1", "files/step1"} {
+		assert.False(t, isReservedWorkspacePath(p), p)
+	}
+}
+
+func TestIsHclExpression(t *testing.T) {
+	for _, v := range []string{`["a", "b"]`, `{ a = 1, b = "x" }`, `{"a": [1, 2]}`, "[\n  \"a\",\n  \"b\",\n]", `true`} {
+		assert.True(t, isHclExpression(v), v)
+	}
+	for _, v := range []string{"[\"a\"]\nother = \"x\"", `["a"`, `a b`, "", "{ a = 1 }\n}\nx = 1"} {
+		assert.False(t, isHclExpression(v), v)
+	}
+}
@@ -19,6 +19,17 @@ type TaskEnv struct {
 	EnvironmentVars map[string]string `json:"environment"`
 	TerraformVars   map[string]string `json:"terraform"`
 	AnsibleVars     map[string]string `json:"ansible"`
+	Files           []TaskFile        `json:"files"`
+
+	// 值需要作为 hcl 表达式写入 tfvars 文件的 terraform 变量(模板中声明为 list、map 等复杂类型)，其他变量按字符串写入
+	HclTerraformVars []string `json:"hclTerraform,omitempty"`
+}
+
+// TaskFile 文件变量，初始化工作目录时写入 Path，并通过名为 Name 的环境变量传递文件在容器中的路径
+type TaskFile struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`    // 相对于工作目录的路径
+	Content string `json:"content"` // 文件内容，敏感变量为加密后的值
 }
 
 type StateStore struct {
@@ -42,6 +53,9 @@ type RunTaskReq struct {
 
 	Timeout    int    `json:"timeout"`
 	PrivateKey string `json:"privateKey"`
+
+	SourceTaskId string `json:"sourceTaskId"` // 重试任务时复用该任务的工作目录
+	Retry        int    `json:"retry"`        // 当前步骤的自动重试次数
 }
 
 type TaskStatusReq struct {
@@ -52,6 +66,11 @@ type TaskStatusReq struct {
 
 type TaskLogReq TaskStatusReq
 
+type TaskWorkspaceReq struct {
+	EnvId  string `json:"envId" form:"envId" binding:"required"`
+	TaskId string `json:"taskId" form:"taskId" binding:"required"`
+}
+
 // TaskStatusMessage runner 通知任务状态到 portal
 type TaskStatusMessage struct {
 	Exited   bool `json:"exited"`
@@ -0,0 +1,59 @@
+// Copyright 2021 CloudJ Company Limited. All rights reserved.
+
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, spec := range []string{"* * * * *", "0 8 * * 1-5", "*/15 0-6,20-23 1,15 */2 0,7", "5/10 * * * *"} {
+		_, err := ParseCron(spec)
+		assert.NoError(t, err, spec)
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	cases := []struct {
+		spec   string
+		from   string
+		expect string
+	}{
+		// 周五晚上之后的下一个工作日早上
+		{"0 8 * * 1-5", "2021-10-15 20:00", "2021-10-18 08:00"},
+		{"0 20 * * 1-5", "2021-10-15 20:00", "2021-10-18 20:00"},
+		{"0 20 * * 1-5", "2021-10-15 19:59", "2021-10-15 20:00"},
+		{"*/15 * * * *", "2021-10-15 10:07", "2021-10-15 10:15"},
+		{"0 0 1 * *", "2021-12-15 10:00", "2022-01-01 00:00"},
+		{"0 0 29 2 *", "2021-03-01 00:00", "2024-02-29 00:00"},
+		// 日与周同时限定时满足其一即可
+		{"0 0 1 * 0", "2021-10-02 00:00", "2021-10-03 00:00"},
+		{"0 0 * * 7", "2021-10-02 00:00", "2021-10-03 00:00"},
+	}
+	for _, c := range cases {
+		s, err := ParseCron(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, at(c.expect), s.Next(at(c.from)), c.spec)
+	}
+
+	s, _ := ParseCron("0 0 30 2 *")
+	assert.True(t, s.Next(at("2021-01-01 00:00")).IsZero())
+}
@@ -524,3 +524,12 @@ func SetGinMode() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 }
+
+// IsSafeRelPath 判断 p 是否为不会跳出基准目录的相对路径
+func IsSafeRelPath(p string) bool {
+	if p == "" || filepath.IsAbs(p) || strings.HasPrefix(p, "/") {
+		return false
+	}
+	cleaned := filepath.ToSlash(filepath.Clean(p))
+	return cleaned != "." && cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
@@ -40,6 +40,24 @@ func TestGlobMatch(t *testing.T) {
 	}
 }
 
+func TestIsSafeRelPath(t *testing.T) {
+	cases := map[string]bool{
+		"kubeconfig":             true,
+		"files/gcp.json":         true,
+		"files/../gcp.json":      true,
+		"":                       false,
+		".":                      false,
+		"/etc/passwd":            false,
+		"..":                     false,
+		"../ssh_key":             false,
+		"files/../../ssh_key":    false,
+		"files/./../../../x.pem": false,
+	}
+	for p, expect := range cases {
+		assert.Equal(t, expect, IsSafeRelPath(p), p)
+	}
+}
+
 func TestFileExists(t *testing.T) {
 	cases := []struct {
 		path   string